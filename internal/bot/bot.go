@@ -4,6 +4,7 @@ import (
 	"ai_support_tg_writer_bot/internal/config"
 	"ai_support_tg_writer_bot/internal/models"
 	"ai_support_tg_writer_bot/internal/service"
+	"context"
 	"fmt"
 	"log"
 	"strconv"
@@ -60,7 +61,7 @@ func (b *Bot) Start() error {
 
 func (b *Bot) handleMessage(message *tgbotapi.Message) {
 	// Получаем или создаем пользователя
-	user, err := b.userService.CreateOrGetUser(
+	user, err := b.userService.CreateOrGetUser(context.Background(),
 		int64(message.From.ID),
 		message.From.UserName,
 		message.From.FirstName,
@@ -76,7 +77,7 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) {
 
 	// Если пользователь админ по конфигурации, но не в базе - обновляем базу
 	if isAdmin && !user.IsAdmin {
-		b.userService.SetAdmin(int64(message.From.ID), true)
+		b.userService.SetAdmin(context.Background(), int64(message.From.ID), true)
 	}
 
 	// Обрабатываем команды
@@ -228,7 +229,7 @@ func (b *Bot) handleRegularMessage(message *tgbotapi.Message, user *models.User,
 		isReplying, ticketID := b.stateManager.IsReplyingToTicket(int64(message.From.ID))
 		if isReplying {
 			// Админ отвечает на тикет
-			admin, err := b.userService.GetUserByTelegramID(int64(message.From.ID))
+			admin, err := b.userService.GetUserByTelegramID(context.Background(), int64(message.From.ID))
 			if err != nil {
 				log.Printf("Failed to get admin user: %v", err)
 				b.sendMessage(message.Chat.ID, "Произошла ошибка.")
@@ -327,7 +328,7 @@ func (b *Bot) handleMessageFiles(message *tgbotapi.Message, ticketID uint, userI
 	// Обрабатываем различные типы файлов
 	if message.Photo != nil && len(message.Photo) > 0 {
 		photo := message.Photo[len(message.Photo)-1] // Берем самое большое фото
-		_, err = b.fileService.CreateFile(
+		_, err = b.fileService.CreateFile(context.Background(),
 			lastMessage.ID,
 			photo.FileID,
 			"photo.jpg",
@@ -338,7 +339,7 @@ func (b *Bot) handleMessageFiles(message *tgbotapi.Message, ticketID uint, userI
 	}
 
 	if message.Video != nil {
-		_, err = b.fileService.CreateFile(
+		_, err = b.fileService.CreateFile(context.Background(),
 			lastMessage.ID,
 			message.Video.FileID,
 			message.Video.FileName,
@@ -349,7 +350,7 @@ func (b *Bot) handleMessageFiles(message *tgbotapi.Message, ticketID uint, userI
 	}
 
 	if message.Document != nil {
-		_, err = b.fileService.CreateFile(
+		_, err = b.fileService.CreateFile(context.Background(),
 			lastMessage.ID,
 			message.Document.FileID,
 			message.Document.FileName,
@@ -360,7 +361,7 @@ func (b *Bot) handleMessageFiles(message *tgbotapi.Message, ticketID uint, userI
 	}
 
 	if message.Voice != nil {
-		_, err = b.fileService.CreateFile(
+		_, err = b.fileService.CreateFile(context.Background(),
 			lastMessage.ID,
 			message.Voice.FileID,
 			"voice.ogg",
@@ -371,7 +372,7 @@ func (b *Bot) handleMessageFiles(message *tgbotapi.Message, ticketID uint, userI
 	}
 
 	if message.VideoNote != nil {
-		_, err = b.fileService.CreateFile(
+		_, err = b.fileService.CreateFile(context.Background(),
 			lastMessage.ID,
 			message.VideoNote.FileID,
 			"video_note.mp4",
@@ -413,7 +414,7 @@ func (b *Bot) handleCallbackQuery(query *tgbotapi.CallbackQuery) {
 }
 
 func (b *Bot) handleCreateTicketCallback(query *tgbotapi.CallbackQuery) {
-	user, err := b.userService.GetUserByTelegramID(int64(query.From.ID))
+	user, err := b.userService.GetUserByTelegramID(context.Background(), int64(query.From.ID))
 	if err != nil {
 		b.answerCallbackQuery(query.ID, "Ошибка при получении данных пользователя.")
 		return
@@ -434,7 +435,7 @@ func (b *Bot) handleCreateTicketCallback(query *tgbotapi.CallbackQuery) {
 }
 
 func (b *Bot) handleMyTicketsCallback(query *tgbotapi.CallbackQuery) {
-	user, err := b.userService.GetUserByTelegramID(int64(query.From.ID))
+	user, err := b.userService.GetUserByTelegramID(context.Background(), int64(query.From.ID))
 	if err != nil {
 		b.answerCallbackQuery(query.ID, "Ошибка при получении данных пользователя.")
 		return
@@ -498,7 +499,7 @@ func (b *Bot) handleCloseTicketCallback(query *tgbotapi.CallbackQuery) {
 		return
 	}
 
-	user, err := b.userService.GetUserByTelegramID(int64(query.From.ID))
+	user, err := b.userService.GetUserByTelegramID(context.Background(), int64(query.From.ID))
 	if err != nil {
 		b.answerCallbackQuery(query.ID, "Ошибка при получении данных пользователя.")
 		return
@@ -0,0 +1,292 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Данные кнопки имеют вид "маршрут:параметр:параметр". Telegram принимает не больше 64 байт,
+// поэтому имена маршрутов короткие, а параметры - числа и короткие коды.
+const (
+	callbackDataLimit = 64
+	callbackSeparator = ":"
+)
+
+// Маршруты кнопок
+const (
+	routeAdminMenu      = "menu"
+	routeActiveChats    = "chats"    // страница
+	routeArchivedChats  = "archived" // страница
+	routeChat           = "chat"     // ID чата, страница сообщений
+	routeOpenChat       = "open"     // ID чата; из уведомления, открывается новым сообщением
+	routeChatHistory    = "history"  // ID чата
+	routeChatReply      = "reply"    // ID чата
+	routeChatArchive    = "archive"  // ID чата
+	routeChatFinish     = "finish"   // ID чата
+	routeChatContinue   = "continue"
+	routeShowMessage    = "msg"   // ID сообщения
+	routeStats          = "stats" // период
+	routeTemplateSave   = "tpl_save"
+	routeTemplateCancel = "tpl_cancel"
+	routeLanguage       = "lang"       // код языка
+	routeClientHistory  = "my_history" // страница переписки клиента с конца
+)
+
+// param - тип параметра маршрута
+type param int
+
+const (
+	paramID   param = iota // ID записи в базе
+	paramPage              // номер страницы, с нуля
+	paramCode              // короткий код: язык, период статистики
+)
+
+// Самое длинное значение параметра каждого типа: ID хранятся в uint32, коды - не длиннее maxCodeLen
+const maxCodeLen = 16
+
+var paramMaxLen = map[param]int{
+	paramID:   10,
+	paramPage: 10,
+	paramCode: maxCodeLen,
+}
+
+// parse проверяет значение параметра
+func (p param) parse(value string) error {
+	switch p {
+	case paramID:
+		_, err := strconv.ParseUint(value, 10, 32)
+		return err
+	case paramPage:
+		_, err := strconv.ParseUint(value, 10, 31)
+		return err
+	case paramCode:
+		if value == "" || len(value) > maxCodeLen {
+			return fmt.Errorf("invalid code %q", value)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown param type %d", p)
+}
+
+// callbackArgs - параметры нажатой кнопки, уже проверенные роутером по типам маршрута
+type callbackArgs []string
+
+// ID - параметр типа paramID
+func (a callbackArgs) ID(i int) uint {
+	id, _ := strconv.ParseUint(a[i], 10, 32)
+	return uint(id)
+}
+
+// Page - параметр типа paramPage
+func (a callbackArgs) Page(i int) int {
+	page, _ := strconv.Atoi(a[i])
+	return page
+}
+
+// Code - параметр типа paramCode
+func (a callbackArgs) Code(i int) string {
+	return a[i]
+}
+
+type callbackHandler func(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs)
+
+type callbackRoute struct {
+	params  []param
+	handler callbackHandler
+}
+
+// callbackRouter находит обработчик нажатой кнопки по ее данным
+type callbackRouter struct {
+	routes map[string]callbackRoute
+}
+
+func newCallbackRouter() *callbackRouter {
+	return &callbackRouter{routes: make(map[string]callbackRoute)}
+}
+
+// handle регистрирует маршрут. Ошибка - маршрут уже есть или его данные могут не поместиться в лимит Telegram.
+func (r *callbackRouter) handle(name string, handler callbackHandler, params ...param) error {
+	if _, ok := r.routes[name]; ok {
+		return fmt.Errorf("callback route %q is already registered", name)
+	}
+
+	size := len(name)
+	for _, p := range params {
+		size += len(callbackSeparator) + paramMaxLen[p]
+	}
+	if size > callbackDataLimit {
+		return fmt.Errorf("callback route %q may take %d bytes, Telegram allows %d", name, size, callbackDataLimit)
+	}
+
+	r.routes[name] = callbackRoute{params: params, handler: handler}
+	return nil
+}
+
+// match разбирает данные кнопки; ok = false - кнопка неизвестна или ее параметры не подходят маршруту
+func (r *callbackRouter) match(data string) (handler callbackHandler, args callbackArgs, ok bool) {
+	parts := strings.Split(data, callbackSeparator)
+	route, found := r.routes[parts[0]]
+	if !found || len(parts)-1 != len(route.params) {
+		return nil, nil, false
+	}
+
+	args = parts[1:]
+	for i, p := range route.params {
+		if err := p.parse(args[i]); err != nil {
+			return nil, nil, false
+		}
+	}
+	return route.handler, args, true
+}
+
+// encodeCallback собирает данные кнопки; вызывается только из функций *Data ниже
+func encodeCallback(route string, args ...string) string {
+	return strings.Join(append([]string{route}, args...), callbackSeparator)
+}
+
+func idArg(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func pageArg(page int) string {
+	return strconv.Itoa(page)
+}
+
+// Данные кнопок, по функции на маршрут: параметры передаются в тех типах, с которыми маршрут зарегистрирован
+
+func adminMenuData() string {
+	return encodeCallback(routeAdminMenu)
+}
+
+func activeChatsData(page int) string {
+	return encodeCallback(routeActiveChats, pageArg(page))
+}
+
+func archivedChatsData(page int) string {
+	return encodeCallback(routeArchivedChats, pageArg(page))
+}
+
+func chatData(chatID uint, page int) string {
+	return encodeCallback(routeChat, idArg(chatID), pageArg(page))
+}
+
+func openChatData(chatID uint) string {
+	return encodeCallback(routeOpenChat, idArg(chatID))
+}
+
+func chatHistoryData(chatID uint) string {
+	return encodeCallback(routeChatHistory, idArg(chatID))
+}
+
+func chatReplyData(chatID uint) string {
+	return encodeCallback(routeChatReply, idArg(chatID))
+}
+
+func chatArchiveData(chatID uint) string {
+	return encodeCallback(routeChatArchive, idArg(chatID))
+}
+
+func chatFinishData(chatID uint) string {
+	return encodeCallback(routeChatFinish, idArg(chatID))
+}
+
+func chatContinueData() string {
+	return encodeCallback(routeChatContinue)
+}
+
+func showMessageData(messageID uint) string {
+	return encodeCallback(routeShowMessage, idArg(messageID))
+}
+
+func statsData(period string) string {
+	return encodeCallback(routeStats, period)
+}
+
+func templateSaveData() string {
+	return encodeCallback(routeTemplateSave)
+}
+
+func templateCancelData() string {
+	return encodeCallback(routeTemplateCancel)
+}
+
+func languageData(lang string) string {
+	return encodeCallback(routeLanguage, lang)
+}
+
+func clientHistoryData(page int) string {
+	return encodeCallback(routeClientHistory, pageArg(page))
+}
+
+// registerCallbacks описывает все кнопки бота
+func (b *ChatBot) registerCallbacks() error {
+	r := newCallbackRouter()
+	admin := b.adminOnly
+
+	routes := []struct {
+		name    string
+		handler callbackHandler
+		params  []param
+	}{
+		{routeAdminMenu, admin(b.handleAdminMenuCallback), nil},
+		{routeActiveChats, admin(func(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+			b.handleChatListCallback(ctx, query, models.ChatStatusActive, args.Page(0))
+		}), []param{paramPage}},
+		{routeArchivedChats, admin(func(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+			b.handleChatListCallback(ctx, query, models.ChatStatusArchived, args.Page(0))
+		}), []param{paramPage}},
+		{routeChat, admin(func(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+			b.showChat(ctx, query, args.ID(0), args.Page(1), true)
+		}), []param{paramID, paramPage}},
+		{routeOpenChat, admin(func(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+			b.showChat(ctx, query, args.ID(0), 0, false)
+		}), []param{paramID}},
+		{routeChatHistory, admin(b.handleDetailedHistoryCallback), []param{paramID}},
+		{routeChatReply, admin(b.handleAdminReplyCallback), []param{paramID}},
+		{routeChatArchive, admin(b.handleArchiveChatCallback), []param{paramID}},
+		{routeChatFinish, admin(b.handleFinishConversationCallback), []param{paramID}},
+		{routeChatContinue, admin(b.handleContinueChatCallback), nil},
+		{routeShowMessage, admin(b.handleShowMessageCallback), []param{paramID}},
+		{routeStats, admin(b.handleAdminStatsCallback), []param{paramCode}},
+		{routeTemplateSave, admin(b.handleTemplateSaveCallback), nil},
+		{routeTemplateCancel, admin(b.handleTemplateCancelCallback), nil},
+		{routeLanguage, b.handleSetLanguageCallback, []param{paramCode}},
+		{routeClientHistory, b.handleClientHistoryCallback, []param{paramPage}},
+	}
+	for _, route := range routes {
+		if err := r.handle(route.name, route.handler, route.params...); err != nil {
+			return err
+		}
+	}
+
+	b.callbacks = r
+	return nil
+}
+
+// adminOnly пропускает к обработчику только админов
+func (b *ChatBot) adminOnly(next callbackHandler) callbackHandler {
+	return func(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+		if !b.isUserAdmin(query.From.ID) {
+			b.answerCallbackQuery(query.ID, b.t(ctx, "common.not_admin"))
+			return
+		}
+		next(ctx, query, args)
+	}
+}
+
+func (b *ChatBot) handleCallbackQuery(ctx context.Context, query *tgbotapi.CallbackQuery) {
+	handler, args, ok := b.callbacks.match(query.Data)
+	if !ok {
+		// Кнопка из старой версии бота или с испорченными данными: без ответа она так и крутилась бы
+		logging.FromContext(ctx).Warn("Unknown callback data", "data", query.Data)
+		b.answerCallbackQuery(query.ID, b.t(ctx, "common.button_expired"))
+		return
+	}
+	handler(ctx, query, args)
+}
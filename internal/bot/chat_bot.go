@@ -2,249 +2,258 @@ package bot
 
 import (
 	"ai_support_tg_writer_bot/internal/config"
+	"ai_support_tg_writer_bot/internal/events"
+	"ai_support_tg_writer_bot/internal/format"
+	"ai_support_tg_writer_bot/internal/i18n"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/metrics"
 	"ai_support_tg_writer_bot/internal/models"
 	"ai_support_tg_writer_bot/internal/service"
+	"context"
 	"fmt"
-	"log"
-	"strconv"
-	"strings"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-const (
-	CHATS_PER_PAGE    = 5
-	MESSAGES_PER_PAGE = 10
-)
-
 type ChatBot struct {
-	api          *tgbotapi.BotAPI
-	config       *config.Config
-	userService  service.UserService
-	chatService  service.ChatService
-	fileService  service.FileService
-	stateManager *StateManager
-}
-
-func NewChatBot(cfg *config.Config, userService service.UserService, chatService service.ChatService, fileService service.FileService) (*ChatBot, error) {
-	bot, err := tgbotapi.NewBotAPI(cfg.TelegramBotToken)
+	api              *tgbotapi.BotAPI
+	sender           *Sender
+	config           *config.Store // Админы, тексты и рабочее время меняются по SIGHUP, поэтому читаем через Get
+	userService      service.UserService
+	chatService      service.ChatService
+	fileService      service.FileService
+	deliveryService  service.DeliveryService
+	authService      service.AuthService
+	analyticsService service.AnalyticsService
+	templateService  service.TemplateService
+	stateManager     *StateManager
+	templateDrafts   templateDrafts
+	callbacks        *callbackRouter
+	commands         []command
+	commandsMu       sync.Mutex
+	commandAdmins    map[int64]bool // Админы, которым выставлено меню команд админа
+	i18n             *i18n.Bundle
+	logger           *slog.Logger
+
+	updatesHeartbeat atomic.Int64 // Unix-время в наносекундах последнего успешного getUpdates
+	getMeMu          sync.Mutex
+	getMeCheckedAt   time.Time
+	getMeErr         error
+}
+
+func NewChatBot(cfg *config.Store, userService service.UserService, chatService service.ChatService, fileService service.FileService, deliveryService service.DeliveryService, authService service.AuthService, analyticsService service.AnalyticsService, templateService service.TemplateService, bundle *i18n.Bundle, bus *events.Bus, logger *slog.Logger) (*ChatBot, error) {
+	bot, err := tgbotapi.NewBotAPI(cfg.Get().TelegramBotToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create bot: %w", err)
 	}
 
 	bot.Debug = false
 
-	log.Printf("Authorized on account %s", bot.Self.UserName)
+	logger = logger.With("component", "bot")
+	logger.Info("Authorized on Telegram", "username", bot.Self.UserName)
+
+	chatBot := &ChatBot{
+		api:              bot,
+		sender:           NewSender(bot, logger),
+		config:           cfg,
+		userService:      userService,
+		chatService:      chatService,
+		fileService:      fileService,
+		deliveryService:  deliveryService,
+		authService:      authService,
+		analyticsService: analyticsService,
+		templateService:  templateService,
+		stateManager:     NewStateManager(),
+		i18n:             bundle,
+		logger:           logger,
+	}
+	if err := chatBot.registerCallbacks(); err != nil {
+		return nil, fmt.Errorf("failed to register callback routes: %w", err)
+	}
+	if err := chatBot.registerCommands(); err != nil {
+		return nil, fmt.Errorf("failed to register commands: %w", err)
+	}
+	chatBot.subscribe(bus)
 
-	return &ChatBot{
-		api:          bot,
-		config:       cfg,
-		userService:  userService,
-		chatService:  chatService,
-		fileService:  fileService,
-		stateManager: NewStateManager(),
-	}, nil
+	return chatBot, nil
 }
 
 func (b *ChatBot) Start() error {
 	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	u.Timeout = updatesTimeout
 
-	updates := b.api.GetUpdatesChan(u)
+	// Повторная отправка ответов, которые не удалось доставить сразу
+	go b.runDeliveryWorker()
+
+	// Меню команд в Telegram; без него бот работает, поэтому ошибка не мешает запуску
+	if err := b.SyncCommands(logging.WithLogger(context.Background(), b.logger)); err != nil {
+		b.logger.Error("Failed to sync bot commands", "error", err)
+	}
 
-	for update := range updates {
-		if update.Message != nil {
-			b.handleMessage(update.Message)
-		} else if update.CallbackQuery != nil {
-			b.handleCallbackQuery(update.CallbackQuery)
+	// Опрашиваем getUpdates сами, а не через GetUpdatesChan, чтобы отмечать каждый успешный ответ для проверки готовности
+	b.touchUpdatesHeartbeat()
+	for {
+		updates, err := b.api.GetUpdates(u)
+		if err != nil {
+			countTelegramError("getUpdates", err)
+			b.logger.Error("Failed to get updates", "retry_in", updatesRetryDelay, "error", err)
+			time.Sleep(updatesRetryDelay)
+			continue
 		}
+		b.touchUpdatesHeartbeat()
+
+		for _, update := range updates {
+			if update.UpdateID >= u.Offset {
+				u.Offset = update.UpdateID + 1
+				b.handleUpdate(update)
+			}
+		}
+	}
+}
+
+// handleUpdate передает обновление нужному обработчику и учитывает его в метриках.
+// Все логи обработки, включая сервисы и SQL, получают поля update_id, user_id и chat_id через контекст.
+func (b *ChatBot) handleUpdate(update tgbotapi.Update) {
+	start := time.Now()
+	updateType := "other"
+
+	logger := b.logger.With("update_id", update.UpdateID)
+	if from := update.SentFrom(); from != nil {
+		logger = logger.With("user_id", from.ID)
+	}
+	if chat := update.FromChat(); chat != nil {
+		logger = logger.With("chat_id", chat.ID)
 	}
+	ctx := logging.WithLogger(context.Background(), logger)
+	ctx = b.withLanguage(ctx, update.SentFrom())
 
-	return nil
+	if update.Message != nil {
+		updateType = "message"
+		if update.Message.IsCommand() {
+			updateType = "command"
+		}
+		b.handleMessage(ctx, update.Message)
+	} else if update.EditedMessage != nil {
+		updateType = "edited_message"
+		b.handleEditedMessage(ctx, update.EditedMessage)
+	} else if update.CallbackQuery != nil {
+		updateType = "callback_query"
+		b.handleCallbackQuery(ctx, update.CallbackQuery)
+	}
+
+	elapsed := metrics.Since(start)
+	metrics.UpdatesTotal.Inc(updateType)
+	metrics.UpdateDuration.Observe(elapsed, updateType)
+	logger.Debug("Update handled", "type", updateType, "elapsed_seconds", elapsed)
+}
+
+// OutboundQueueDepth - запросы к Telegram, ожидающие отправки, по приоритетам
+func (b *ChatBot) OutboundQueueDepth() map[string]float64 {
+	return b.sender.QueueDepth()
 }
 
-func (b *ChatBot) handleMessage(message *tgbotapi.Message) {
+func (b *ChatBot) handleMessage(ctx context.Context, message *tgbotapi.Message) {
 	// Получаем или создаем пользователя
-	user, err := b.userService.CreateOrGetUser(
+	user, err := b.userService.CreateOrGetUser(ctx,
 		int64(message.From.ID),
 		message.From.UserName,
 		message.From.FirstName,
 		message.From.LastName,
 	)
 	if err != nil {
-		log.Printf("Failed to create/get user: %v", err)
+		logging.FromContext(ctx).Error("Failed to create/get user", "error", err)
 		return
 	}
 
+	// Запоминаем язык, подобранный по Telegram, чтобы писать пользователю на нем и вне его обновлений
+	if user.Language == "" {
+		user.Language = b.localizer(ctx).Lang()
+		if err := b.userService.SetLanguage(ctx, user.TelegramID, user.Language); err != nil {
+			logging.FromContext(ctx).Error("Failed to save user language", "error", err)
+		}
+	}
+
 	// Проверяем, является ли пользователь админом
 	isAdmin := b.isUserAdmin(int64(message.From.ID))
 
 	// Если пользователь админ по конфигурации, но не в базе - обновляем базу
 	if isAdmin && !user.IsAdmin {
-		b.userService.SetAdmin(int64(message.From.ID), true)
+		b.userService.SetAdmin(ctx, int64(message.From.ID), true)
+	}
+
+	// Сообщения из группы поддержки относятся к темам чатов
+	if b.isSupportGroup(message.Chat.ID) {
+		b.handleForumMessage(ctx, message)
+		return
 	}
 
 	// Обрабатываем команды
 	if message.IsCommand() {
-		b.handleCommand(message, user, isAdmin)
+		b.handleCommand(ctx, message, user, isAdmin)
 		return
 	}
 
 	// Обрабатываем обычные сообщения
-	b.handleRegularMessage(message, user, isAdmin)
+	b.handleRegularMessage(ctx, message, user, isAdmin)
 }
 
-func (b *ChatBot) handleCommand(message *tgbotapi.Message, user *models.User, isAdmin bool) {
-	switch message.Command() {
-	case "start":
-		b.handleStartCommand(message, user)
-	case "help":
-		b.handleHelpCommand(message, user, isAdmin)
-	case "admin":
-		if isAdmin {
-			b.handleAdminCommand(message, user)
-		} else {
-			b.sendMessage(message.Chat.ID, "У вас нет прав для выполнения этой команды.")
-		}
-	case "cancel":
-		if isAdmin {
-			b.handleCancelCommand(message, user)
-		} else {
-			b.sendMessage(message.Chat.ID, "У вас нет прав для выполнения этой команды.")
-		}
-	default:
-		b.sendMessage(message.Chat.ID, "Неизвестная команда. Используйте /help для получения списка команд.")
-	}
-}
-
-func (b *ChatBot) handleStartCommand(message *tgbotapi.Message, user *models.User) {
-	welcomeText := `🤖 Добро пожаловать в службу технической поддержки Social Flow!
-
-Здесь вы можете:
-• Задать вопросы по работе с ботом
-• Отправить отзывы о багах или ошибках
-• Приложить скриншоты или записи экрана
-
-Просто напишите ваш вопрос или проблему, и мы обязательно поможем!`
-
-	b.sendMessage(message.Chat.ID, welcomeText)
+func (b *ChatBot) handleStartCommand(ctx context.Context, message *tgbotapi.Message, user *models.User) {
+	text := b.clientText(ctx, models.TemplateWelcome, b.localizer(ctx), service.NewTemplateData(user, 0))
+	b.sendMessage(ctx, message.Chat.ID, text)
 }
 
-func (b *ChatBot) handleHelpCommand(message *tgbotapi.Message, user *models.User, isAdmin bool) {
-	helpText := `📖 Доступные команды:
-
-/start - Начать работу с ботом
-/help - Показать эту справку`
-
-	// Добавляем админские команды если пользователь админ
-	if isAdmin {
-		helpText += `
-
-👨‍💼 Админские команды:
-/admin - Админская панель
-/cancel - Отменить режим ответа на чат`
-	}
-
-	helpText += `
-
-Для создания чата поддержки просто напишите ваш вопрос или проблему. Вы также можете приложить скриншоты или видео для лучшего понимания проблемы.`
-
-	b.sendMessage(message.Chat.ID, helpText)
+func (b *ChatBot) handleHelpCommand(ctx context.Context, message *tgbotapi.Message, user *models.User) {
+	// Админские команды видны только админам
+	isAdmin := b.isUserAdmin(int64(message.From.ID))
+	b.sendMessage(ctx, message.Chat.ID, b.helpText(b.localizer(ctx), isAdmin))
 }
 
-func (b *ChatBot) handleAdminCommand(message *tgbotapi.Message, user *models.User) {
+func (b *ChatBot) handleAdminCommand(ctx context.Context, message *tgbotapi.Message, user *models.User) {
 	// Очищаем состояние ответа при входе в админку
 	b.stateManager.ClearUserState(int64(message.From.ID))
 
-	// Получаем количество непрочитанных чатов
-	unreadCount, _ := b.chatService.GetUnreadChatsCount()
-
-	adminText := fmt.Sprintf(`👨‍💼 Админская панель
-
-Непрочитанных чатов: %d
-
-Выберите действие:`, unreadCount)
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💬 Активные чаты", "admin_active_chats"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📁 Архивированные чаты", "admin_archived_chats"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📊 Статистика", "admin_stats"),
-		),
-	)
-
-	msg := tgbotapi.NewMessage(message.Chat.ID, adminText)
-	msg.ReplyMarkup = keyboard
-	b.api.Send(msg)
+	b.sendScreen(ctx, message.Chat.ID, b.adminMenuScreen(ctx))
 }
 
-func (b *ChatBot) handleCancelCommand(message *tgbotapi.Message, user *models.User) {
+func (b *ChatBot) handleCancelCommand(ctx context.Context, message *tgbotapi.Message, user *models.User) {
 	// Очищаем состояние ответа
 	b.stateManager.ClearUserState(int64(message.From.ID))
-	b.sendMessage(message.Chat.ID, "✅ Режим ответа отменен. Используйте /admin для доступа к админской панели.")
+	b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "admin.cancel_done"))
 }
 
-func (b *ChatBot) handleRegularMessage(message *tgbotapi.Message, user *models.User, isAdmin bool) {
-	// Проверяем, находится ли админ в режиме ответа на чат
+func (b *ChatBot) handleRegularMessage(ctx context.Context, message *tgbotapi.Message, user *models.User, isAdmin bool) {
+	// Цитируемое сообщение, если пользователь ответил на сообщение через "Ответить" в Telegram
+	quoted := b.resolveQuotedMessage(ctx, message)
+
 	if isAdmin {
+		// Ответ на уведомление о сообщении клиента сразу направляется в нужный чат
+		if quoted != nil {
+			b.handleAdminReply(ctx, message, quoted.ChatID, quoted)
+			return
+		}
+
+		// Проверяем, находится ли админ в режиме ответа на чат
 		isReplying, chatID := b.stateManager.IsReplyingToTicket(int64(message.From.ID))
 		if isReplying {
-			// Админ отвечает в чат
-			admin, err := b.userService.GetUserByTelegramID(int64(message.From.ID))
-			if err != nil {
-				log.Printf("Failed to get admin user: %v", err)
-				b.sendMessage(message.Chat.ID, "Произошла ошибка.")
-				return
-			}
-
-			// Определяем содержимое сообщения (текст или подпись к медиа)
-			content := message.Text
-			if content == "" && message.Caption != "" {
-				content = message.Caption
-			}
-
-			// Добавляем ответ от админа
-			adminMessage, err := b.chatService.AddMessage(chatID, admin.ID, content, false)
-			if err != nil {
-				log.Printf("Failed to add admin message: %v", err)
-				b.sendMessage(message.Chat.ID, "Произошла ошибка при отправке ответа.")
-				return
-			}
-
-			// Обрабатываем файлы если есть
-			if err := b.handleMessageFiles(message, adminMessage.ID); err != nil {
-				log.Printf("Failed to handle files: %v", err)
-			}
-
-			// Отправляем ответ клиенту
-			if err := b.sendResponseToClient(chatID, content, message); err != nil {
-				log.Printf("Failed to send response to client: %v", err)
-			}
-
-			// Показываем кнопку "Закончить разговор"
-			b.showFinishConversationButton(message.Chat.ID, chatID)
-
+			b.handleAdminReply(ctx, message, chatID, nil)
 			// НЕ очищаем состояние ответа - админ остается в режиме ответа
 			return
 		}
-	}
 
-	// Если это админ без выбора меню - игнорируем
-	if isAdmin {
 		// Админ пишет без выбора чата - ничего не делаем
 		return
 	}
 
 	// Обычная логика для клиентов
 	// Создаем или получаем чат пользователя
-	chat, err := b.chatService.CreateOrGetChat(user.ID)
+	chat, err := b.chatService.CreateOrGetChat(ctx, user.ID)
 	if err != nil {
-		log.Printf("Failed to create/get chat: %v", err)
-		b.sendMessage(message.Chat.ID, "Произошла ошибка. Попробуйте позже.")
+		logging.FromContext(ctx).Error("Failed to create/get chat", "error", err)
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "common.error"))
 		return
 	}
 
@@ -254,519 +263,238 @@ func (b *ChatBot) handleRegularMessage(message *tgbotapi.Message, user *models.U
 		content = message.Caption
 	}
 
-	// Добавляем сообщение в чат
-	chatMessage, err := b.chatService.AddMessage(chat.ID, user.ID, content, true)
+	// Сохраняем сообщение. Уведомления админам, публикация в тему и подтверждение клиенту
+	// выполняются подписчиками события MessageReceived.
+	_, err = b.chatService.PostMessage(ctx, chat.ID, user.ID, true, service.NewMessage{
+		Content:           content,
+		TelegramChatID:    message.Chat.ID,
+		TelegramMessageID: message.MessageID,
+		Files:             messageFiles(message),
+		Quoted:            quoted,
+	})
 	if err != nil {
-		log.Printf("Failed to add message: %v", err)
-		b.sendMessage(message.Chat.ID, "Произошла ошибка при отправке сообщения.")
+		logging.FromContext(ctx).Error("Failed to add message", "error", err)
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "client.send_failed"))
 		return
 	}
-
-	// Обрабатываем файлы если есть
-	if err := b.handleMessageFiles(message, chatMessage.ID); err != nil {
-		log.Printf("Failed to handle files: %v", err)
-	}
-
-	// Отправляем уведомление админам о новом сообщении
-	b.notifyAdminsAboutNewMessage(chat.ID, user, content)
-
-	// Отправляем медиа файлы админам если есть
-	if err := b.sendMediaToAdmins(chat.ID, user, message); err != nil {
-		log.Printf("Failed to send media to admins: %v", err)
-	}
-
-	b.sendMessage(message.Chat.ID, "✅ Сообщение отправлено! Мы получили ваше сообщение и скоро ответим.")
 }
 
-func (b *ChatBot) handleMessageFiles(message *tgbotapi.Message, messageID uint) error {
-
-	// Обрабатываем различные типы файлов
-	if message.Photo != nil && len(message.Photo) > 0 {
-		photo := message.Photo[len(message.Photo)-1] // Берем самое большое фото
-		_, err := b.fileService.CreateFile(
-			messageID,
-			photo.FileID,
-			"photo.jpg",
-			"photo",
-			int64(photo.FileSize),
-		)
-		if err != nil {
-			return err
-		}
-	}
-
-	if message.Video != nil {
-		_, err := b.fileService.CreateFile(
-			messageID,
-			message.Video.FileID,
-			message.Video.FileName,
-			"video",
-			int64(message.Video.FileSize),
-		)
-		if err != nil {
-			return err
-		}
-	}
-
-	if message.Document != nil {
-		_, err := b.fileService.CreateFile(
-			messageID,
-			message.Document.FileID,
-			message.Document.FileName,
-			"document",
-			int64(message.Document.FileSize),
-		)
-		if err != nil {
-			return err
-		}
-	}
-
-	if message.Voice != nil {
-		_, err := b.fileService.CreateFile(
-			messageID,
-			message.Voice.FileID,
-			"voice.ogg",
-			"voice",
-			int64(message.Voice.FileSize),
-		)
-		if err != nil {
-			return err
+// handleAdminReply сохраняет ответ админа в чат и доставляет его клиенту
+func (b *ChatBot) handleAdminReply(ctx context.Context, message *tgbotapi.Message, chatID uint, quoted *models.ChatMessage) {
+	adminMessage, err := b.relayAdminReply(ctx, message, chatID, quoted)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to relay admin reply", "error", err)
+		if adminMessage == nil {
+			b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "admin.reply_failed"))
+			return
 		}
 	}
 
-	if message.VideoNote != nil {
-		_, err := b.fileService.CreateFile(
-			messageID,
-			message.VideoNote.FileID,
-			"video_note.mp4",
-			"video_note",
-			int64(message.VideoNote.FileSize),
-		)
-		if err != nil {
-			return err
-		}
+	// В режиме тем ответ из личного чата дублируется в тему, чтобы группа видела всю переписку
+	if b.forumEnabled() {
+		b.mirrorAdminReplyToTopic(ctx, chatID, message)
 	}
 
-	return nil
+	// Показываем кнопку "Закончить разговор" и результат доставки
+	b.showFinishConversationButton(ctx, message.Chat.ID, chatID, adminMessage.DeliveryStatus)
 }
 
-func (b *ChatBot) handleCallbackQuery(query *tgbotapi.CallbackQuery) {
-	// Обработка callback запросов от inline кнопок
-	switch query.Data {
-	case "admin_active_chats":
-		b.handleAdminActiveChatsCallback(query)
-	case "admin_archived_chats":
-		b.handleAdminArchivedChatsCallback(query)
-	case "admin_stats":
-		b.handleAdminStatsCallback(query)
-	default:
-		if strings.HasPrefix(query.Data, "view_chat_") {
-			if strings.Contains(query.Data, "_page_") {
-				b.handleViewChatPageCallback(query)
-			} else {
-				b.handleViewChatCallback(query)
-			}
-		} else if strings.HasPrefix(query.Data, "admin_reply_") {
-			b.handleAdminReplyCallback(query)
-		} else if strings.HasPrefix(query.Data, "archive_chat_") {
-			b.handleArchiveChatCallback(query)
-		} else if strings.HasPrefix(query.Data, "finish_conversation_") {
-			b.handleFinishConversationCallback(query)
-		} else if query.Data == "continue_chat" {
-			b.handleContinueChatCallback(query)
-		} else if strings.HasPrefix(query.Data, "active_chats_page_") {
-			b.handleActiveChatsPageCallback(query)
-		} else if strings.HasPrefix(query.Data, "archived_chats_page_") {
-			b.handleArchivedChatsPageCallback(query)
-		} else if strings.HasPrefix(query.Data, "detailed_history_") {
-			b.handleDetailedHistoryCallback(query)
-		} else if query.Data == "admin_menu" {
-			b.handleAdminMenuCallback(query)
-		}
+// relayAdminReply сохраняет сообщение админа в чат и отправляет его клиенту.
+// Возвращает сохраненное сообщение с итоговым статусом доставки, даже если доставить его не удалось.
+func (b *ChatBot) relayAdminReply(ctx context.Context, message *tgbotapi.Message, chatID uint, quoted *models.ChatMessage) (*models.ChatMessage, error) {
+	admin, err := b.userService.GetUserByTelegramID(ctx, int64(message.From.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin user: %w", err)
 	}
-}
-
-func (b *ChatBot) handleAdminActiveChatsCallback(query *tgbotapi.CallbackQuery) {
-	b.handleAdminActiveChatsCallbackWithPage(query, 0)
-}
 
-func (b *ChatBot) handleAdminActiveChatsCallbackWithPage(query *tgbotapi.CallbackQuery, page int) {
-	// Проверяем права админа
-	if !b.isUserAdmin(int64(query.From.ID)) {
-		b.answerCallbackQuery(query.ID, "У вас нет прав администратора.")
-		return
+	// Определяем содержимое сообщения (текст или подпись к медиа)
+	content := message.Text
+	if content == "" && message.Caption != "" {
+		content = message.Caption
 	}
 
-	offset := page * CHATS_PER_PAGE
-	chats, err := b.chatService.GetActiveChatsPaginated(CHATS_PER_PAGE, offset)
+	// Добавляем ответ от админа вместе с исходным сообщением, чтобы отслеживать его редактирование
+	adminMessage, err := b.chatService.PostMessage(ctx, chatID, admin.ID, false, service.NewMessage{
+		Content:           content,
+		TelegramChatID:    message.Chat.ID,
+		TelegramMessageID: message.MessageID,
+		Files:             messageFiles(message),
+		Quoted:            quoted,
+	})
 	if err != nil {
-		b.answerCallbackQuery(query.ID, "Ошибка при получении чатов.")
-		return
+		return nil, fmt.Errorf("failed to add admin message: %w", err)
 	}
 
-	if len(chats) == 0 && page == 0 {
-		msg := tgbotapi.NewMessage(query.Message.Chat.ID, "💬 Активных чатов нет.")
-		b.api.Send(msg)
-		b.answerCallbackQuery(query.ID, "")
-		return
+	// Отправляем ответ клиенту
+	status, err := b.sendResponseToClient(ctx, adminMessage, message, quoted)
+	adminMessage.DeliveryStatus = status
+	if err != nil {
+		return adminMessage, fmt.Errorf("failed to send response to client: %w", err)
 	}
 
-	text := fmt.Sprintf("💬 Активные чаты (страница %d):\n\n", page+1)
-	for _, chat := range chats {
-		unreadBadge := ""
-		if chat.UnreadCount > 0 {
-			unreadBadge = fmt.Sprintf(" 🔴(%d)", chat.UnreadCount)
-		}
+	return adminMessage, nil
+}
 
-		text += fmt.Sprintf("🔸 Чат #%d%s\n", chat.ID, unreadBadge)
-		text += fmt.Sprintf("👤 %s\n", b.formatUserName(&chat.User))
+// resolveQuotedMessage находит сообщение чата, на которое пользователь ответил через "Ответить" в Telegram
+func (b *ChatBot) resolveQuotedMessage(ctx context.Context, message *tgbotapi.Message) *models.ChatMessage {
+	if message.ReplyToMessage == nil {
+		return nil
+	}
 
-		if chat.LastMessageAt != nil {
-			text += fmt.Sprintf("📅 %s\n\n", chat.LastMessageAt.Format("02.01.2006 15:04"))
-		} else {
-			text += fmt.Sprintf("📅 %s\n\n", chat.CreatedAt.Format("02.01.2006 15:04"))
-		}
+	quoted, err := b.chatService.ResolveTelegramMessage(ctx, message.Chat.ID, message.ReplyToMessage.MessageID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to resolve quoted message", "error", err)
+		return nil
 	}
 
-	// Создаем кнопки для каждого чата
-	var buttons [][]tgbotapi.InlineKeyboardButton
-	for _, chat := range chats {
-		unreadBadge := ""
-		if chat.UnreadCount > 0 {
-			unreadBadge = " 🔴"
-		}
+	return quoted
+}
 
-		button := tgbotapi.NewInlineKeyboardButtonData(
-			fmt.Sprintf("🔸 #%d%s - %s", chat.ID, unreadBadge, chat.User.FirstName),
-			fmt.Sprintf("view_chat_%d", chat.ID),
-		)
-		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(button))
+// quotedMessageID возвращает message_id цитируемого сообщения в указанном чате Telegram, 0 если его там нет
+func (b *ChatBot) quotedMessageID(ctx context.Context, quoted *models.ChatMessage, telegramChatID int64) int {
+	if quoted == nil {
+		return 0
 	}
 
-	// Добавляем кнопки навигации
-	var navButtons []tgbotapi.InlineKeyboardButton
-	if page > 0 {
-		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("active_chats_page_%d", page-1)))
-	}
-	if len(chats) == CHATS_PER_PAGE {
-		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Вперед ➡️", fmt.Sprintf("active_chats_page_%d", page+1)))
-	}
-	if len(navButtons) > 0 {
-		buttons = append(buttons, navButtons)
+	messageID, err := b.chatService.FindTelegramMessageID(ctx, quoted, telegramChatID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to find quoted message copy", "error", err)
+		return 0
 	}
 
-	// Добавляем кнопку "Назад в админку"
-	backButton := tgbotapi.NewInlineKeyboardButtonData("🔙 Назад в админку", "admin_menu")
-	buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(backButton))
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
-
-	msg := tgbotapi.NewMessage(query.Message.Chat.ID, text)
-	msg.ReplyMarkup = keyboard
-	b.api.Send(msg)
-	b.answerCallbackQuery(query.ID, "")
+	return messageID
 }
 
-func (b *ChatBot) handleAdminArchivedChatsCallback(query *tgbotapi.CallbackQuery) {
-	b.handleAdminArchivedChatsCallbackWithPage(query, 0)
+// messageMarkup - текст или подпись сообщения Telegram с оформлением в разметке HTML
+func messageMarkup(message *tgbotapi.Message) string {
+	if message.Text != "" {
+		return format.FromEntities(message.Text, message.Entities)
+	}
+	return format.FromEntities(message.Caption, message.CaptionEntities)
 }
 
-func (b *ChatBot) handleAdminArchivedChatsCallbackWithPage(query *tgbotapi.CallbackQuery, page int) {
-	// Проверяем права админа
-	if !b.isUserAdmin(int64(query.From.ID)) {
-		b.answerCallbackQuery(query.ID, "У вас нет прав администратора.")
-		return
-	}
+// messageFiles описывает вложения сообщения Telegram для сохранения в чат
+func messageFiles(message *tgbotapi.Message) []models.File {
+	var files []models.File
 
-	offset := page * CHATS_PER_PAGE
-	chats, err := b.chatService.GetArchivedChatsPaginated(CHATS_PER_PAGE, offset)
-	if err != nil {
-		b.answerCallbackQuery(query.ID, "Ошибка при получении чатов.")
-		return
+	if message.Photo != nil && len(message.Photo) > 0 {
+		photo := message.Photo[len(message.Photo)-1] // Берем самое большое фото
+		files = append(files, models.File{
+			FileID:   photo.FileID,
+			FileName: "photo.jpg",
+			FileType: "photo",
+			FileSize: int64(photo.FileSize),
+		})
 	}
 
-	if len(chats) == 0 && page == 0 {
-		msg := tgbotapi.NewMessage(query.Message.Chat.ID, "📁 Архивированных чатов нет.")
-		b.api.Send(msg)
-		b.answerCallbackQuery(query.ID, "")
-		return
+	if message.Video != nil {
+		files = append(files, models.File{
+			FileID:   message.Video.FileID,
+			FileName: message.Video.FileName,
+			FileType: "video",
+			FileSize: int64(message.Video.FileSize),
+		})
 	}
 
-	text := fmt.Sprintf("📁 Архивированные чаты (страница %d):\n\n", page+1)
-	for _, chat := range chats {
-		text += fmt.Sprintf("🔸 Чат #%d\n", chat.ID)
-		text += fmt.Sprintf("👤 %s\n", b.formatUserName(&chat.User))
-		text += fmt.Sprintf("📅 %s\n\n", chat.UpdatedAt.Format("02.01.2006 15:04"))
+	if message.Document != nil {
+		files = append(files, models.File{
+			FileID:   message.Document.FileID,
+			FileName: message.Document.FileName,
+			FileType: "document",
+			FileSize: int64(message.Document.FileSize),
+		})
 	}
 
-	// Создаем кнопки для каждого чата
-	var buttons [][]tgbotapi.InlineKeyboardButton
-	for _, chat := range chats {
-		button := tgbotapi.NewInlineKeyboardButtonData(
-			fmt.Sprintf("🔸 #%d - %s", chat.ID, chat.User.FirstName),
-			fmt.Sprintf("view_chat_%d", chat.ID),
-		)
-		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(button))
+	if message.Voice != nil {
+		files = append(files, models.File{
+			FileID:   message.Voice.FileID,
+			FileName: "voice.ogg",
+			FileType: "voice",
+			FileSize: int64(message.Voice.FileSize),
+		})
 	}
 
-	// Добавляем кнопки навигации
-	var navButtons []tgbotapi.InlineKeyboardButton
-	if page > 0 {
-		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("archived_chats_page_%d", page-1)))
-	}
-	if len(chats) == CHATS_PER_PAGE {
-		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Вперед ➡️", fmt.Sprintf("archived_chats_page_%d", page+1)))
-	}
-	if len(navButtons) > 0 {
-		buttons = append(buttons, navButtons)
+	if message.VideoNote != nil {
+		files = append(files, models.File{
+			FileID:   message.VideoNote.FileID,
+			FileName: "video_note.mp4",
+			FileType: "video_note",
+			FileSize: int64(message.VideoNote.FileSize),
+		})
 	}
 
-	// Добавляем кнопку "Назад в админку"
-	backButton := tgbotapi.NewInlineKeyboardButtonData("🔙 Назад в админку", "admin_menu")
-	buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(backButton))
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
-
-	msg := tgbotapi.NewMessage(query.Message.Chat.ID, text)
-	msg.ReplyMarkup = keyboard
-	b.api.Send(msg)
-	b.answerCallbackQuery(query.ID, "")
+	return files
 }
 
-func (b *ChatBot) handleAdminStatsCallback(query *tgbotapi.CallbackQuery) {
-	// Проверяем права админа
-	if !b.isUserAdmin(int64(query.From.ID)) {
-		b.answerCallbackQuery(query.ID, "У вас нет прав администратора.")
+// handleChatListCallback показывает страницу списка чатов на месте сообщения с кнопкой
+func (b *ChatBot) handleChatListCallback(ctx context.Context, query *tgbotapi.CallbackQuery, status models.ChatStatus, page int) {
+	s, err := b.chatListScreen(ctx, status, page)
+	if err != nil {
+		b.answerScreenError(ctx, query, err)
 		return
 	}
 
-	activeChats, _ := b.chatService.GetActiveChats()
-	archivedChats, _ := b.chatService.GetArchivedChats()
-	unreadCount, _ := b.chatService.GetUnreadChatsCount()
-
-	text := "📊 Статистика чатов:\n\n"
-	text += fmt.Sprintf("💬 Активные: %d\n", len(activeChats))
-	text += fmt.Sprintf("📁 Архивированные: %d\n", len(archivedChats))
-	text += fmt.Sprintf("🔴 Непрочитанные: %d\n", unreadCount)
-	text += fmt.Sprintf("📈 Всего: %d\n", len(activeChats)+len(archivedChats))
-
-	msg := tgbotapi.NewMessage(query.Message.Chat.ID, text)
-	b.api.Send(msg)
+	b.showScreen(ctx, query, s)
 	b.answerCallbackQuery(query.ID, "")
 }
 
-func (b *ChatBot) handleViewChatCallback(query *tgbotapi.CallbackQuery) {
-	b.handleViewChatCallbackWithPage(query, 0)
-}
-
-func (b *ChatBot) handleViewChatCallbackWithPage(query *tgbotapi.CallbackQuery, page int) {
-	// Проверяем права админа
-	if !b.isUserAdmin(int64(query.From.ID)) {
-		b.answerCallbackQuery(query.ID, "У вас нет прав администратора.")
-		return
-	}
-
-	// Извлекаем ID чата
-	parts := strings.Split(query.Data, "_")
-	if len(parts) != 3 {
-		return
-	}
-
-	chatID, err := strconv.ParseUint(parts[2], 10, 32)
+// showChat показывает страницу чата: на месте сообщения с кнопкой (inPlace) или новым сообщением.
+// Из уведомления чат открывается новым сообщением: на уведомление отвечают через "Ответить", его нельзя заменять.
+func (b *ChatBot) showChat(ctx context.Context, query *tgbotapi.CallbackQuery, chatID uint, page int, inPlace bool) {
+	s, err := b.chatScreen(ctx, chatID, page)
 	if err != nil {
-		return
-	}
-
-	chat, err := b.chatService.GetChatByID(uint(chatID))
-	if err != nil {
-		b.answerCallbackQuery(query.ID, "Чат не найден.")
+		b.answerScreenError(ctx, query, err)
 		return
 	}
 
 	// Помечаем чат как прочитанный
-	b.chatService.MarkChatAsRead(uint(chatID))
-
-	// Получаем сообщения с пагинацией
-	offset := page * MESSAGES_PER_PAGE
-	messages, err := b.chatService.GetChatMessagesPaginated(uint(chatID), MESSAGES_PER_PAGE, offset)
-	if err != nil {
-		b.answerCallbackQuery(query.ID, "Ошибка при получении сообщений.")
-		return
-	}
-
-	// Получаем общее количество сообщений
-	totalMessages, err := b.chatService.GetChatMessagesCount(uint(chatID))
-	if err != nil {
-		b.answerCallbackQuery(query.ID, "Ошибка при получении количества сообщений.")
-		return
-	}
-
-	// Формируем текст с информацией о чате
-	text := fmt.Sprintf("🔸 Чат #%d\n", chat.ID)
-	text += fmt.Sprintf("👤 Пользователь: %s\n", b.formatUserName(&chat.User))
-	text += fmt.Sprintf("📅 Создан: %s\n", chat.CreatedAt.Format("02.01.2006 15:04"))
-
-	status := "💬 Активный"
-	if chat.Status == models.ChatStatusArchived {
-		status = "📁 Архивированный"
-	}
-	text += fmt.Sprintf("📊 Статус: %s\n", status)
-	text += fmt.Sprintf("💬 Сообщений: %d\n\n", totalMessages)
-
-	// Добавляем сообщения
-	if len(messages) > 0 {
-		text += fmt.Sprintf("💬 Сообщения (страница %d):\n", page+1)
-		for _, message := range messages {
-			sender := "👤 Клиент"
-			if !message.IsFromUser {
-				sender = "👨‍💼 Админ"
-			}
+	b.chatService.MarkChatAsRead(ctx, chatID)
 
-			// Добавляем информацию о файлах если есть
-			content := message.Content
-			if len(message.Files) > 0 {
-				content += " 📎"
-			}
-
-			text += fmt.Sprintf("%s: %s\n", sender, content)
-			text += fmt.Sprintf("📅 %s\n\n", message.CreatedAt.Format("02.01.2006 15:04"))
-		}
+	if inPlace {
+		b.showScreen(ctx, query, s)
 	} else {
-		text += "💬 Сообщений пока нет.\n"
-	}
-
-	// Создаем кнопки
-	var buttons [][]tgbotapi.InlineKeyboardButton
-
-	// Кнопки навигации по сообщениям
-	var navButtons []tgbotapi.InlineKeyboardButton
-	if page > 0 {
-		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("view_chat_%d_page_%d", chatID, page-1)))
-	}
-	if int64(offset+MESSAGES_PER_PAGE) < totalMessages {
-		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData("Вперед ➡️", fmt.Sprintf("view_chat_%d_page_%d", chatID, page+1)))
-	}
-	if len(navButtons) > 0 {
-		buttons = append(buttons, navButtons)
-	}
-
-	// Кнопка для детального просмотра истории
-	historyButton := tgbotapi.NewInlineKeyboardButtonData(
-		"📋 Детальная история",
-		fmt.Sprintf("detailed_history_%d", chat.ID),
-	)
-	buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(historyButton))
-
-	if chat.Status == models.ChatStatusActive {
-		replyButton := tgbotapi.NewInlineKeyboardButtonData(
-			"💬 Ответить",
-			fmt.Sprintf("admin_reply_%d", chat.ID),
-		)
-		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(replyButton))
-
-		archiveButton := tgbotapi.NewInlineKeyboardButtonData(
-			"📁 Архивировать",
-			fmt.Sprintf("archive_chat_%d", chat.ID),
-		)
-		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(archiveButton))
-	}
-
-	// Определяем откуда пришли (активные или архивированные чаты)
-	backButton := tgbotapi.NewInlineKeyboardButtonData(
-		"🔙 Назад к чатам",
-		"admin_active_chats",
-	)
-	if chat.Status == models.ChatStatusArchived {
-		backButton = tgbotapi.NewInlineKeyboardButtonData(
-			"🔙 Назад к чатам",
-			"admin_archived_chats",
-		)
+		b.sendScreen(ctx, query.Message.Chat.ID, s)
 	}
-	buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(backButton))
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
-
-	msg := tgbotapi.NewMessage(query.Message.Chat.ID, text)
-	msg.ReplyMarkup = keyboard
-	b.api.Send(msg)
 	b.answerCallbackQuery(query.ID, "")
 }
 
-func (b *ChatBot) handleAdminReplyCallback(query *tgbotapi.CallbackQuery) {
-	// Проверяем права админа
-	if !b.isUserAdmin(int64(query.From.ID)) {
-		b.answerCallbackQuery(query.ID, "У вас нет прав администратора.")
-		return
-	}
-
-	// Извлекаем ID чата
-	parts := strings.Split(query.Data, "_")
-	if len(parts) != 3 {
-		return
-	}
-
-	chatID, err := strconv.ParseUint(parts[2], 10, 32)
-	if err != nil {
-		return
-	}
+func (b *ChatBot) handleAdminReplyCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+	chatID := args.ID(0)
 
 	// Устанавливаем состояние ответа на чат
-	b.stateManager.SetReplyingState(int64(query.From.ID), uint(chatID))
+	b.stateManager.SetReplyingState(int64(query.From.ID), chatID)
 
-	text := fmt.Sprintf("💬 Ответ в чат #%d\n\nНапишите ваш ответ:", chatID)
+	text := b.t(ctx, "admin.reply.prompt", chatID)
 
-	msg := tgbotapi.NewMessage(query.Message.Chat.ID, text)
-	b.api.Send(msg)
-	b.answerCallbackQuery(query.ID, "Напишите ответ в чат.")
+	msg := newMessage(query.Message.Chat.ID, text)
+	b.send(ctx, msg, PriorityNormal)
+	b.answerCallbackQuery(query.ID, b.t(ctx, "admin.reply.prompt_short"))
 }
 
-func (b *ChatBot) handleArchiveChatCallback(query *tgbotapi.CallbackQuery) {
-	// Проверяем права админа
-	if !b.isUserAdmin(int64(query.From.ID)) {
-		b.answerCallbackQuery(query.ID, "У вас нет прав администратора.")
-		return
-	}
+func (b *ChatBot) handleArchiveChatCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+	chatID := args.ID(0)
 
-	// Извлекаем ID чата
-	parts := strings.Split(query.Data, "_")
-	if len(parts) != 3 {
-		return
-	}
-
-	chatID, err := strconv.ParseUint(parts[2], 10, 32)
-	if err != nil {
+	if err := b.chatService.ArchiveChat(ctx, chatID); err != nil {
+		b.answerCallbackQuery(query.ID, b.t(ctx, "admin.archive.failed"))
 		return
 	}
 
-	if err := b.chatService.ArchiveChat(uint(chatID)); err != nil {
-		b.answerCallbackQuery(query.ID, "Ошибка при архивировании чата.")
-		return
+	// Карточка чата обновляется на месте: статус меняется, кнопки ответа и архивации пропадают
+	if s, err := b.chatScreen(ctx, chatID, 0); err == nil {
+		b.showScreen(ctx, query, s)
+	} else {
+		logging.FromContext(ctx).Error("Failed to render chat screen", "chat_id", chatID, "error", err)
 	}
 
-	b.answerCallbackQuery(query.ID, "Чат архивирован.")
+	b.answerCallbackQuery(query.ID, b.t(ctx, "admin.archive.done"))
 }
 
-func (b *ChatBot) handleFinishConversationCallback(query *tgbotapi.CallbackQuery) {
-	// Проверяем права админа
-	if !b.isUserAdmin(int64(query.From.ID)) {
-		b.answerCallbackQuery(query.ID, "У вас нет прав администратора.")
-		return
-	}
-
-	// Извлекаем ID чата
-	parts := strings.Split(query.Data, "_")
-	if len(parts) != 3 {
-		return
-	}
-
-	chatID, err := strconv.ParseUint(parts[2], 10, 32)
-	if err != nil {
-		return
-	}
+func (b *ChatBot) handleFinishConversationCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+	chatID := args.ID(0)
 
 	// Архивируем чат
-	if err := b.chatService.ArchiveChat(uint(chatID)); err != nil {
-		b.answerCallbackQuery(query.ID, "Ошибка при архивировании чата.")
+	if err := b.chatService.ArchiveChat(ctx, chatID); err != nil {
+		b.answerCallbackQuery(query.ID, b.t(ctx, "admin.archive.failed"))
 		return
 	}
 
@@ -774,204 +502,103 @@ func (b *ChatBot) handleFinishConversationCallback(query *tgbotapi.CallbackQuery
 	b.stateManager.ClearUserState(int64(query.From.ID))
 
 	// Возвращаемся в главное меню админа
-	b.handleAdminCommand(&tgbotapi.Message{
-		Chat: &tgbotapi.Chat{ID: query.Message.Chat.ID},
-		From: query.From,
-	}, &models.User{})
+	b.showScreen(ctx, query, b.adminMenuScreen(ctx))
 
-	b.answerCallbackQuery(query.ID, "Разговор завершен. Чат архивирован.")
+	b.answerCallbackQuery(query.ID, b.t(ctx, "admin.archive.finished"))
 }
 
-func (b *ChatBot) handleContinueChatCallback(query *tgbotapi.CallbackQuery) {
-	// Проверяем права админа
-	if !b.isUserAdmin(int64(query.From.ID)) {
-		b.answerCallbackQuery(query.ID, "У вас нет прав администратора.")
-		return
-	}
+func (b *ChatBot) handleContinueChatCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackArgs) {
+	// Выбор сделан, кнопки под статусом доставки больше не нужны
+	b.removeKeyboard(ctx, query.Message)
 
 	// Просто подтверждаем, что админ может продолжать общение
-	msg := tgbotapi.NewMessage(query.Message.Chat.ID, "💬 Продолжайте общение! Ваши сообщения будут отправляться в активный чат.")
-	b.api.Send(msg)
+	msg := newMessage(query.Message.Chat.ID, b.t(ctx, "admin.reply.continue"))
+	b.send(ctx, msg, PriorityNormal)
 
-	b.answerCallbackQuery(query.ID, "Продолжайте общение.")
+	b.answerCallbackQuery(query.ID, b.t(ctx, "admin.reply.continue_short"))
 }
 
-func (b *ChatBot) handleViewChatPageCallback(query *tgbotapi.CallbackQuery) {
-	// Извлекаем ID чата и номер страницы
-	// Формат: view_chat_1_page_0
-	parts := strings.Split(query.Data, "_")
-	if len(parts) != 5 {
-		return
-	}
-
-	chatID, err := strconv.ParseUint(parts[2], 10, 32)
-	if err != nil {
-		return
-	}
-
-	page, err := strconv.Atoi(parts[4])
-	if err != nil {
-		return
-	}
-
-	// Создаем новый callback query с правильным форматом
-	newQuery := *query
-	newQuery.Data = fmt.Sprintf("view_chat_%d", chatID)
-
-	b.handleViewChatCallbackWithPage(&newQuery, page)
-}
-
-func (b *ChatBot) handleActiveChatsPageCallback(query *tgbotapi.CallbackQuery) {
-	// Извлекаем номер страницы
-	// Формат: active_chats_page_1
-	parts := strings.Split(query.Data, "_")
-	if len(parts) != 4 {
-		return
-	}
-
-	page, err := strconv.Atoi(parts[3])
-	if err != nil {
-		return
-	}
-
-	b.handleAdminActiveChatsCallbackWithPage(query, page)
-}
-
-func (b *ChatBot) handleArchivedChatsPageCallback(query *tgbotapi.CallbackQuery) {
-	// Извлекаем номер страницы
-	// Формат: archived_chats_page_1
-	parts := strings.Split(query.Data, "_")
-	if len(parts) != 4 {
-		return
-	}
-
-	page, err := strconv.Atoi(parts[3])
-	if err != nil {
-		return
-	}
-
-	b.handleAdminArchivedChatsCallbackWithPage(query, page)
-}
-
-func (b *ChatBot) handleAdminMenuCallback(query *tgbotapi.CallbackQuery) {
-	// Возвращаемся в главное меню админа
-	b.handleAdminCommand(&tgbotapi.Message{
-		Chat: &tgbotapi.Chat{ID: query.Message.Chat.ID},
-		From: query.From,
-	}, &models.User{})
+func (b *ChatBot) handleAdminMenuCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackArgs) {
+	// Возвращаемся в главное меню админа, как и по /admin сбрасывая режим ответа
+	b.stateManager.ClearUserState(int64(query.From.ID))
+	b.showScreen(ctx, query, b.adminMenuScreen(ctx))
 
-	b.answerCallbackQuery(query.ID, "Возврат в главное меню.")
+	b.answerCallbackQuery(query.ID, b.t(ctx, "admin.menu.returned"))
 }
 
-func (b *ChatBot) handleDetailedHistoryCallback(query *tgbotapi.CallbackQuery) {
-	// Проверяем права админа
-	if !b.isUserAdmin(int64(query.From.ID)) {
-		b.answerCallbackQuery(query.ID, "У вас нет прав администратора.")
-		return
-	}
-
-	// Извлекаем ID чата
-	parts := strings.Split(query.Data, "_")
-	if len(parts) != 3 {
-		return
-	}
-
-	chatID, err := strconv.ParseUint(parts[2], 10, 32)
-	if err != nil {
-		return
-	}
+func (b *ChatBot) handleDetailedHistoryCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+	chatID := args.ID(0)
 
 	// Получаем все сообщения чата
-	messages, err := b.chatService.GetChatMessagesPaginated(uint(chatID), 1000, 0) // Получаем все сообщения
+	messages, err := b.chatService.GetChatMessagesPaginated(ctx, chatID, 1000, 0) // Получаем все сообщения
 	if err != nil {
-		b.answerCallbackQuery(query.ID, "Ошибка при получении истории.")
+		b.answerCallbackQuery(query.ID, b.t(ctx, "admin.history.failed"))
 		return
 	}
 
 	// Получаем информацию о чате
-	chat, err := b.chatService.GetChatByID(uint(chatID))
+	chat, err := b.chatService.GetChatWithUser(ctx, chatID)
 	if err != nil {
-		b.answerCallbackQuery(query.ID, "Чат не найден.")
+		b.answerCallbackQuery(query.ID, b.t(ctx, "admin.chat.not_found"))
 		return
 	}
 
 	// Отправляем заголовок
-	headerText := fmt.Sprintf("📋 Детальная история чата #%d\n👤 %s\n\n", chat.ID, b.formatUserName(&chat.User))
-	msg := tgbotapi.NewMessage(query.Message.Chat.ID, headerText)
-	b.api.Send(msg)
+	headerText := b.t(ctx, "admin.history.title", chat.ID, b.formatUserName(&chat.User))
+	msg := newMessage(query.Message.Chat.ID, headerText)
+	b.send(ctx, msg, PriorityLow)
 
 	// Отправляем каждое сообщение отдельно с улучшенным форматированием
 	for _, message := range messages {
-		var senderIcon, senderName string
-		if message.IsFromUser {
-			senderIcon = "👤"
-			senderName = "КЛИЕНТ"
-		} else {
-			senderIcon = "👨‍💼"
-			senderName = "АДМИН"
+		senderName := b.t(ctx, "admin.history.client")
+		if !message.IsFromUser {
+			senderName = b.t(ctx, "admin.history.admin")
 		}
 
 		// Формируем информацию об отправителе
-		senderInfo := fmt.Sprintf("%s %s (%s)", senderIcon, senderName, b.formatUserName(&message.User))
+		senderInfo := fmt.Sprintf("%s (%s)", senderName, b.formatUserName(&message.User))
 
 		// Отправляем медиа файлы если есть
-		if len(message.Files) > 0 {
-			for _, file := range message.Files {
-				caption := fmt.Sprintf("%s\n📅 %s", senderInfo, message.CreatedAt.Format("02.01.2006 15:04:05"))
-
-				switch file.FileType {
-				case "photo":
-					photoMsg := tgbotapi.NewPhoto(query.Message.Chat.ID, tgbotapi.FileID(file.FileID))
-					photoMsg.Caption = caption
-					b.api.Send(photoMsg)
-				case "video":
-					videoMsg := tgbotapi.NewVideo(query.Message.Chat.ID, tgbotapi.FileID(file.FileID))
-					videoMsg.Caption = caption
-					b.api.Send(videoMsg)
-				case "document":
-					docMsg := tgbotapi.NewDocument(query.Message.Chat.ID, tgbotapi.FileID(file.FileID))
-					docMsg.Caption = caption
-					b.api.Send(docMsg)
-				case "voice":
-					voiceMsg := tgbotapi.NewVoice(query.Message.Chat.ID, tgbotapi.FileID(file.FileID))
-					voiceMsg.Caption = caption
-					b.api.Send(voiceMsg)
-				case "video_note":
-					videoNoteMsg := tgbotapi.NewVideoNote(query.Message.Chat.ID, 0, tgbotapi.FileID(file.FileID))
-					b.api.Send(videoNoteMsg)
-				}
+		for _, file := range message.Files {
+			media := mediaMessage(query.Message.Chat.ID, file)
+			if media == nil {
+				continue
+			}
+			caption := fmt.Sprintf("%s\n📅 %s", senderInfo, message.CreatedAt.Format(b.t(ctx, "common.datetime_format")))
+			if _, err := b.sendMedia(media, caption, PriorityLow); err != nil {
+				logging.FromContext(ctx).Error("Failed to send history media", "error", err)
 			}
 		}
 
 		// Отправляем текстовое сообщение если есть
 		if message.Content != "" {
-			messageText := fmt.Sprintf("%s:\n%s\n📅 %s", senderInfo, message.Content, message.CreatedAt.Format("02.01.2006 15:04:05"))
-			msg := tgbotapi.NewMessage(query.Message.Chat.ID, messageText)
-			b.api.Send(msg)
+			messageText := fmt.Sprintf("%s:\n%s\n📅 %s", senderInfo, format.Escape(message.Content), message.CreatedAt.Format(b.t(ctx, "common.datetime_format")))
+			msg := newMessage(query.Message.Chat.ID, messageText)
+			b.send(ctx, msg, PriorityLow)
 		} else if len(message.Files) == 0 {
 			// Только если нет ни текста, ни файлов
-			messageText := fmt.Sprintf("%s:\n[Сообщение без текста]\n📅 %s", senderInfo, message.CreatedAt.Format("02.01.2006 15:04:05"))
-			msg := tgbotapi.NewMessage(query.Message.Chat.ID, messageText)
-			b.api.Send(msg)
+			messageText := fmt.Sprintf("%s:\n%s\n📅 %s", senderInfo, b.t(ctx, "admin.history.empty_message"), message.CreatedAt.Format(b.t(ctx, "common.datetime_format")))
+			msg := newMessage(query.Message.Chat.ID, messageText)
+			b.send(ctx, msg, PriorityLow)
 		}
 	}
 
 	// Отправляем кнопку возврата
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🔙 Назад к чату", fmt.Sprintf("view_chat_%d", chatID)),
+			tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "admin.history.back_to_chat"), chatData(chatID, 0)),
 		),
 	)
 
-	backMsg := tgbotapi.NewMessage(query.Message.Chat.ID, "📋 История чата отправлена выше.")
+	backMsg := newMessage(query.Message.Chat.ID, b.t(ctx, "admin.history.sent_above"))
 	backMsg.ReplyMarkup = keyboard
-	b.api.Send(backMsg)
+	b.send(ctx, backMsg, PriorityLow)
 
-	b.answerCallbackQuery(query.ID, "История отправлена.")
+	b.answerCallbackQuery(query.ID, b.t(ctx, "admin.history.sent"))
 }
 
 func (b *ChatBot) isUserAdmin(telegramID int64) bool {
-	for _, adminID := range b.config.AdminIDs {
+	for _, adminID := range b.config.Get().AdminIDs {
 		if adminID == telegramID {
 			return true
 		}
@@ -979,174 +606,70 @@ func (b *ChatBot) isUserAdmin(telegramID int64) bool {
 	return false
 }
 
-func (b *ChatBot) sendMessage(chatID int64, text string) {
+// newMessage - сообщение в разметке HTML; все, что пришло от пользователей, в text должно быть экранировано
+func newMessage(chatID int64, text string) tgbotapi.MessageConfig {
 	msg := tgbotapi.NewMessage(chatID, text)
-	b.api.Send(msg)
+	msg.ParseMode = format.ParseMode
+	return msg
 }
 
-// formatUserName форматирует имя пользователя с username
+func (b *ChatBot) sendMessage(ctx context.Context, chatID int64, text string) {
+	b.send(ctx, newMessage(chatID, text), PriorityNormal)
+}
+
+// formatUserName форматирует имя пользователя с username, уже экранированное для разметки
 func (b *ChatBot) formatUserName(user *models.User) string {
 	name := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
 	if user.Username != "" {
 		name += fmt.Sprintf(" %s", user.Username)
 	}
-	return name
+	return format.Escape(name)
 }
 
 func (b *ChatBot) answerCallbackQuery(queryID, text string) {
 	callback := tgbotapi.NewCallback(queryID, text)
-	b.api.Request(callback)
+	b.sender.Request(callback, PriorityNormal)
 }
 
 func (b *ChatBot) GetBotUsername() string {
 	return b.api.Self.UserName
 }
 
-// sendResponseToClient отправляет ответ админа клиенту
-func (b *ChatBot) sendResponseToClient(chatID uint, messageText string, originalMessage *tgbotapi.Message) error {
+// sendResponseToClient отправляет ответ админа клиенту и возвращает статус доставки.
+// Если передано цитируемое сообщение, ответ приходит клиенту как ответ на него.
+func (b *ChatBot) sendResponseToClient(ctx context.Context, adminMessage *models.ChatMessage, originalMessage *tgbotapi.Message, quoted *models.ChatMessage) (models.DeliveryStatus, error) {
 	// Получаем чат
-	chat, err := b.chatService.GetChatByID(chatID)
+	chat, err := b.chatService.GetChatWithUser(ctx, adminMessage.ChatID)
 	if err != nil {
-		return err
+		return models.DeliveryStatusFailed, err
 	}
 
-	// Отправляем сообщение клиенту
-	clientMsg := tgbotapi.NewMessage(int64(chat.User.TelegramID), "👨‍💼 Ответ от поддержки:\n\n"+messageText)
-
-	// Если есть файлы в оригинальном сообщении, отправляем их тоже
-	if originalMessage.Photo != nil && len(originalMessage.Photo) > 0 {
-		photo := originalMessage.Photo[len(originalMessage.Photo)-1]
-		photoMsg := tgbotapi.NewPhoto(int64(chat.User.TelegramID), tgbotapi.FileID(photo.FileID))
-		photoMsg.Caption = "👨‍💼 Ответ от поддержки:\n\n" + messageText
-		_, err = b.api.Send(photoMsg)
-	} else if originalMessage.Video != nil {
-		videoMsg := tgbotapi.NewVideo(int64(chat.User.TelegramID), tgbotapi.FileID(originalMessage.Video.FileID))
-		videoMsg.Caption = "👨‍💼 Ответ от поддержки:\n\n" + messageText
-		_, err = b.api.Send(videoMsg)
-	} else if originalMessage.Document != nil {
-		docMsg := tgbotapi.NewDocument(int64(chat.User.TelegramID), tgbotapi.FileID(originalMessage.Document.FileID))
-		docMsg.Caption = "👨‍💼 Ответ от поддержки:\n\n" + messageText
-		_, err = b.api.Send(docMsg)
-	} else {
-		_, err = b.api.Send(clientMsg)
+	replyTo := b.quotedMessageID(ctx, quoted, chat.User.TelegramID)
+	// Клиент получает ответ с тем же оформлением, с которым админ написал его в Telegram
+	job := b.newClientReplyJob(ctx, adminMessage, chat, messageMarkup(originalMessage), replyTo)
+	attachMessageFile(job, originalMessage)
+	job.NotifyChatID = originalMessage.Chat.ID
+	if b.isSupportGroup(originalMessage.Chat.ID) {
+		job.NotifyTopicID = chat.TopicID
 	}
 
-	return err
+	return b.deliverJob(ctx, job)
 }
 
-// showFinishConversationButton показывает кнопку "Закончить разговор"
-func (b *ChatBot) showFinishConversationButton(adminChatID int64, chatID uint) {
-	text := "✅ Ответ отправлен клиенту!\n\nВыберите действие:"
+// showFinishConversationButton показывает результат доставки и кнопку "Закончить разговор"
+func (b *ChatBot) showFinishConversationButton(ctx context.Context, adminChatID int64, chatID uint, status models.DeliveryStatus) {
+	text := deliveryStatusText(b.localizer(ctx), status) + "\n\n" + b.t(ctx, "admin.reply.choose_action")
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✅ Закончить разговор", fmt.Sprintf("finish_conversation_%d", chatID)),
+			tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "admin.reply.finish_button"), chatFinishData(chatID)),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💬 Продолжить общение", "continue_chat"),
+			tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "admin.reply.continue_button"), chatContinueData()),
 		),
 	)
 
-	msg := tgbotapi.NewMessage(adminChatID, text)
+	msg := newMessage(adminChatID, text)
 	msg.ReplyMarkup = keyboard
-	b.api.Send(msg)
-}
-
-// notifyAdminsAboutNewMessage отправляет уведомления админам о новом сообщении
-func (b *ChatBot) notifyAdminsAboutNewMessage(chatID uint, user *models.User, messageText string) {
-	// Получаем количество непрочитанных чатов
-	unreadCount, _ := b.chatService.GetUnreadChatsCount()
-
-	// Формируем текст уведомления
-	notificationText := fmt.Sprintf("🔔 Новое сообщение!\n\n")
-	notificationText += fmt.Sprintf("👤 От: %s\n", b.formatUserName(user))
-	notificationText += fmt.Sprintf("💬 Чат #%d\n", chatID)
-	notificationText += fmt.Sprintf("📝 Сообщение: %s\n\n", messageText)
-	notificationText += fmt.Sprintf("📊 Непрочитанных чатов: %d", unreadCount)
-
-	// Отправляем уведомление всем админам
-	for _, adminID := range b.config.AdminIDs {
-		msg := tgbotapi.NewMessage(adminID, notificationText)
-
-		// Добавляем кнопку для быстрого перехода к чату
-		keyboard := tgbotapi.NewInlineKeyboardMarkup(
-			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData("💬 Открыть чат", fmt.Sprintf("view_chat_%d", chatID)),
-			),
-		)
-		msg.ReplyMarkup = keyboard
-
-		b.api.Send(msg)
-	}
-}
-
-// sendMediaToAdmins отправляет медиа файлы от клиента всем админам
-func (b *ChatBot) sendMediaToAdmins(chatID uint, user *models.User, message *tgbotapi.Message) error {
-	// Отправляем медиа всем админам
-	for _, adminID := range b.config.AdminIDs {
-		// Определяем тип медиа и создаем соответствующее сообщение
-		if message.Photo != nil && len(message.Photo) > 0 {
-			photo := message.Photo[len(message.Photo)-1]
-			photoMsg := tgbotapi.NewPhoto(adminID, tgbotapi.FileID(photo.FileID))
-
-			// Формируем подпись с username
-			caption := fmt.Sprintf("📷 Фото от %s", b.formatUserName(user))
-			caption += fmt.Sprintf(" (Чат #%d)", chatID)
-
-			// Добавляем подпись к фото если есть
-			if message.Caption != "" {
-				caption += fmt.Sprintf("\n\n%s", message.Caption)
-			}
-
-			photoMsg.Caption = caption
-			b.api.Send(photoMsg)
-		} else if message.Video != nil {
-			videoMsg := tgbotapi.NewVideo(adminID, tgbotapi.FileID(message.Video.FileID))
-
-			// Формируем подпись с username
-			caption := fmt.Sprintf("🎥 Видео от %s", b.formatUserName(user))
-			caption += fmt.Sprintf(" (Чат #%d)", chatID)
-
-			// Добавляем подпись к видео если есть
-			if message.Caption != "" {
-				caption += fmt.Sprintf("\n\n%s", message.Caption)
-			}
-
-			videoMsg.Caption = caption
-			b.api.Send(videoMsg)
-		} else if message.Document != nil {
-			docMsg := tgbotapi.NewDocument(adminID, tgbotapi.FileID(message.Document.FileID))
-
-			// Формируем подпись с username
-			caption := fmt.Sprintf("📄 Документ от %s", b.formatUserName(user))
-			caption += fmt.Sprintf(" (Чат #%d)", chatID)
-
-			// Добавляем подпись к документу если есть
-			if message.Caption != "" {
-				caption += fmt.Sprintf("\n\n%s", message.Caption)
-			}
-
-			docMsg.Caption = caption
-			b.api.Send(docMsg)
-		} else if message.Voice != nil {
-			voiceMsg := tgbotapi.NewVoice(adminID, tgbotapi.FileID(message.Voice.FileID))
-
-			// Формируем подпись с username
-			caption := fmt.Sprintf("🎤 Голосовое от %s", b.formatUserName(user))
-			caption += fmt.Sprintf(" (Чат #%d)", chatID)
-
-			// Добавляем подпись к голосовому если есть
-			if message.Caption != "" {
-				caption += fmt.Sprintf("\n\n%s", message.Caption)
-			}
-
-			voiceMsg.Caption = caption
-			b.api.Send(voiceMsg)
-		} else if message.VideoNote != nil {
-			videoNoteMsg := tgbotapi.NewVideoNote(adminID, 0, tgbotapi.FileID(message.VideoNote.FileID))
-			b.api.Send(videoNoteMsg)
-		}
-	}
-
-	return nil
+	b.send(ctx, msg, PriorityNormal)
 }
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/service"
+	"context"
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleHistoryCommand показывает клиенту его переписку с поддержкой, начиная с последних сообщений
+func (b *ChatBot) handleHistoryCommand(ctx context.Context, message *tgbotapi.Message, user *models.User) {
+	s, err := b.clientHistoryScreen(ctx, user, 0)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to render client history", "error", err)
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "common.error"))
+		return
+	}
+	b.sendScreen(ctx, message.Chat.ID, s)
+}
+
+// handleClientHistoryCallback листает переписку клиента. Чат берется по автору нажатия,
+// поэтому чужую переписку по кнопке не открыть.
+func (b *ChatBot) handleClientHistoryCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+	user, err := b.userService.GetUserByTelegramID(ctx, query.From.ID)
+	if err != nil {
+		b.answerScreenError(ctx, query, err)
+		return
+	}
+
+	s, err := b.clientHistoryScreen(ctx, user, args.Page(0))
+	if err != nil {
+		b.answerScreenError(ctx, query, err)
+		return
+	}
+
+	b.showScreen(ctx, query, s)
+	b.answerCallbackQuery(query.ID, "")
+}
+
+// clientHistoryScreen - страница переписки последнего обращения клиента.
+// Страницы считаются с конца: страница 0 - самые новые сообщения, клиенту прежде всего нужны они.
+func (b *ChatBot) clientHistoryScreen(ctx context.Context, user *models.User, page int) (screen, error) {
+	chat, err := b.chatService.GetClientChat(ctx, user.ID)
+	if err != nil {
+		return screen{}, err
+	}
+	if chat == nil {
+		return newScreen(b.t(ctx, "client.history.empty")), nil
+	}
+
+	total, err := b.chatService.GetChatMessagesCount(ctx, chat.ID)
+	if err != nil {
+		return screen{}, err
+	}
+
+	perPage := b.config.Get().MessagesPerPage
+	pages := int((total + int64(perPage) - 1) / int64(perPage))
+	if pages == 0 {
+		return newScreen(b.t(ctx, "client.history.empty")), nil
+	}
+	if page >= pages {
+		return screen{}, &screenError{key: "common.button_expired", err: fmt.Errorf("history page %d of %d", page, pages)}
+	}
+
+	offset := int(total) - (page+1)*perPage
+	limit := perPage
+	if offset < 0 {
+		limit += offset
+		offset = 0
+	}
+
+	messages, err := b.chatService.GetChatMessagesPaginated(ctx, chat.ID, limit, offset)
+	if err != nil {
+		return screen{}, err
+	}
+
+	text := b.t(ctx, "client.history.title", chat.ID, page+1, pages) + "\n\n"
+	for _, message := range messages {
+		sender := b.t(ctx, "client.history.you")
+		if !message.IsFromUser {
+			sender = b.t(ctx, "client.history.support")
+		}
+
+		// Целиком сообщения остаются выше в этом же чате с ботом
+		content, _ := format.Truncate(format.Escape(message.Content), chatPreviewLimit)
+		if len(message.Files) > 0 {
+			content += " 📎"
+		}
+
+		text += fmt.Sprintf("<b>%s</b> · %s\n%s\n\n", sender, message.CreatedAt.Format(b.t(ctx, "common.date_format")), content)
+	}
+
+	var navButtons []tgbotapi.InlineKeyboardButton
+	if page+1 < pages {
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "client.history.older"), clientHistoryData(page+1)))
+	}
+	if page > 0 {
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "client.history.newer"), clientHistoryData(page-1)))
+	}
+	if len(navButtons) == 0 {
+		return newScreen(text), nil
+	}
+	return newScreen(text, navButtons), nil
+}
+
+// handleStatusCommand рассказывает клиенту, что с его обращением: ждет, прочитано или уже есть ответ
+func (b *ChatBot) handleStatusCommand(ctx context.Context, message *tgbotapi.Message, user *models.User) {
+	status, err := b.chatService.GetClientStatus(ctx, user.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get client chat status", "error", err)
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "common.error"))
+		return
+	}
+
+	if status == nil {
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "client.status.none"))
+		return
+	}
+
+	b.sendScreen(ctx, message.Chat.ID, newScreen(b.clientStatusText(ctx, status),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "client.status.history_button"), clientHistoryData(0))),
+	))
+}
+
+func (b *ChatBot) clientStatusText(ctx context.Context, status *service.ClientChatStatus) string {
+	dateFormat := b.t(ctx, "common.date_format")
+	text := b.t(ctx, "client.status.title", status.Chat.ID) + "\n\n"
+
+	switch status.State {
+	case service.ClientChatWaiting:
+		text += b.t(ctx, "client.status.waiting")
+	case service.ClientChatRead:
+		text += b.t(ctx, "client.status.read")
+	case service.ClientChatAnswered:
+		text += b.t(ctx, "client.status.answered", status.LastMessage.CreatedAt.Format(dateFormat))
+	case service.ClientChatClosed:
+		text += b.t(ctx, "client.status.closed")
+	}
+
+	if status.QueuePosition > 0 {
+		text += "\n" + b.t(ctx, "client.status.queue", status.QueuePosition)
+		if !b.config.Get().BusinessHours.IsOpen(time.Now()) {
+			text += "\n" + b.t(ctx, "client.status.outside_business_hours")
+		}
+	}
+	if status.LastMessage != nil && status.State != service.ClientChatAnswered {
+		text += "\n" + b.t(ctx, "client.status.last_message", status.LastMessage.CreatedAt.Format(dateFormat))
+	}
+
+	return text
+}
@@ -0,0 +1,205 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/config"
+	"ai_support_tg_writer_bot/internal/i18n"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// role - кто может вызвать команду
+type role int
+
+const (
+	roleAnyone role = iota // клиенты и админы
+	roleAdmin
+)
+
+type commandHandler func(ctx context.Context, message *tgbotapi.Message, user *models.User)
+
+// command - команда бота. Описание берется из каталога по ключу "commands.<name>"
+// и попадает и в /help, и в меню команд Telegram.
+type command struct {
+	name    string
+	role    role
+	enabled func(cfg *config.Config) bool // nil - команда есть всегда, иначе зависит от настроек
+	handler commandHandler
+}
+
+// available - команда включена в текущей конфигурации
+func (c command) available(cfg *config.Config) bool {
+	return c.enabled == nil || c.enabled(cfg)
+}
+
+// Так Telegram требует называть команды в setMyCommands
+var commandNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// registerCommands описывает все команды бота в том порядке, в котором они показываются в /help и меню
+func (b *ChatBot) registerCommands() error {
+	webAdmin := func(cfg *config.Config) bool { return cfg.EnableWebAdmin }
+
+	commands := []command{
+		{name: "start", role: roleAnyone, handler: b.handleStartCommand},
+		{name: "help", role: roleAnyone, handler: b.handleHelpCommand},
+		{name: "history", role: roleAnyone, handler: b.handleHistoryCommand},
+		{name: "status", role: roleAnyone, handler: b.handleStatusCommand},
+		{name: "language", role: roleAnyone, handler: b.handleLanguageCommand},
+		{name: "admin", role: roleAdmin, handler: b.handleAdminCommand},
+		{name: "cancel", role: roleAdmin, handler: b.handleCancelCommand},
+		{name: "template", role: roleAdmin, handler: b.handleTemplateCommand},
+		{name: "weblogin", role: roleAdmin, enabled: webAdmin, handler: b.handleWebLoginCommand},
+		{name: "weblogout", role: roleAdmin, enabled: webAdmin, handler: b.handleWebLogoutCommand},
+	}
+
+	seen := make(map[string]bool, len(commands))
+	for _, c := range commands {
+		if !commandNamePattern.MatchString(c.name) {
+			return fmt.Errorf("invalid command name %q", c.name)
+		}
+		if seen[c.name] {
+			return fmt.Errorf("command %q is already registered", c.name)
+		}
+		seen[c.name] = true
+	}
+
+	b.commands = commands
+	return nil
+}
+
+// findCommand ищет включенную команду по имени
+func (b *ChatBot) findCommand(name string) (command, bool) {
+	cfg := b.config.Get()
+	for _, c := range b.commands {
+		if c.name == name && c.available(cfg) {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// commandsFor - включенные команды роли r в порядке регистрации
+func (b *ChatBot) commandsFor(r role) []command {
+	cfg := b.config.Get()
+	var commands []command
+	for _, c := range b.commands {
+		if c.role == r && c.available(cfg) {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+func (b *ChatBot) handleCommand(ctx context.Context, message *tgbotapi.Message, user *models.User, isAdmin bool) {
+	c, ok := b.findCommand(message.Command())
+	if !ok {
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "common.unknown_command"))
+		return
+	}
+	if c.role == roleAdmin && !isAdmin {
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "common.no_permission"))
+		return
+	}
+
+	c.handler(ctx, message, user)
+}
+
+// helpText - справка по командам, доступным пользователю
+func (b *ChatBot) helpText(l *i18n.Localizer, isAdmin bool) string {
+	text := l.T("help.title") + "\n\n" + commandList(l, b.commandsFor(roleAnyone))
+	if isAdmin {
+		text += "\n\n" + l.T("help.admin_title") + "\n" + commandList(l, b.commandsFor(roleAdmin))
+	}
+	return text + "\n\n" + l.T("help.footer")
+}
+
+func commandList(l *i18n.Localizer, commands []command) string {
+	lines := make([]string, 0, len(commands))
+	for _, c := range commands {
+		lines = append(lines, "/"+c.name+" - "+l.T("commands."+c.name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// botCommands - меню команд Telegram для роли: админам видны и команды клиентов
+func (b *ChatBot) botCommands(l *i18n.Localizer, isAdmin bool) []tgbotapi.BotCommand {
+	commands := b.commandsFor(roleAnyone)
+	if isAdmin {
+		commands = append(commands, b.commandsFor(roleAdmin)...)
+	}
+
+	botCommands := make([]tgbotapi.BotCommand, 0, len(commands))
+	for _, c := range commands {
+		botCommands = append(botCommands, tgbotapi.BotCommand{Command: c.name, Description: l.T("commands." + c.name)})
+	}
+	return botCommands
+}
+
+// SyncCommands выставляет меню команд Telegram: клиентам - во всех личных чатах, админам - в их чатах
+// с ботом, на каждом языке каталога и на языке по умолчанию для остальных. Вызывается при запуске
+// и после перезагрузки конфигурации, в которой мог измениться список админов.
+func (b *ChatBot) SyncCommands(ctx context.Context) error {
+	b.commandsMu.Lock()
+	defer b.commandsMu.Unlock()
+
+	// Пустой код - меню для языков, которых нет в каталоге
+	languages := append([]string{""}, b.i18n.Languages()...)
+	localizer := func(lang string) *i18n.Localizer {
+		if lang == "" {
+			return b.defaultLocalizer()
+		}
+		return b.i18n.Localizer(lang)
+	}
+
+	for _, lang := range languages {
+		scope := tgbotapi.NewBotCommandScopeAllPrivateChats()
+		if err := b.setCommands(scope, lang, b.botCommands(localizer(lang), false)); err != nil {
+			return err
+		}
+	}
+
+	admins := make(map[int64]bool)
+	for _, adminID := range b.config.Get().AdminIDs {
+		admins[adminID] = true
+		for _, lang := range languages {
+			scope := tgbotapi.NewBotCommandScopeChat(adminID)
+			if err := b.setCommands(scope, lang, b.botCommands(localizer(lang), true)); err != nil {
+				// Админ, который еще не писал боту, для Telegram не существует - меню появится при следующей синхронизации
+				logging.FromContext(ctx).Warn("Failed to set admin commands", "admin_id", adminID, "error", err)
+				break
+			}
+		}
+	}
+
+	// Бывшим админам возвращаем меню клиента. Тех, кого убрали, пока бот был остановлен, здесь не знаем,
+	// но права все равно проверяются при вызове команды.
+	for adminID := range b.commandAdmins {
+		if admins[adminID] {
+			continue
+		}
+		for _, lang := range languages {
+			remove := tgbotapi.NewDeleteMyCommandsWithScopeAndLanguage(tgbotapi.NewBotCommandScopeChat(adminID), lang)
+			if _, err := b.sender.Request(remove, PriorityLow); err != nil {
+				logging.FromContext(ctx).Warn("Failed to delete commands of former admin", "admin_id", adminID, "error", err)
+				break
+			}
+		}
+	}
+	b.commandAdmins = admins
+
+	logging.FromContext(ctx).Info("Bot commands synced", "languages", len(languages)-1, "admins", len(admins))
+	return nil
+}
+
+func (b *ChatBot) setCommands(scope tgbotapi.BotCommandScope, lang string, commands []tgbotapi.BotCommand) error {
+	set := tgbotapi.NewSetMyCommandsWithScopeAndLanguage(scope, lang, commands...)
+	if _, err := b.sender.Request(set, PriorityLow); err != nil {
+		return fmt.Errorf("failed to set commands for scope %s (language %q): %w", scope.Type, lang, err)
+	}
+	return nil
+}
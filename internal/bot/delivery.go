@@ -0,0 +1,307 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"ai_support_tg_writer_bot/internal/i18n"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Как часто проверять очередь повторной отправки
+	deliveryPollInterval = 5 * time.Second
+	// Сколько задач обрабатывать за один проход
+	deliveryBatchSize = 20
+)
+
+// newClientReplyJob описывает отправку ответа админа клиенту так, чтобы ее можно было повторить.
+// body - текст ответа в разметке HTML. Заголовок ответа - на языке клиента, а не админа.
+func (b *ChatBot) newClientReplyJob(ctx context.Context, adminMessage *models.ChatMessage, chat *models.Chat, body string, replyTo int) *models.DeliveryJob {
+	return &models.DeliveryJob{
+		MessageID:        adminMessage.ID,
+		ChatID:           chat.ID,
+		TelegramChatID:   chat.User.TelegramID,
+		Text:             b.clientReplyText(ctx, chat, adminMessage.UserID, body),
+		ParseMode:        format.ParseMode,
+		ReplyToMessageID: replyTo,
+	}
+}
+
+// clientReplyText добавляет к ответу поддержки в разметке HTML заголовок из шаблона support_reply
+func (b *ChatBot) clientReplyText(ctx context.Context, chat *models.Chat, operatorID uint, body string) string {
+	// Без оператора (например, если его удалили) в заголовке просто не будет его имени
+	operator, _ := b.userService.GetUserByID(ctx, operatorID)
+
+	data := service.NewReplyTemplateData(&chat.User, chat.ID, operator)
+	header := b.clientText(ctx, models.TemplateSupportReply, b.i18n.Localizer(chat.User.Language), data)
+	return header + "\n\n" + body
+}
+
+// attachMessageFile прикладывает к задаче доставки медиа из сообщения админа
+func attachMessageFile(job *models.DeliveryJob, originalMessage *tgbotapi.Message) {
+	if originalMessage.Photo != nil && len(originalMessage.Photo) > 0 {
+		job.FileID = originalMessage.Photo[len(originalMessage.Photo)-1].FileID
+		job.FileType = "photo"
+	} else if originalMessage.Video != nil {
+		job.FileID = originalMessage.Video.FileID
+		job.FileType = "video"
+	} else if originalMessage.Document != nil {
+		job.FileID = originalMessage.Document.FileID
+		job.FileType = "document"
+	}
+}
+
+// jobParts делит текст задачи доставки по лимитам Telegram: первая часть - подпись к медиа или
+// текст сообщения, остальные уходят следом. Задачи без разметки отправляются как раньше, одним сообщением.
+func jobParts(job *models.DeliveryJob) []string {
+	if job.ParseMode != format.ParseMode {
+		return []string{job.Text}
+	}
+
+	switch job.FileType {
+	case "photo", "video", "document":
+		caption, rest := format.SplitCaption(job.Text)
+		return append([]string{caption}, rest...)
+	default:
+		return format.Split(job.Text, format.MessageLimit)
+	}
+}
+
+// sendJob отправляет еще не доставленные части ответа по задаче доставки. Доставленные части считает
+// job.SentParts: повтор задачи продолжает с первой недоставленной и не присылает клиенту уже полученное.
+func (b *ChatBot) sendJob(ctx context.Context, job *models.DeliveryJob) error {
+	parts := jobParts(job)
+
+	for job.SentParts < len(parts) {
+		if job.SentParts == 0 {
+			sent, err := b.sender.Send(jobChattable(job, parts[0]), PriorityHigh)
+			if err != nil {
+				return err
+			}
+			// Цитаты клиента привязываются к первой части - с подписью к медиа и ответом на сообщение
+			b.saveClientCopy(ctx, job, sent)
+		} else {
+			msg := newMessage(job.TelegramChatID, parts[job.SentParts])
+			if _, err := b.sender.Send(msg, PriorityHigh); err != nil {
+				return fmt.Errorf("failed to send reply part %d of %d: %w", job.SentParts+1, len(parts), err)
+			}
+		}
+		job.SentParts++
+	}
+
+	return nil
+}
+
+// jobChattable собирает запрос к Telegram по описанию задачи доставки с текстом text
+func jobChattable(job *models.DeliveryJob, text string) tgbotapi.Chattable {
+	switch job.FileType {
+	case "photo":
+		msg := tgbotapi.NewPhoto(job.TelegramChatID, tgbotapi.FileID(job.FileID))
+		msg.Caption = text
+		msg.ParseMode = job.ParseMode
+		msg.ReplyToMessageID = job.ReplyToMessageID
+		msg.AllowSendingWithoutReply = true
+		return msg
+	case "video":
+		msg := tgbotapi.NewVideo(job.TelegramChatID, tgbotapi.FileID(job.FileID))
+		msg.Caption = text
+		msg.ParseMode = job.ParseMode
+		msg.ReplyToMessageID = job.ReplyToMessageID
+		msg.AllowSendingWithoutReply = true
+		return msg
+	case "document":
+		msg := tgbotapi.NewDocument(job.TelegramChatID, tgbotapi.FileID(job.FileID))
+		msg.Caption = text
+		msg.ParseMode = job.ParseMode
+		msg.ReplyToMessageID = job.ReplyToMessageID
+		msg.AllowSendingWithoutReply = true
+		return msg
+	default:
+		msg := tgbotapi.NewMessage(job.TelegramChatID, text)
+		msg.ParseMode = job.ParseMode
+		msg.ReplyToMessageID = job.ReplyToMessageID
+		msg.AllowSendingWithoutReply = true
+		return msg
+	}
+}
+
+// deliverJob делает первую попытку доставки. Неудачные временные отправки уходят в очередь повторов.
+func (b *ChatBot) deliverJob(ctx context.Context, job *models.DeliveryJob) (models.DeliveryStatus, error) {
+	err := b.sendJob(ctx, job)
+	if err == nil {
+		if err := b.deliveryService.MarkSent(ctx, job.MessageID); err != nil {
+			logging.FromContext(ctx).Error("Failed to mark message as sent", "message_id", job.MessageID, "error", err)
+		}
+		return models.DeliveryStatusSent, nil
+	}
+
+	// Недоставленные части длинного ответа повторяются через очередь так же, как ответ целиком
+	retryable, status, retryAfter := classifySendError(err)
+	if retryable {
+		if qerr := b.deliveryService.EnqueueRetry(ctx, job, err.Error(), retryAfter); qerr != nil {
+			logging.FromContext(ctx).Error("Failed to enqueue delivery retry", "error", qerr)
+			status = job.UndeliveredStatus(models.DeliveryStatusFailed)
+		} else {
+			status = models.DeliveryStatusPending
+		}
+		return status, err
+	}
+
+	status = job.UndeliveredStatus(status)
+	if merr := b.deliveryService.MarkUndelivered(ctx, job.MessageID, status, err.Error()); merr != nil {
+		logging.FromContext(ctx).Error("Failed to mark message as undelivered", "message_id", job.MessageID, "error", merr)
+	}
+	return status, err
+}
+
+// DeliverReply доставляет клиенту ответ, сохраненный вне Telegram (из веб-панели или через API).
+// Из файлов сообщения отправляется первый: file_id Telegram или HTTP-ссылка.
+func (b *ChatBot) DeliverReply(ctx context.Context, message *models.ChatMessage) (models.DeliveryStatus, error) {
+	chat, err := b.chatService.GetChatWithUser(ctx, message.ChatID)
+	if err != nil {
+		return models.DeliveryStatusFailed, err
+	}
+
+	// Ответы из веб-панели и API - обычный текст
+	job := b.newClientReplyJob(ctx, message, chat, format.Escape(message.Content), 0)
+	if len(message.Files) > 0 {
+		job.FileID = message.Files[0].FileID
+		job.FileType = message.Files[0].FileType
+	}
+
+	status, err := b.deliverJob(ctx, job)
+
+	// В режиме тем показываем ответ в теме чата
+	if b.forumEnabled() && chat.TopicID != 0 {
+		source := b.defaultLocalizer().T("topic.web_reply")
+		if sender, serr := b.userService.GetUserByID(ctx, message.UserID); serr == nil && sender.IsSystem() {
+			source = b.defaultLocalizer().T("topic.api_message")
+		}
+		b.sendToTopic(ctx, chat.TopicID, source+"\n\n"+format.Escape(message.Content))
+	}
+
+	return status, err
+}
+
+// runDeliveryWorker периодически повторяет отправку сообщений из очереди
+func (b *ChatBot) runDeliveryWorker() {
+	ctx := logging.WithLogger(context.Background(), b.logger.With("component", "delivery"))
+	ticker := time.NewTicker(deliveryPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		b.processDeliveryJobs(ctx)
+	}
+}
+
+func (b *ChatBot) processDeliveryJobs(ctx context.Context) {
+	jobs, err := b.deliveryService.GetDueJobs(ctx, deliveryBatchSize)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get delivery jobs", "error", err)
+		return
+	}
+
+	for i := range jobs {
+		job := &jobs[i]
+
+		err := b.sendJob(ctx, job)
+		if err == nil {
+			if err := b.deliveryService.CompleteJob(ctx, job); err != nil {
+				logging.FromContext(ctx).Error("Failed to complete delivery job", "job_id", job.ID, "error", err)
+			}
+			b.notifyDeliveryResult(ctx, job, func(l *i18n.Localizer) string {
+				return l.T("delivery.retried", job.ChatID)
+			})
+			continue
+		}
+
+		retryable, status, retryAfter := classifySendError(err)
+		if retryable {
+			scheduled, rerr := b.deliveryService.RescheduleJob(ctx, job, err.Error(), retryAfter)
+			if rerr != nil {
+				logging.FromContext(ctx).Error("Failed to reschedule delivery job", "job_id", job.ID, "error", rerr)
+				continue
+			}
+			if !scheduled {
+				b.notifyDeliveryResult(ctx, job, deliveryResultText(job, job.UndeliveredStatus(models.DeliveryStatusFailed)))
+			}
+			continue
+		}
+
+		if ferr := b.deliveryService.FailJob(ctx, job, status, err.Error()); ferr != nil {
+			logging.FromContext(ctx).Error("Failed to fail delivery job", "job_id", job.ID, "error", ferr)
+		}
+		b.notifyDeliveryResult(ctx, job, deliveryResultText(job, job.UndeliveredStatus(status)))
+	}
+}
+
+// saveClientCopy запоминает доставленную клиенту копию ответа
+func (b *ChatBot) saveClientCopy(ctx context.Context, job *models.DeliveryJob, sent tgbotapi.Message) {
+	if err := b.chatService.AddMessageCopy(ctx, job.MessageID, job.TelegramChatID, sent.MessageID, models.MessageCopyClient); err != nil {
+		logging.FromContext(ctx).Error("Failed to save delivered message mapping", "error", err)
+	}
+}
+
+// notifyDeliveryResult сообщает админу, отправившему ответ, чем закончилась доставка.
+// text переводит сообщение на язык получателя: админа или группы поддержки.
+func (b *ChatBot) notifyDeliveryResult(ctx context.Context, job *models.DeliveryJob, text func(l *i18n.Localizer) string) {
+	if job.NotifyTopicID != 0 {
+		b.sendToTopic(ctx, job.NotifyTopicID, text(b.defaultLocalizer()))
+		return
+	}
+	if job.NotifyChatID != 0 {
+		b.sendMessage(ctx, job.NotifyChatID, text(b.localizerFor(ctx, job.NotifyChatID)))
+	}
+}
+
+// deliveryResultText описывает итог доставки ответа в чат
+func deliveryResultText(job *models.DeliveryJob, status models.DeliveryStatus) func(l *i18n.Localizer) string {
+	return func(l *i18n.Localizer) string {
+		return l.T("delivery.in_chat", deliveryStatusText(l, status), job.ChatID)
+	}
+}
+
+// classifySendError определяет, стоит ли повторять отправку, и с каким статусом ее завершить
+func classifySendError(err error) (retryable bool, status models.DeliveryStatus, retryAfter time.Duration) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		// Сетевые ошибки и таймауты - временные
+		return true, models.DeliveryStatusPending, 0
+	}
+
+	switch {
+	case apiErr.Code == http.StatusTooManyRequests:
+		return true, models.DeliveryStatusPending, time.Duration(apiErr.RetryAfter) * time.Second
+	case apiErr.Code == http.StatusForbidden:
+		// Бот заблокирован или аккаунт клиента удален - повторять бессмысленно
+		return false, models.DeliveryStatusBlocked, 0
+	case apiErr.Code >= http.StatusInternalServerError:
+		return true, models.DeliveryStatusPending, 0
+	default:
+		return false, models.DeliveryStatusFailed, 0
+	}
+}
+
+// deliveryStatusText описывает статус доставки ответа для админа
+func deliveryStatusText(l *i18n.Localizer, status models.DeliveryStatus) string {
+	switch status {
+	case models.DeliveryStatusSent:
+		return l.T("delivery.sent")
+	case models.DeliveryStatusPending:
+		return l.T("delivery.pending")
+	case models.DeliveryStatusBlocked:
+		return l.T("delivery.blocked")
+	case models.DeliveryStatusPartial:
+		return l.T("delivery.partial")
+	default:
+		return l.T("delivery.failed")
+	}
+}
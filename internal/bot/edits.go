@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"ai_support_tg_writer_bot/internal/i18n"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleEditedMessage синхронизирует отредактированное в Telegram сообщение с сохраненным в чате
+func (b *ChatBot) handleEditedMessage(ctx context.Context, message *tgbotapi.Message) {
+	if message.From == nil {
+		return
+	}
+
+	chatMessage, err := b.chatService.GetMessageByTelegramID(ctx, message.Chat.ID, message.MessageID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get edited message", "error", err)
+		return
+	}
+	if chatMessage == nil {
+		// Сообщение не относится ни к одному чату поддержки
+		return
+	}
+
+	// Определяем содержимое сообщения (текст или подпись к медиа)
+	content := message.Text
+	if content == "" && message.Caption != "" {
+		content = message.Caption
+	}
+
+	if content == chatMessage.Content {
+		return
+	}
+
+	updated, _, err := b.chatService.EditMessage(ctx, chatMessage.ID, content)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to save edited message", "error", err)
+		return
+	}
+
+	// Админам о правке клиента сообщают подписчики MessageEdited: в личные чаты или в тему чата
+	if updated.IsFromUser {
+		return
+	}
+
+	// Админ отредактировал свой ответ - обновляем копию у клиента
+	if err := b.propagateAdminEdit(ctx, updated, message); err != nil {
+		logging.FromContext(ctx).Error("Failed to propagate admin edit", "error", err)
+	}
+}
+
+// notifyAdminsAboutEditedMessage уведомляет админов об изменении сообщения клиента
+func (b *ChatBot) notifyAdminsAboutEditedMessage(ctx context.Context, message *models.ChatMessage, oldContent string) {
+	for _, adminID := range b.config.Get().AdminIDs {
+		// Каждому админу - на его языке
+		l := b.localizerFor(ctx, adminID)
+		notificationText, truncated := b.editedMessageText(l, message, oldContent)
+
+		buttons := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.T("common.open_chat"), openChatData(message.ChatID)),
+		)
+		if truncated {
+			buttons = append(buttons, showFullButton(l.T("common.show_full"), message.ID))
+		}
+
+		msg := newMessage(adminID, notificationText)
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons)
+		b.send(ctx, msg, PriorityLow)
+	}
+}
+
+// postEditToTopic сообщает в теме чата об изменении сообщения клиента
+func (b *ChatBot) postEditToTopic(ctx context.Context, message *models.ChatMessage, oldContent string) {
+	chat, err := b.chatService.GetChatWithUser(ctx, message.ChatID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get chat", "error", err)
+		return
+	}
+	if chat.TopicID == 0 {
+		return
+	}
+
+	text, _ := b.editedMessageText(b.defaultLocalizer(), message, oldContent)
+	b.sendToTopic(ctx, chat.TopicID, text)
+}
+
+// editedMessageText - текст уведомления о правке: было, стало и пословный diff.
+// Длинные тексты сокращаются; truncated - новый текст целиком покажет кнопка "Показать полностью".
+func (b *ChatBot) editedMessageText(l *i18n.Localizer, message *models.ChatMessage, oldContent string) (text string, truncated bool) {
+	before, beforeTruncated := format.Truncate(format.Escape(oldContent), notificationPreviewLimit)
+	after, afterTruncated := format.Truncate(format.Escape(message.Content), notificationPreviewLimit)
+	diff, diffTruncated := format.Truncate(wordDiff(oldContent, message.Content), notificationPreviewLimit)
+
+	text = l.T("notify.edited") + "\n\n"
+	text += l.T("notify.from", b.formatUserName(&message.User)) + "\n"
+	text += l.T("notify.chat", message.ChatID) + "\n\n"
+	text += l.T("notify.edited_before", before) + "\n"
+	text += l.T("notify.edited_after", after) + "\n\n"
+	text += l.T("notify.edited_diff", diff)
+
+	return text, beforeTruncated || afterTruncated || diffTruncated
+}
+
+// propagateAdminEdit обновляет ответ, который клиент уже получил от поддержки
+func (b *ChatBot) propagateAdminEdit(ctx context.Context, message *models.ChatMessage, edited *tgbotapi.Message) error {
+	chat, err := b.chatService.GetChatWithUser(ctx, message.ChatID)
+	if err != nil {
+		return err
+	}
+
+	clientID := chat.User.TelegramID
+	deliveredID, err := b.chatService.FindTelegramMessageID(ctx, message, clientID)
+	if err != nil {
+		return err
+	}
+	if deliveredID == 0 {
+		return fmt.Errorf("message %d has no delivered copy", message.ID)
+	}
+
+	text := b.clientReplyText(ctx, chat, message.UserID, messageMarkup(edited))
+
+	// Ответ с медиа доставлялся с подписью, текстовый - обычным сообщением.
+	// Длинный ответ клиент получил частями, правка обновляет первую из них.
+	if edited.Text == "" {
+		text, _ = format.SplitCaption(text)
+		edit := tgbotapi.NewEditMessageCaption(clientID, deliveredID, text)
+		edit.ParseMode = format.ParseMode
+		_, err = b.sender.Send(edit, PriorityHigh)
+	} else {
+		text = format.Split(text, format.MessageLimit)[0]
+		edit := tgbotapi.NewEditMessageText(clientID, deliveredID, text)
+		edit.ParseMode = format.ParseMode
+		_, err = b.sender.Send(edit, PriorityHigh)
+	}
+
+	return err
+}
+
+// wordDiff строит пословный diff двух текстов в разметке HTML: удаленные слова зачеркнуты, добавленные подчеркнуты
+func wordDiff(oldText, newText string) string {
+	oldWords := strings.Fields(oldText)
+	newWords := strings.Fields(newText)
+
+	// Таблица длин наибольшей общей подпоследовательности
+	lcs := make([][]int, len(oldWords)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newWords)+1)
+	}
+	for i := len(oldWords) - 1; i >= 0; i-- {
+		for j := len(newWords) - 1; j >= 0; j-- {
+			if oldWords[i] == newWords[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var parts []string
+	i, j := 0, 0
+	for i < len(oldWords) && j < len(newWords) {
+		switch {
+		case oldWords[i] == newWords[j]:
+			parts = append(parts, format.Escape(oldWords[i]))
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			parts = append(parts, "<s>"+format.Escape(oldWords[i])+"</s>")
+			i++
+		default:
+			parts = append(parts, "<u>"+format.Escape(newWords[j])+"</u>")
+			j++
+		}
+	}
+	for ; i < len(oldWords); i++ {
+		parts = append(parts, "<s>"+format.Escape(oldWords[i])+"</s>")
+	}
+	for ; j < len(newWords); j++ {
+		parts = append(parts, "<u>"+format.Escape(newWords[j])+"</u>")
+	}
+
+	return strings.Join(parts, " ")
+}
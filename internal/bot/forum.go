@@ -0,0 +1,240 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"ai_support_tg_writer_bot/internal/i18n"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Максимальная длина названия темы в Telegram
+const maxTopicNameLength = 128
+
+// forumTopic - ответ createForumTopic (в tgbotapi v5.5.1 методов для тем нет)
+type forumTopic struct {
+	MessageThreadID int    `json:"message_thread_id"`
+	Name            string `json:"name"`
+}
+
+// forumEnabled сообщает, включен ли режим тем в группе поддержки
+func (b *ChatBot) forumEnabled() bool {
+	return b.config.Get().SupportGroupID != 0
+}
+
+// isSupportGroup проверяет, пришло ли сообщение из группы поддержки
+func (b *ChatBot) isSupportGroup(chatID int64) bool {
+	return b.forumEnabled() && chatID == b.config.Get().SupportGroupID
+}
+
+// ensureChatTopic создает тему для чата или переоткрывает тему предыдущего чата клиента
+func (b *ChatBot) ensureChatTopic(ctx context.Context, chat *models.Chat, user *models.User, isNewChat bool) error {
+	if chat.TopicID != 0 {
+		if isNewChat {
+			// Клиент вернулся после архивации - переоткрываем его тему
+			if err := b.reopenTopic(chat.TopicID); err != nil {
+				logging.FromContext(ctx).Error("Failed to reopen topic", "topic_id", chat.TopicID, "error", err)
+			}
+			b.sendToTopic(ctx, chat.TopicID, b.defaultLocalizer().T("topic.client_returned", chat.ID))
+		}
+		return nil
+	}
+
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", b.config.Get().SupportGroupID)
+	params.AddNonEmpty("name", topicName(b.defaultLocalizer(), chat, user))
+
+	resp, err := b.sender.MakeRequest(b.config.Get().SupportGroupID, "createForumTopic", params, PriorityNormal)
+	if err != nil {
+		return fmt.Errorf("failed to create topic: %w", err)
+	}
+
+	var topic forumTopic
+	if err := json.Unmarshal(resp.Result, &topic); err != nil {
+		return fmt.Errorf("failed to decode topic: %w", err)
+	}
+
+	if err := b.chatService.SetChatTopic(ctx, chat.ID, topic.MessageThreadID); err != nil {
+		return fmt.Errorf("failed to save topic: %w", err)
+	}
+	chat.TopicID = topic.MessageThreadID
+
+	return nil
+}
+
+// postClientMessageToTopic копирует сообщение клиента в тему его чата
+func (b *ChatBot) postClientMessageToTopic(ctx context.Context, chat *models.Chat, chatMessage *models.ChatMessage) error {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", b.config.Get().SupportGroupID)
+	params.AddNonZero("message_thread_id", chat.TopicID)
+	params.AddNonZero64("from_chat_id", chatMessage.TelegramChatID)
+	params.AddNonZero("message_id", chatMessage.TelegramMessageID)
+
+	resp, err := b.sender.MakeRequest(b.config.Get().SupportGroupID, "copyMessage", params, PriorityLow)
+	if err != nil {
+		return fmt.Errorf("failed to copy message to topic: %w", err)
+	}
+
+	var copied tgbotapi.MessageID
+	if err := json.Unmarshal(resp.Result, &copied); err != nil {
+		return fmt.Errorf("failed to decode copied message: %w", err)
+	}
+
+	// Запоминаем копию, чтобы ответ оператора на нее цитировал исходное сообщение клиента
+	return b.chatService.AddMessageCopy(ctx, chatMessage.ID, b.config.Get().SupportGroupID, copied.MessageID, models.MessageCopyAdmin)
+}
+
+// handleForumMessage пересылает клиенту все, что оператор написал в теме его чата
+func (b *ChatBot) handleForumMessage(ctx context.Context, message *tgbotapi.Message) {
+	if message.From == nil || message.From.IsBot || message.ReplyToMessage == nil {
+		// Сообщения вне тем (в "General") к клиентам не относятся
+		return
+	}
+
+	// Отвечать клиентам могут только админы из конфигурации, а команды в теме не должны уходить клиенту
+	if !b.isUserAdmin(message.From.ID) || message.IsCommand() {
+		logging.FromContext(ctx).Debug("Ignoring topic message", "user_id", message.From.ID, "command", message.Command())
+		return
+	}
+
+	chat, quoted := b.resolveTopicChat(ctx, message)
+	if chat == nil {
+		return
+	}
+
+	if chat.Status == models.ChatStatusArchived {
+		b.sendToTopic(ctx, chat.TopicID, b.defaultLocalizer().T("topic.chat_archived_not_sent"))
+		return
+	}
+
+	adminMessage, err := b.relayAdminReply(ctx, message, chat.ID, quoted)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to relay topic message", "error", err)
+		if adminMessage == nil {
+			b.sendToTopic(ctx, chat.TopicID, b.defaultLocalizer().T("topic.send_failed"))
+			return
+		}
+		b.sendToTopic(ctx, chat.TopicID, deliveryStatusText(b.defaultLocalizer(), adminMessage.DeliveryStatus))
+	}
+}
+
+// resolveTopicChat определяет чат по теме, в которой написано сообщение.
+// В tgbotapi v5.5.1 нет message_thread_id, поэтому тема определяется по reply_to_message:
+// для обычного сообщения в теме это служебное сообщение о создании темы, для ответа - цитируемое сообщение.
+func (b *ChatBot) resolveTopicChat(ctx context.Context, message *tgbotapi.Message) (*models.Chat, *models.ChatMessage) {
+	replyToID := message.ReplyToMessage.MessageID
+
+	chat, err := b.chatService.GetChatByTopicID(ctx, replyToID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get chat by topic", "error", err)
+		return nil, nil
+	}
+	if chat != nil {
+		return chat, nil
+	}
+
+	quoted, err := b.chatService.ResolveTelegramMessage(ctx, message.Chat.ID, replyToID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to resolve topic message", "error", err)
+		return nil, nil
+	}
+	if quoted == nil {
+		return nil, nil
+	}
+
+	chat, err = b.chatService.GetChatWithUser(ctx, quoted.ChatID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get chat", "error", err)
+		return nil, nil
+	}
+
+	return chat, quoted
+}
+
+// mirrorAdminReplyToTopic показывает в теме ответ, отправленный админом из личного чата с ботом
+func (b *ChatBot) mirrorAdminReplyToTopic(ctx context.Context, chatID uint, message *tgbotapi.Message) {
+	chat, err := b.chatService.GetChatWithUser(ctx, chatID)
+	if err != nil || chat.TopicID == 0 {
+		return
+	}
+
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", b.config.Get().SupportGroupID)
+	params.AddNonZero("message_thread_id", chat.TopicID)
+	params.AddNonZero64("from_chat_id", message.Chat.ID)
+	params.AddNonZero("message_id", message.MessageID)
+
+	if _, err := b.sender.MakeRequest(b.config.Get().SupportGroupID, "copyMessage", params, PriorityLow); err != nil {
+		logging.FromContext(ctx).Error("Failed to mirror admin reply to topic", "error", err)
+	}
+}
+
+// closeChatTopic закрывает тему архивированного чата
+func (b *ChatBot) closeChatTopic(ctx context.Context, chatID uint) {
+	if !b.forumEnabled() {
+		return
+	}
+
+	chat, err := b.chatService.GetChatWithUser(ctx, chatID)
+	if err != nil || chat.TopicID == 0 {
+		return
+	}
+
+	b.sendToTopic(ctx, chat.TopicID, b.defaultLocalizer().T("topic.chat_archived", chat.ID))
+
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", b.config.Get().SupportGroupID)
+	params.AddNonZero("message_thread_id", chat.TopicID)
+
+	if _, err := b.sender.MakeRequest(b.config.Get().SupportGroupID, "closeForumTopic", params, PriorityLow); err != nil {
+		logging.FromContext(ctx).Error("Failed to close topic", "topic_id", chat.TopicID, "error", err)
+	}
+}
+
+func (b *ChatBot) reopenTopic(topicID int) error {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", b.config.Get().SupportGroupID)
+	params.AddNonZero("message_thread_id", topicID)
+
+	_, err := b.sender.MakeRequest(b.config.Get().SupportGroupID, "reopenForumTopic", params, PriorityNormal)
+	return err
+}
+
+// sendToTopic отправляет служебное сообщение в разметке HTML в тему группы поддержки, длинное - частями
+func (b *ChatBot) sendToTopic(ctx context.Context, topicID int, text string) {
+	for _, part := range format.Split(text, format.MessageLimit) {
+		params := tgbotapi.Params{}
+		params.AddNonZero64("chat_id", b.config.Get().SupportGroupID)
+		params.AddNonZero("message_thread_id", topicID)
+		params.AddNonEmpty("text", part)
+		params.AddNonEmpty("parse_mode", format.ParseMode)
+
+		if _, err := b.sender.MakeRequest(b.config.Get().SupportGroupID, "sendMessage", params, PriorityLow); err != nil {
+			logging.FromContext(ctx).Error("Failed to send message to topic", "topic_id", topicID, "error", err)
+			return
+		}
+	}
+}
+
+// topicName формирует название темы по данным клиента
+func topicName(l *i18n.Localizer, chat *models.Chat, user *models.User) string {
+	name := strings.TrimSpace(fmt.Sprintf("%s %s", user.FirstName, user.LastName))
+	if user.Username != "" {
+		name = strings.TrimSpace(name + " " + user.Username)
+	}
+	if name == "" {
+		name = l.T("topic.client_name", chat.UserID)
+	}
+
+	runes := []rune(name)
+	if len(runes) > maxTopicNameLength {
+		name = string(runes[:maxTopicNameLength])
+	}
+
+	return name
+}
@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// Таймаут long polling getUpdates в секундах
+	updatesTimeout = 60
+	// Пауза перед повтором после ошибки getUpdates
+	updatesRetryDelay = 3 * time.Second
+	// Если getUpdates не отвечал дольше, цикл обновлений считается зависшим
+	updatesStaleAfter = 2*updatesTimeout*time.Second + 30*time.Second
+	// Как долго переиспользовать результат getMe, чтобы частые проверки не нагружали Bot API
+	getMeCacheTTL = 30 * time.Second
+)
+
+func (b *ChatBot) touchUpdatesHeartbeat() {
+	b.updatesHeartbeat.Store(time.Now().UnixNano())
+}
+
+// CheckTelegram проверяет, что Bot API доступен и принимает токен (getMe)
+func (b *ChatBot) CheckTelegram(ctx context.Context) error {
+	b.getMeMu.Lock()
+	defer b.getMeMu.Unlock()
+
+	if time.Since(b.getMeCheckedAt) < getMeCacheTTL {
+		return b.getMeErr
+	}
+
+	err := observeRequest("getMe", func() error {
+		_, err := b.api.GetMe()
+		return err
+	})
+	if err != nil {
+		err = fmt.Errorf("getMe failed: %w", err)
+	}
+
+	b.getMeCheckedAt = time.Now()
+	b.getMeErr = err
+	return err
+}
+
+// CheckUpdates проверяет, что цикл getUpdates жив и недавно получал ответ
+func (b *ChatBot) CheckUpdates(ctx context.Context) error {
+	heartbeat := b.updatesHeartbeat.Load()
+	if heartbeat == 0 {
+		return fmt.Errorf("update loop is not running")
+	}
+
+	if since := time.Since(time.Unix(0, heartbeat)); since > updatesStaleAfter {
+		return fmt.Errorf("no successful getUpdates for %s", since.Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/i18n"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// withLanguage кладет в контекст переводчик на язык автора обновления:
+// выбранный им язык, а если он еще не выбран - язык его клиента Telegram
+func (b *ChatBot) withLanguage(ctx context.Context, from *tgbotapi.User) context.Context {
+	if from == nil {
+		return ctx
+	}
+
+	lang := b.i18n.Match(from.LanguageCode)
+	if user, err := b.userService.GetUserByTelegramID(ctx, from.ID); err == nil && b.i18n.Supports(user.Language) {
+		lang = user.Language
+	}
+
+	return i18n.WithLocalizer(ctx, b.i18n.Localizer(lang))
+}
+
+// localizer - переводчик на язык автора обновления, вне обработки обновления - язык по умолчанию
+func (b *ChatBot) localizer(ctx context.Context) *i18n.Localizer {
+	if l := i18n.FromContext(ctx); l != nil {
+		return l
+	}
+	return b.defaultLocalizer()
+}
+
+// t переводит сообщение на язык автора обновления
+func (b *ChatBot) t(ctx context.Context, key string, args ...interface{}) string {
+	return b.localizer(ctx).T(key, args...)
+}
+
+// defaultLocalizer - переводчик для общих мест вроде тем группы поддержки, где читателей несколько
+func (b *ChatBot) defaultLocalizer() *i18n.Localizer {
+	return b.i18n.Localizer(b.i18n.Default())
+}
+
+// localizerFor - переводчик на язык получателя, который не является автором обновления (админа в уведомлениях)
+func (b *ChatBot) localizerFor(ctx context.Context, telegramID int64) *i18n.Localizer {
+	user, err := b.userService.GetUserByTelegramID(ctx, telegramID)
+	if err != nil {
+		return b.defaultLocalizer()
+	}
+	return b.i18n.Localizer(user.Language)
+}
+
+// handleLanguageCommand предлагает выбрать язык бота
+func (b *ChatBot) handleLanguageCommand(ctx context.Context, message *tgbotapi.Message, _ *models.User) {
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	for _, lang := range b.i18n.Languages() {
+		// Каждый язык подписан на нем самом, чтобы его нашел тот, кто не понимает текущий
+		label := b.i18n.Localizer(lang).T("language.name")
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, languageData(lang)),
+		))
+	}
+
+	msg := newMessage(message.Chat.ID, b.t(ctx, "language.choose"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
+	b.send(ctx, msg, PriorityNormal)
+}
+
+// handleSetLanguageCallback сохраняет выбранный язык и отвечает уже на нем
+func (b *ChatBot) handleSetLanguageCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+	lang := args.Code(0)
+	if !b.i18n.Supports(lang) {
+		b.answerCallbackQuery(query.ID, b.t(ctx, "language.unknown"))
+		return
+	}
+
+	if err := b.userService.SetLanguage(ctx, int64(query.From.ID), lang); err != nil {
+		logging.FromContext(ctx).Error("Failed to set user language", "error", err)
+		b.answerCallbackQuery(query.ID, b.t(ctx, "common.error"))
+		return
+	}
+
+	// Выбор языка заменяет собой список языков
+	l := b.i18n.Localizer(lang)
+	b.showScreen(ctx, query, newScreen(l.T("language.changed")))
+	b.answerCallbackQuery(query.ID, "")
+}
@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Сколько символов сообщения показывать в истории чата, остальное - по кнопке "Полностью"
+	chatPreviewLimit = 300
+	// Сколько символов сообщения клиента показывать в уведомлении админу
+	notificationPreviewLimit = 1000
+	// Сколько символов цитируемого сообщения показывать в уведомлении
+	quotePreviewLimit = 200
+)
+
+// sendText отправляет сообщение, разбивая длинный текст на части по лимиту Telegram.
+// Ответом на сообщение становится первая часть, кнопки получает последняя.
+func (b *ChatBot) sendText(msg tgbotapi.MessageConfig, priority Priority) ([]tgbotapi.Message, error) {
+	if msg.ParseMode != format.ParseMode {
+		sent, err := b.sender.Send(msg, priority)
+		if err != nil {
+			return nil, err
+		}
+		return []tgbotapi.Message{sent}, nil
+	}
+
+	parts := format.Split(msg.Text, format.MessageLimit)
+	sent := make([]tgbotapi.Message, 0, len(parts))
+	for i, part := range parts {
+		m := msg
+		m.Text = part
+		if i > 0 {
+			m.ReplyToMessageID = 0
+		}
+		if i < len(parts)-1 {
+			m.ReplyMarkup = nil
+		}
+
+		s, err := b.sender.Send(m, priority)
+		if err != nil {
+			return sent, err
+		}
+		sent = append(sent, s)
+	}
+	return sent, nil
+}
+
+// send - sendText для сообщений, о неудачной отправке которых достаточно записать в лог
+func (b *ChatBot) send(ctx context.Context, msg tgbotapi.MessageConfig, priority Priority) {
+	if _, err := b.sendText(msg, priority); err != nil {
+		logging.FromContext(ctx).Error("Failed to send message", "to", msg.ChatID, "error", err)
+	}
+}
+
+// mediaMessage - вложение чата для отправки в Telegram, nil для неизвестного типа
+func mediaMessage(chatID int64, file models.File) tgbotapi.Chattable {
+	switch file.FileType {
+	case "photo":
+		return tgbotapi.NewPhoto(chatID, tgbotapi.FileID(file.FileID))
+	case "video":
+		return tgbotapi.NewVideo(chatID, tgbotapi.FileID(file.FileID))
+	case "document":
+		return tgbotapi.NewDocument(chatID, tgbotapi.FileID(file.FileID))
+	case "voice":
+		return tgbotapi.NewVoice(chatID, tgbotapi.FileID(file.FileID))
+	case "video_note":
+		return tgbotapi.NewVideoNote(chatID, 0, tgbotapi.FileID(file.FileID))
+	}
+	return nil
+}
+
+// withCaption добавляет к медиа подпись в разметке HTML; у кружков подписи не бывает
+func withCaption(media tgbotapi.Chattable, caption string) (tgbotapi.Chattable, bool) {
+	switch m := media.(type) {
+	case tgbotapi.PhotoConfig:
+		m.Caption, m.ParseMode = caption, format.ParseMode
+		return m, true
+	case tgbotapi.VideoConfig:
+		m.Caption, m.ParseMode = caption, format.ParseMode
+		return m, true
+	case tgbotapi.DocumentConfig:
+		m.Caption, m.ParseMode = caption, format.ParseMode
+		return m, true
+	case tgbotapi.VoiceConfig:
+		m.Caption, m.ParseMode = caption, format.ParseMode
+		return m, true
+	}
+	return media, false
+}
+
+// sendMedia отправляет медиа с подписью. То, что не поместилось в подпись (или вся подпись, если
+// у медиа ее не бывает), уходит следом отдельными сообщениями в ответ на него.
+func (b *ChatBot) sendMedia(media tgbotapi.Chattable, caption string, priority Priority) ([]tgbotapi.Message, error) {
+	first, rest := format.SplitCaption(caption)
+	media, ok := withCaption(media, first)
+	if !ok && first != "" {
+		rest = append([]string{first}, rest...)
+	}
+
+	sent, err := b.sender.Send(media, priority)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []tgbotapi.Message{sent}
+	for _, part := range rest {
+		msg := newMessage(sent.Chat.ID, part)
+		msg.ReplyToMessageID = sent.MessageID
+		msg.AllowSendingWithoutReply = true
+
+		s, err := b.sender.Send(msg, priority)
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, s)
+	}
+	return messages, nil
+}
+
+// showFullButton - кнопка, по которой бот присылает сокращенное сообщение целиком
+func showFullButton(label string, messageID uint) tgbotapi.InlineKeyboardButton {
+	return tgbotapi.NewInlineKeyboardButtonData(label, showMessageData(messageID))
+}
+
+// handleShowMessageCallback присылает админу сообщение чата целиком
+func (b *ChatBot) handleShowMessageCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+	messageID := args.ID(0)
+	message, err := b.chatService.GetMessageByID(ctx, messageID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get message", "message_id", messageID, "error", err)
+		b.answerCallbackQuery(query.ID, b.t(ctx, "admin.message.not_found"))
+		return
+	}
+
+	sender := b.t(ctx, "admin.chat.from_client")
+	if !message.IsFromUser {
+		sender = b.t(ctx, "admin.chat.from_admin")
+	}
+
+	text := b.t(ctx, "admin.message.title", message.ChatID, sender, message.CreatedAt.Format(b.t(ctx, "common.datetime_format")))
+	text += "\n\n" + format.Escape(message.Content)
+
+	b.send(ctx, newMessage(query.Message.Chat.ID, text), PriorityNormal)
+	b.answerCallbackQuery(query.ID, "")
+}
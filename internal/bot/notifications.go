@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"ai_support_tg_writer_bot/internal/i18n"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// notifyAdminsAboutNewMessage отправляет уведомления админам о новом сообщении
+func (b *ChatBot) notifyAdminsAboutNewMessage(ctx context.Context, message *models.ChatMessage, user *models.User, quoted *models.ChatMessage) {
+	// Получаем количество непрочитанных чатов
+	unreadCount, _ := b.chatService.GetUnreadChatsCount(ctx)
+
+	// Отправляем уведомление всем админам, каждому на его языке
+	for _, adminID := range b.config.Get().AdminIDs {
+		l := b.localizerFor(ctx, adminID)
+
+		// Формируем текст уведомления
+		notificationText := l.T("notify.new_message") + "\n\n"
+		notificationText += l.T("notify.from", b.formatUserName(user)) + "\n"
+		notificationText += l.T("notify.chat", message.ChatID) + "\n"
+		if quoted != nil {
+			quote, _ := format.Truncate(format.Escape(quoted.Content), quotePreviewLimit)
+			notificationText += l.T("notify.quoted", quote) + "\n"
+		}
+		// Длинное сообщение целиком покажет кнопка "Показать полностью"
+		content, truncated := format.Truncate(format.Escape(message.Content), notificationPreviewLimit)
+		notificationText += l.T("notify.message", content) + "\n\n"
+		notificationText += l.T("notify.unread_chats", unreadCount) + "\n\n"
+		notificationText += l.T("notify.reply_hint")
+
+		msg := newMessage(adminID, notificationText)
+		msg.ReplyToMessageID = b.quotedMessageID(ctx, quoted, adminID)
+		msg.AllowSendingWithoutReply = true
+
+		// Добавляем кнопку для быстрого перехода к чату
+		buttons := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(l.T("common.open_chat"), openChatData(message.ChatID)),
+		)
+		if truncated {
+			buttons = append(buttons, showFullButton(l.T("common.show_full"), message.ID))
+		}
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons)
+
+		sent, err := b.sendText(msg, PriorityLow)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to notify admin", "admin_id", adminID, "error", err)
+		}
+
+		// Запоминаем копии, чтобы ответ админа на уведомление попал в нужный чат
+		b.saveAdminCopies(ctx, message.ID, adminID, sent)
+	}
+}
+
+// saveAdminCopies запоминает сообщения, которыми сообщение чата показано админу, в том числе все части длинного
+func (b *ChatBot) saveAdminCopies(ctx context.Context, messageID uint, adminID int64, sent []tgbotapi.Message) {
+	for _, s := range sent {
+		if err := b.chatService.AddMessageCopy(ctx, messageID, adminID, s.MessageID, models.MessageCopyAdmin); err != nil {
+			logging.FromContext(ctx).Error("Failed to save notification mapping", "error", err)
+		}
+	}
+}
+
+// sendMediaToAdmins отправляет медиа файлы от клиента всем админам
+func (b *ChatBot) sendMediaToAdmins(ctx context.Context, chatMessage *models.ChatMessage, user *models.User) {
+	for _, file := range chatMessage.Files {
+		for _, adminID := range b.config.Get().AdminIDs {
+			// Формируем подпись с username на языке админа
+			l := b.localizerFor(ctx, adminID)
+			caption := l.T("notify.media", mediaTitle(l, file.FileType), b.formatUserName(user), chatMessage.ChatID)
+
+			// Добавляем подпись к медиа если есть
+			if chatMessage.Content != "" {
+				caption += fmt.Sprintf("\n\n%s", format.Escape(chatMessage.Content))
+			}
+
+			media := mediaMessage(adminID, file)
+			if media == nil {
+				continue
+			}
+
+			// Подпись длиннее лимита Telegram продолжается следующими сообщениями
+			sent, err := b.sendMedia(media, caption, PriorityLow)
+			if err != nil {
+				logging.FromContext(ctx).Error("Failed to send media to admin", "admin_id", adminID, "error", err)
+			}
+
+			// Запоминаем копии, чтобы на медиа тоже можно было ответить через "Ответить"
+			b.saveAdminCopies(ctx, chatMessage.ID, adminID, sent)
+		}
+	}
+}
+
+// mediaTitle подписывает тип вложения для админа
+func mediaTitle(l *i18n.Localizer, fileType string) string {
+	switch fileType {
+	case "photo", "video", "document", "voice":
+		return l.T("media." + fileType)
+	default:
+		return l.T("media.file")
+	}
+}
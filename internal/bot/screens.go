@@ -0,0 +1,305 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// screen - экран админки: текст в разметке HTML и кнопки под ним.
+// Экраны собираются одними и теми же функциями, открыты ли они командой (новым сообщением)
+// или кнопкой (на месте сообщения с этой кнопкой), поэтому меню везде выглядят одинаково.
+type screen struct {
+	text     string
+	keyboard *tgbotapi.InlineKeyboardMarkup // nil - экран без кнопок
+}
+
+func newScreen(text string, rows ...[]tgbotapi.InlineKeyboardButton) screen {
+	s := screen{text: text}
+	if len(rows) > 0 {
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+		s.keyboard = &keyboard
+	}
+	return s
+}
+
+// screenError - экран не удалось собрать; key - текст ответа админу на нажатие кнопки
+type screenError struct {
+	key string
+	err error
+}
+
+func (e *screenError) Error() string {
+	return e.key + ": " + e.err.Error()
+}
+
+func (e *screenError) Unwrap() error {
+	return e.err
+}
+
+// sendScreen отправляет экран новым сообщением
+func (b *ChatBot) sendScreen(ctx context.Context, chatID int64, s screen) {
+	msg := newMessage(chatID, s.text)
+	if s.keyboard != nil {
+		msg.ReplyMarkup = *s.keyboard
+	}
+	b.send(ctx, msg, PriorityNormal)
+}
+
+// showScreen показывает экран на месте сообщения, с кнопки которого пришел callback, чтобы
+// навигация по меню не засыпала чат новыми сообщениями. Новое сообщение отправляется, только
+// когда старое нельзя превратить в экран: это медиа, экран не помещается в одно сообщение
+// или Telegram отказался редактировать сообщение (например, слишком старое).
+func (b *ChatBot) showScreen(ctx context.Context, query *tgbotapi.CallbackQuery, s screen) {
+	message := query.Message
+	if message == nil {
+		return
+	}
+
+	if message.Text != "" && format.Len(s.text) <= format.MessageLimit {
+		edit := tgbotapi.NewEditMessageText(message.Chat.ID, message.MessageID, s.text)
+		edit.ParseMode = format.ParseMode
+		// Без reply_markup Telegram убирает кнопки у отредактированного сообщения
+		edit.ReplyMarkup = s.keyboard
+
+		_, err := b.sender.Request(edit, PriorityNormal)
+		if err == nil || isNotModified(err) {
+			return
+		}
+		logging.FromContext(ctx).Warn("Failed to edit screen, sending a new message", "message_id", message.MessageID, "error", err)
+	}
+
+	b.sendScreen(ctx, message.Chat.ID, s)
+}
+
+// removeKeyboard убирает кнопки у сообщения, когда выбор по ним уже сделан
+func (b *ChatBot) removeKeyboard(ctx context.Context, message *tgbotapi.Message) {
+	if message == nil || message.ReplyMarkup == nil {
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageReplyMarkup(message.Chat.ID, message.MessageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+	if _, err := b.sender.Request(edit, PriorityNormal); err != nil && !isNotModified(err) {
+		logging.FromContext(ctx).Warn("Failed to remove keyboard", "message_id", message.MessageID, "error", err)
+	}
+}
+
+// isNotModified - Telegram отклонил редактирование, потому что сообщение уже такое.
+// Так бывает при повторном нажатии той же кнопки, экран при этом показан верно.
+func isNotModified(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "message is not modified")
+}
+
+// answerScreenError отвечает на нажатие кнопки, экран для которой собрать не удалось
+func (b *ChatBot) answerScreenError(ctx context.Context, query *tgbotapi.CallbackQuery, err error) {
+	key := "common.error"
+	var se *screenError
+	if errors.As(err, &se) {
+		key = se.key
+	}
+	logging.FromContext(ctx).Error("Failed to render screen", "data", query.Data, "error", err)
+	b.answerCallbackQuery(query.ID, b.t(ctx, key))
+}
+
+// adminMenuScreen - главное меню админки
+func (b *ChatBot) adminMenuScreen(ctx context.Context) screen {
+	unreadCount, _ := b.chatService.GetUnreadChatsCount(ctx)
+
+	return newScreen(b.t(ctx, "admin.menu.title", unreadCount),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "admin.menu.active_chats"), activeChatsData(0)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "admin.menu.archived_chats"), archivedChatsData(0)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "admin.menu.stats"), statsData(service.PeriodWeek)),
+		),
+	)
+}
+
+// chatListScreen - страница списка активных или архивированных чатов
+func (b *ChatBot) chatListScreen(ctx context.Context, status models.ChatStatus, page int) (screen, error) {
+	titleKey, emptyKey, pageData := "admin.chats.active_title", "admin.chats.active_empty", activeChatsData
+	load := b.chatService.GetActiveChatsPaginated
+	if status == models.ChatStatusArchived {
+		titleKey, emptyKey, pageData = "admin.chats.archived_title", "admin.chats.archived_empty", archivedChatsData
+		load = b.chatService.GetArchivedChatsPaginated
+	}
+
+	perPage := b.config.Get().ChatsPerPage
+	chats, err := load(ctx, perPage, page*perPage)
+	if err != nil {
+		return screen{}, &screenError{key: "admin.chats.load_failed", err: err}
+	}
+
+	backRow := tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "common.back_to_admin"), adminMenuData()))
+	if len(chats) == 0 && page == 0 {
+		return newScreen(b.t(ctx, emptyKey), backRow), nil
+	}
+
+	text := b.t(ctx, titleKey, page+1) + "\n\n"
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	for _, chat := range chats {
+		unreadBadge, buttonBadge := "", ""
+		if chat.UnreadCount > 0 {
+			unreadBadge = fmt.Sprintf(" 🔴(%d)", chat.UnreadCount)
+			buttonBadge = " 🔴"
+		}
+
+		// Активные чаты упорядочены по последнему сообщению, архивированные - по архивации
+		date := chat.CreatedAt
+		if status == models.ChatStatusArchived {
+			date = chat.UpdatedAt
+		} else if chat.LastMessageAt != nil {
+			date = *chat.LastMessageAt
+		}
+
+		text += b.t(ctx, "admin.chats.chat", chat.ID, unreadBadge) + "\n"
+		text += fmt.Sprintf("👤 %s\n", b.formatUserName(&chat.User))
+		text += fmt.Sprintf("📅 %s\n\n", date.Format(b.t(ctx, "common.date_format")))
+
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("🔸 #%d%s - %s", chat.ID, buttonBadge, chat.User.FirstName),
+			chatData(chat.ID, 0),
+		)))
+	}
+
+	// Кнопки навигации
+	var navButtons []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "common.back"), pageData(page-1)))
+	}
+	if len(chats) == perPage {
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "common.next"), pageData(page+1)))
+	}
+	if len(navButtons) > 0 {
+		buttons = append(buttons, navButtons)
+	}
+
+	buttons = append(buttons, backRow)
+	return newScreen(text, buttons...), nil
+}
+
+// chatScreen - карточка чата со страницей его сообщений
+func (b *ChatBot) chatScreen(ctx context.Context, chatID uint, page int) (screen, error) {
+	chat, err := b.chatService.GetChatWithUser(ctx, chatID)
+	if err != nil {
+		return screen{}, &screenError{key: "admin.chat.not_found", err: err}
+	}
+
+	// Получаем сообщения с пагинацией
+	perPage := b.config.Get().MessagesPerPage
+	offset := page * perPage
+	messages, err := b.chatService.GetChatMessagesPaginated(ctx, chatID, perPage, offset)
+	if err != nil {
+		return screen{}, &screenError{key: "admin.chat.messages_failed", err: err}
+	}
+
+	// Получаем общее количество сообщений
+	totalMessages, err := b.chatService.GetChatMessagesCount(ctx, chatID)
+	if err != nil {
+		return screen{}, &screenError{key: "admin.chat.count_failed", err: err}
+	}
+
+	// Формируем текст с информацией о чате
+	text := b.t(ctx, "admin.chat.title", chat.ID) + "\n"
+	text += b.t(ctx, "admin.chat.user", b.formatUserName(&chat.User)) + "\n"
+	text += b.t(ctx, "admin.chat.created", chat.CreatedAt.Format(b.t(ctx, "common.date_format"))) + "\n"
+
+	status := b.t(ctx, "admin.chat.status_active")
+	if chat.Status == models.ChatStatusArchived {
+		status = b.t(ctx, "admin.chat.status_archived")
+	}
+	text += b.t(ctx, "admin.chat.status", status) + "\n"
+	text += b.t(ctx, "admin.chat.messages_count", totalMessages) + "\n\n"
+
+	// Добавляем сообщения
+	var fullButtons [][]tgbotapi.InlineKeyboardButton
+	if len(messages) > 0 {
+		text += b.t(ctx, "admin.chat.messages_title", page+1) + "\n"
+		for _, message := range messages {
+			sender := b.t(ctx, "admin.chat.from_client")
+			if !message.IsFromUser {
+				sender = b.t(ctx, "admin.chat.from_admin")
+			}
+
+			// Длинные сообщения сокращаем, целиком их покажет кнопка "Полностью"
+			content, truncated := format.Truncate(format.Escape(message.Content), chatPreviewLimit)
+			if truncated {
+				fullButtons = append(fullButtons, tgbotapi.NewInlineKeyboardRow(showFullButton(
+					b.t(ctx, "admin.chat.show_full", message.CreatedAt.Format(b.t(ctx, "common.date_format"))), message.ID)))
+			}
+
+			// Добавляем информацию о файлах если есть
+			if len(message.Files) > 0 {
+				content += " 📎"
+			}
+
+			// Отмечаем ответы, которые не дошли до клиента
+			switch message.DeliveryStatus {
+			case models.DeliveryStatusPending:
+				content += " ⏳"
+			case models.DeliveryStatusFailed:
+				content += " " + b.t(ctx, "admin.chat.not_delivered")
+			case models.DeliveryStatusBlocked:
+				content += " " + b.t(ctx, "admin.chat.blocked")
+			case models.DeliveryStatusPartial:
+				content += " " + b.t(ctx, "admin.chat.partially_delivered")
+			}
+
+			text += fmt.Sprintf("%s: %s\n", sender, content)
+			text += fmt.Sprintf("📅 %s\n\n", message.CreatedAt.Format(b.t(ctx, "common.date_format")))
+		}
+	} else {
+		text += b.t(ctx, "admin.chat.no_messages") + "\n"
+	}
+
+	// Создаем кнопки
+	buttons := fullButtons
+
+	// Кнопки навигации по сообщениям
+	var navButtons []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "common.back"), chatData(chat.ID, page-1)))
+	}
+	if int64(offset+perPage) < totalMessages {
+		navButtons = append(navButtons, tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "common.next"), chatData(chat.ID, page+1)))
+	}
+	if len(navButtons) > 0 {
+		buttons = append(buttons, navButtons)
+	}
+
+	// Кнопка для детального просмотра истории
+	buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+		b.t(ctx, "admin.chat.history_button"),
+		chatHistoryData(chat.ID),
+	)))
+
+	backData := activeChatsData(0)
+	if chat.Status == models.ChatStatusActive {
+		buttons = append(buttons,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+				b.t(ctx, "admin.chat.reply_button"),
+				chatReplyData(chat.ID),
+			)),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+				b.t(ctx, "admin.chat.archive_button"),
+				chatArchiveData(chat.ID),
+			)),
+		)
+	} else {
+		backData = archivedChatsData(0)
+	}
+	buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "admin.chat.back_to_chats"), backData)))
+
+	return newScreen(text, buttons...), nil
+}
@@ -0,0 +1,445 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/metrics"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Priority определяет очередность исходящих запросов при упоре в лимиты Telegram
+type Priority int
+
+const (
+	PriorityHigh   Priority = iota // Ответы поддержки клиентам
+	PriorityNormal                 // Ответы на действия пользователя: меню, подтверждения
+	PriorityLow                    // Рассылка уведомлений админам, пересылка медиа, история
+)
+
+const (
+	// Общий лимит бота - около 30 сообщений в секунду
+	globalRatePerSecond = 30
+	globalBurst         = 30
+	// В личный чат - не чаще одного сообщения в секунду
+	privateChatRatePerSecond = 1
+	privateChatBurst         = 3
+	// В группу - не больше 20 сообщений в минуту
+	groupChatRatePerSecond = 20.0 / 60.0
+	groupChatBurst         = 5
+	// Сколько раз повторять запрос после ответа 429, прежде чем вернуть ошибку
+	maxRateLimitRetries = 3
+	// Через сколько простоя удалять лимитер чата
+	chatBucketIdleTTL = 10 * time.Minute
+)
+
+// Sender - единая точка отправки запросов в Telegram с учетом лимитов по чатам и общего лимита бота.
+// Оба лимита ждет диспетчер, а не вызывающий: запросы с более высоким приоритетом проходят первыми,
+// запросы в чат, упершийся в свой лимит, пропускаются до появления токена, не задерживая остальные чаты.
+// После ответа 429 все отправки приостанавливаются на retry_after.
+type Sender struct {
+	api    *tgbotapi.BotAPI
+	global *tokenBucket
+	logger *slog.Logger
+
+	chatsMu sync.Mutex
+	chats   map[int64]*tokenBucket
+
+	pauseMu     sync.Mutex
+	pausedUntil time.Time
+
+	queueMu sync.Mutex
+	queues  [PriorityLow + 1][]*sendJob
+	wakeup  chan struct{}
+}
+
+type sendJob struct {
+	chatID int64 // 0 - применяется только общий лимит
+	method string
+	call   func() error
+	done   chan error
+}
+
+// NewSender создает отправителя и запускает диспетчер очередей
+func NewSender(api *tgbotapi.BotAPI, logger *slog.Logger) *Sender {
+	s := &Sender{
+		api:    api,
+		global: newTokenBucket(globalRatePerSecond, globalBurst),
+		logger: logger,
+		chats:  make(map[int64]*tokenBucket),
+		wakeup: make(chan struct{}, 1),
+	}
+
+	go s.dispatch()
+
+	return s
+}
+
+// Send отправляет сообщение (или редактирование) с учетом лимитов
+func (s *Sender) Send(c tgbotapi.Chattable, priority Priority) (tgbotapi.Message, error) {
+	var sent tgbotapi.Message
+	err := s.do(chattableChatID(c), chattableMethod(c), priority, func() error {
+		var err error
+		sent, err = s.api.Send(c)
+		return err
+	})
+	return sent, err
+}
+
+// Request выполняет запрос, результат которого не является сообщением
+func (s *Sender) Request(c tgbotapi.Chattable, priority Priority) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := s.do(chattableChatID(c), chattableMethod(c), priority, func() error {
+		var err error
+		resp, err = s.api.Request(c)
+		return err
+	})
+	return resp, err
+}
+
+// MakeRequest вызывает метод Bot API, для которого в tgbotapi нет готового конфига
+func (s *Sender) MakeRequest(chatID int64, endpoint string, params tgbotapi.Params, priority Priority) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := s.do(chatID, endpoint, priority, func() error {
+		var err error
+		resp, err = s.api.MakeRequest(endpoint, params)
+		return err
+	})
+	return resp, err
+}
+
+// QueueDepth возвращает число запросов, ожидающих лимитов, по приоритетам
+func (s *Sender) QueueDepth() map[string]float64 {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	return map[string]float64{
+		"high":   float64(len(s.queues[PriorityHigh])),
+		"normal": float64(len(s.queues[PriorityNormal])),
+		"low":    float64(len(s.queues[PriorityLow])),
+	}
+}
+
+// do ставит запрос в очередь и ждет его выполнения. Сам вызывающий лимиты не ждет.
+func (s *Sender) do(chatID int64, method string, priority Priority, call func() error) error {
+	job := &sendJob{chatID: chatID, method: method, call: call, done: make(chan error, 1)}
+
+	s.queueMu.Lock()
+	s.queues[priority] = append(s.queues[priority], job)
+	s.queueMu.Unlock()
+
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+
+	return <-job.done
+}
+
+// dispatch выдает запросам лимиты чатов и общий лимит в порядке приоритета
+func (s *Sender) dispatch() {
+	for {
+		job, retryIn := s.next()
+		if job == nil {
+			s.sleep(retryIn)
+			continue
+		}
+
+		s.waitPause()
+		s.global.wait()
+
+		go func(job *sendJob) {
+			job.done <- s.execute(job.method, job.call)
+		}(job)
+	}
+}
+
+// next забирает из очередей первый запрос с наивысшим приоритетом, чат которого не уперся в свой лимит.
+// Запросы одного чата с одним приоритетом уходят по порядку. Если готовых запросов нет, возвращает,
+// через сколько появится токен у ближайшего чата (0 - очереди пусты).
+func (s *Sender) next() (*sendJob, time.Duration) {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	var retryIn time.Duration
+	blocked := make(map[int64]bool)
+	for priority, queue := range s.queues {
+		for i, job := range queue {
+			if job.chatID != 0 {
+				if blocked[job.chatID] {
+					continue
+				}
+				if wait := s.chatBucket(job.chatID).tryTake(); wait > 0 {
+					blocked[job.chatID] = true
+					if retryIn == 0 || wait < retryIn {
+						retryIn = wait
+					}
+					continue
+				}
+			}
+
+			s.queues[priority] = append(queue[:i], queue[i+1:]...)
+			return job, 0
+		}
+	}
+
+	return nil, retryIn
+}
+
+// sleep ждет нового запроса или, если d > 0, появления токена у чата, упершегося в лимит
+func (s *Sender) sleep(d time.Duration) {
+	if d <= 0 {
+		<-s.wakeup
+		return
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-s.wakeup:
+	case <-timer.C:
+	}
+}
+
+// execute выполняет запрос, повторяя его после паузы, если Telegram ответил 429
+func (s *Sender) execute(method string, call func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := observeRequest(method, call)
+
+		var apiErr *tgbotapi.Error
+		if err == nil || !errors.As(err, &apiErr) || apiErr.Code != http.StatusTooManyRequests {
+			return err
+		}
+
+		retryAfter := time.Duration(apiErr.RetryAfter) * time.Second
+		if retryAfter <= 0 {
+			retryAfter = time.Second
+		}
+		s.logger.Warn("Telegram rate limit hit, pausing sends", "method", method, "retry_after", retryAfter)
+		s.pause(retryAfter)
+
+		if attempt >= maxRateLimitRetries {
+			return err
+		}
+
+		s.waitPause()
+		s.global.wait()
+	}
+}
+
+func (s *Sender) pause(d time.Duration) {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(s.pausedUntil) {
+		s.pausedUntil = until
+	}
+}
+
+func (s *Sender) waitPause() {
+	s.pauseMu.Lock()
+	until := s.pausedUntil
+	s.pauseMu.Unlock()
+
+	if d := time.Until(until); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// chatBucket возвращает лимитер чата, создавая его при первом обращении
+func (s *Sender) chatBucket(chatID int64) *tokenBucket {
+	s.chatsMu.Lock()
+	defer s.chatsMu.Unlock()
+
+	bucket, ok := s.chats[chatID]
+	if !ok {
+		// Отрицательные ID - группы и каналы, у них свой лимит
+		if chatID < 0 {
+			bucket = newTokenBucket(groupChatRatePerSecond, groupChatBurst)
+		} else {
+			bucket = newTokenBucket(privateChatRatePerSecond, privateChatBurst)
+		}
+		s.chats[chatID] = bucket
+		s.evictIdleBuckets()
+	}
+
+	return bucket
+}
+
+// evictIdleBuckets удаляет лимитеры давно неактивных чатов. Вызывается под chatsMu.
+func (s *Sender) evictIdleBuckets() {
+	if len(s.chats) < 1000 {
+		return
+	}
+	for id, bucket := range s.chats {
+		if bucket.idleFor() > chatBucketIdleTTL {
+			delete(s.chats, id)
+		}
+	}
+}
+
+// observeRequest выполняет один запрос к Bot API и учитывает его в метриках
+func observeRequest(method string, call func() error) error {
+	start := time.Now()
+	err := call()
+	metrics.TelegramRequestDuration.Observe(metrics.Since(start), method)
+	metrics.TelegramRequestsTotal.Inc(method)
+
+	if err != nil {
+		countTelegramError(method, err)
+	}
+
+	return err
+}
+
+func countTelegramError(method string, err error) {
+	code := "network"
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		code = strconv.Itoa(apiErr.Code)
+	}
+	metrics.TelegramErrorsTotal.Inc(method, code)
+}
+
+// chattableMethod возвращает имя метода Bot API для метрик
+func chattableMethod(c tgbotapi.Chattable) string {
+	switch c.(type) {
+	case tgbotapi.MessageConfig:
+		return "sendMessage"
+	case tgbotapi.PhotoConfig:
+		return "sendPhoto"
+	case tgbotapi.VideoConfig:
+		return "sendVideo"
+	case tgbotapi.DocumentConfig:
+		return "sendDocument"
+	case tgbotapi.VoiceConfig:
+		return "sendVoice"
+	case tgbotapi.VideoNoteConfig:
+		return "sendVideoNote"
+	case tgbotapi.CopyMessageConfig:
+		return "copyMessage"
+	case tgbotapi.EditMessageTextConfig:
+		return "editMessageText"
+	case tgbotapi.EditMessageCaptionConfig:
+		return "editMessageCaption"
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return "editMessageReplyMarkup"
+	case tgbotapi.CallbackConfig:
+		return "answerCallbackQuery"
+	default:
+		// Для остальных конфигов хватит имени типа: tgbotapi.DeleteMessageConfig → DeleteMessageConfig
+		name := fmt.Sprintf("%T", c)
+		return name[strings.LastIndex(name, ".")+1:]
+	}
+}
+
+// chattableChatID достает ID чата из конфигов, которые отправляет бот. 0 - применяется только общий лимит.
+func chattableChatID(c tgbotapi.Chattable) int64 {
+	switch cfg := c.(type) {
+	case tgbotapi.MessageConfig:
+		return cfg.ChatID
+	case tgbotapi.PhotoConfig:
+		return cfg.ChatID
+	case tgbotapi.VideoConfig:
+		return cfg.ChatID
+	case tgbotapi.DocumentConfig:
+		return cfg.ChatID
+	case tgbotapi.VoiceConfig:
+		return cfg.ChatID
+	case tgbotapi.VideoNoteConfig:
+		return cfg.ChatID
+	case tgbotapi.CopyMessageConfig:
+		return cfg.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return cfg.ChatID
+	case tgbotapi.EditMessageCaptionConfig:
+		return cfg.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return cfg.ChatID
+	default:
+		return 0
+	}
+}
+
+// tokenBucket - простой лимитер "ведро токенов"
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64 // токенов в секунду
+	burst    float64
+	tokens   float64
+	last     time.Time
+	lastUsed time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	now := time.Now()
+	return &tokenBucket{
+		rate:     rate,
+		burst:    burst,
+		tokens:   burst,
+		last:     now,
+		lastUsed: now,
+	}
+}
+
+// wait забирает токен, при необходимости дожидаясь его появления
+func (tb *tokenBucket) wait() {
+	if d := tb.reserve(); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// reserve забирает токен (в долг, если их нет) и возвращает, сколько нужно подождать
+func (tb *tokenBucket) reserve() time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.refill()
+
+	tb.tokens--
+	if tb.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+// tryTake забирает токен, если он есть. Иначе, не уходя в долг, возвращает, через сколько он появится.
+func (tb *tokenBucket) tryTake() time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.refill()
+
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+}
+
+// refill начисляет токены за прошедшее время. Вызывается под mu.
+func (tb *tokenBucket) refill() {
+	now := time.Now()
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = now
+	tb.lastUsed = now
+}
+
+func (tb *tokenBucket) idleFor() time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return time.Since(tb.lastUsed)
+}
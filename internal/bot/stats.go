@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"ai_support_tg_writer_bot/internal/i18n"
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/service"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Сколько операторов и часов показывать в статистике
+	statsTopOperators = 5
+	statsTopHours     = 3
+	// До какой длины периода показывать разбивку по дням
+	statsMaxDailyRows = 7
+)
+
+var statsPeriods = []string{service.PeriodToday, service.PeriodWeek, service.PeriodMonth, service.PeriodYear}
+
+// statsPeriodTitle - название периода на кнопке и в заголовке отчета
+func statsPeriodTitle(l *i18n.Localizer, period string) string {
+	return l.T("stats.period." + period)
+}
+
+func (b *ChatBot) handleAdminStatsCallback(ctx context.Context, query *tgbotapi.CallbackQuery, args callbackArgs) {
+	s, err := b.statsScreen(ctx, args.Code(0))
+	if err != nil {
+		b.answerScreenError(ctx, query, err)
+		return
+	}
+
+	b.showScreen(ctx, query, s)
+	b.answerCallbackQuery(query.ID, "")
+}
+
+// statsScreen - отчет за период с кнопками переключения периода
+func (b *ChatBot) statsScreen(ctx context.Context, period string) (screen, error) {
+	from, to, err := b.analyticsService.PeriodRange(period)
+	if err != nil {
+		return screen{}, &screenError{key: "stats.unknown_period", err: err}
+	}
+
+	report, err := b.analyticsService.GetReport(ctx, from, to)
+	if err != nil {
+		return screen{}, &screenError{key: "stats.failed", err: err}
+	}
+
+	l := b.localizer(ctx)
+	periodButtons := make([]tgbotapi.InlineKeyboardButton, 0, len(statsPeriods))
+	for _, p := range statsPeriods {
+		title := statsPeriodTitle(l, p)
+		if p == period {
+			title = "• " + title
+		}
+		periodButtons = append(periodButtons, tgbotapi.NewInlineKeyboardButtonData(title, statsData(p)))
+	}
+
+	return newScreen(formatStatsReport(l, report, statsPeriodTitle(l, period)),
+		periodButtons,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(l.T("common.back_to_admin"), adminMenuData())),
+	), nil
+}
+
+// formatStatsReport описывает отчет для админа в Telegram
+func formatStatsReport(l *i18n.Localizer, report *models.AnalyticsReport, periodTitle string) string {
+	var text strings.Builder
+
+	text.WriteString(l.T("stats.title", periodTitle) + "\n\n")
+	text.WriteString(l.T("stats.active", report.ActiveChats) + "\n")
+	text.WriteString(l.T("stats.archived", report.ArchivedChats) + "\n")
+	text.WriteString(l.T("stats.total", report.ActiveChats+report.ArchivedChats) + "\n\n")
+
+	backlog := report.Backlog
+	text.WriteString(l.T("stats.unread", backlog.Chats, backlog.Messages) + "\n")
+	if backlog.OldestUnreadAt != nil {
+		text.WriteString(l.T("stats.oldest_unread", formatSeconds(l, time.Since(*backlog.OldestUnreadAt).Seconds())) + "\n")
+	}
+	text.WriteString("\n")
+
+	var newChats, clientMessages, supportMessages int64
+	for _, day := range report.Daily {
+		newChats += day.NewChats
+		clientMessages += day.ClientMessages
+		supportMessages += day.SupportMessages
+	}
+	text.WriteString(l.T("stats.new_chats", newChats) + "\n")
+	text.WriteString(l.T("stats.messages", clientMessages, supportMessages) + "\n")
+
+	if len(report.Daily) > 1 && len(report.Daily) <= statsMaxDailyRows {
+		for _, day := range report.Daily {
+			fmt.Fprintf(&text, "  %s: 🆕 %d, ✉️ %d/%d\n", day.Day.Format(l.T("stats.day_format")), day.NewChats, day.ClientMessages, day.SupportMessages)
+		}
+	}
+	text.WriteString("\n")
+
+	response := report.FirstResponse
+	if response.Answered > 0 {
+		text.WriteString(l.T("stats.first_response",
+			formatSeconds(l, response.MedianSeconds), formatSeconds(l, response.AverageSeconds)) + "\n")
+	} else {
+		text.WriteString(l.T("stats.first_response_none") + "\n")
+	}
+	if response.Unanswered > 0 {
+		text.WriteString(l.T("stats.unanswered", response.Unanswered) + "\n")
+	}
+
+	if len(report.Operators) > 0 {
+		text.WriteString("\n" + l.T("stats.operators") + "\n")
+		for i, operator := range report.Operators {
+			if i == statsTopOperators {
+				break
+			}
+			fmt.Fprintf(&text, "  %s: %d / %d\n", format.Escape(operatorName(operator)), operator.Chats, operator.Messages)
+		}
+	}
+
+	if len(report.BusiestHours) > 0 {
+		hours := make([]string, 0, statsTopHours)
+		for i, hour := range report.BusiestHours {
+			if i == statsTopHours {
+				break
+			}
+			hours = append(hours, fmt.Sprintf("%02d:00 (%d)", hour.Hour, hour.Messages))
+		}
+		text.WriteString("\n" + l.T("stats.busiest_hours", strings.Join(hours, ", ")) + "\n")
+	}
+
+	return text.String()
+}
+
+func operatorName(operator models.OperatorStats) string {
+	name := strings.TrimSpace(operator.FirstName + " " + operator.LastName)
+	if name == "" {
+		name = operator.Username
+	}
+	if name == "" {
+		name = fmt.Sprintf("ID %d", operator.UserID)
+	}
+	return name
+}
+
+// formatSeconds записывает длительность коротко: "45 с", "12 мин", "3 ч 5 мин", "2 дн 4 ч"
+func formatSeconds(l *i18n.Localizer, seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	switch {
+	case d < time.Minute:
+		return l.T("stats.seconds", int(d.Seconds()))
+	case d < time.Hour:
+		return l.T("stats.minutes", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return l.T("stats.hours", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return l.T("stats.days", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/events"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/service"
+	"context"
+	"time"
+)
+
+// subscribe подключает побочные эффекты бота к событиям чатов
+func (b *ChatBot) subscribe(bus *events.Bus) {
+	// Подтверждение клиенту - сразу, в порядке обработки его сообщений
+	events.On(bus, "bot.client_ack", events.Sync, b.onMessageReceivedAck)
+
+	if b.forumEnabled() {
+		// Создание темы и публикация в нее идут через одну очередь, чтобы тема появлялась раньше сообщений
+		bus.Subscribe("bot.forum", events.Async, b.onForumEvent,
+			events.ChatCreatedEvent, events.MessageReceivedEvent, events.MessageEditedEvent, events.ChatArchivedEvent)
+	} else {
+		bus.Subscribe("bot.admin_notifications", events.Async, b.onAdminNotificationEvent,
+			events.MessageReceivedEvent, events.MessageEditedEvent)
+	}
+}
+
+// onMessageReceivedAck подтверждает клиенту, что сообщение получено
+func (b *ChatBot) onMessageReceivedAck(ctx context.Context, e events.MessageReceived) {
+	if e.Message.TelegramChatID == 0 {
+		return
+	}
+
+	// Вне рабочего времени предупреждаем, что ответ будет позже
+	key := models.TemplateMessageReceived
+	if !b.config.Get().BusinessHours.IsOpen(time.Now()) {
+		key = models.TemplateOutsideBusinessHours
+	}
+
+	data := service.TemplateData{ChatID: e.Message.ChatID}
+	if user, err := b.userService.GetUserByID(ctx, e.Message.UserID); err == nil {
+		data = service.NewTemplateData(user, e.Message.ChatID)
+	}
+
+	// Событие публикуется при обработке сообщения клиента, поэтому в контексте его язык
+	text := b.clientText(ctx, key, b.localizer(ctx), data)
+	b.sendMessage(ctx, e.Message.TelegramChatID, text)
+}
+
+// onAdminNotificationEvent рассылает админам в личные чаты новые сообщения клиентов и их правки
+func (b *ChatBot) onAdminNotificationEvent(ctx context.Context, event events.Event) {
+	switch e := event.(type) {
+	case events.MessageReceived:
+		user, err := b.userService.GetUserByID(ctx, e.Message.UserID)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to get message author", "error", err)
+			return
+		}
+
+		b.notifyAdminsAboutNewMessage(ctx, &e.Message, user, e.Quoted)
+		b.sendMediaToAdmins(ctx, &e.Message, user)
+
+	case events.MessageEdited:
+		// Свои правки админы видят сами, клиенту их доставляет обработчик правки
+		if e.Message.IsFromUser {
+			b.notifyAdminsAboutEditedMessage(ctx, &e.Message, e.OldContent)
+		}
+	}
+}
+
+// onForumEvent ведет тему чата в группе поддержки
+func (b *ChatBot) onForumEvent(ctx context.Context, event events.Event) {
+	switch e := event.(type) {
+	case events.ChatCreated:
+		user, err := b.userService.GetUserByID(ctx, e.Chat.UserID)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to get chat user", "error", err)
+			return
+		}
+		if err := b.ensureChatTopic(ctx, &e.Chat, user, true); err != nil {
+			logging.FromContext(ctx).Error("Failed to ensure chat topic", "error", err)
+		}
+
+	case events.MessageReceived:
+		chat, err := b.chatService.GetChatWithUser(ctx, e.Message.ChatID)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to get chat", "error", err)
+			return
+		}
+		// Тема могла не создаться вместе с чатом - пробуем еще раз
+		if err := b.ensureChatTopic(ctx, chat, &chat.User, false); err != nil {
+			logging.FromContext(ctx).Error("Failed to ensure chat topic", "error", err)
+			return
+		}
+		if err := b.postClientMessageToTopic(ctx, chat, &e.Message); err != nil {
+			logging.FromContext(ctx).Error("Failed to post message to topic", "error", err)
+		}
+
+	case events.MessageEdited:
+		if e.Message.IsFromUser {
+			b.postEditToTopic(ctx, &e.Message, e.OldContent)
+		}
+
+	case events.ChatArchived:
+		b.closeChatTopic(ctx, e.ChatID)
+	}
+}
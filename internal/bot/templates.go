@@ -0,0 +1,367 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"ai_support_tg_writer_bot/internal/i18n"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Сколько версий показывать в истории шаблона
+	templateHistoryLimit = 10
+	// Сколько символов версии показывать в истории
+	templateHistorySnippet = 100
+	// Номер чата в предпросмотре шаблона
+	templatePreviewChatID = 42
+)
+
+// clientText возвращает текст для клиента по шаблону key на языке l.
+// Шаблон, измененный админами, важнее текста из конфигурации, а тот - важнее перевода.
+func (b *ChatBot) clientText(ctx context.Context, key string, l *i18n.Localizer, data service.TemplateData) string {
+	body := b.defaultTemplateBody(key, l)
+	tpl, err := b.templateService.Get(ctx, key, l.Lang())
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get message template", "key", key, "error", err)
+	} else if tpl != nil {
+		body = tpl.Body
+	}
+
+	// Шаблон клиенту не уходит как есть: если он не собрался (тексты из конфигурации при запуске
+	// не проверяются), берем текст по умолчанию, а за ним - перевод из каталога, с теми же данными
+	catalogBody := l.T(templateCatalogKey(key))
+	tried := make(map[string]bool, 3)
+	for _, candidate := range []string{body, b.defaultTemplateBody(key, l), catalogBody} {
+		if tried[candidate] {
+			continue
+		}
+		tried[candidate] = true
+
+		text, err := service.RenderTemplate(candidate, data)
+		if err == nil {
+			return text
+		}
+		logging.FromContext(ctx).Warn("Failed to render message template", "key", key, "language", l.Lang(), "error", err)
+	}
+	return catalogBody
+}
+
+// defaultTemplateBody - текст шаблона, пока админы его не меняли: из конфигурации или перевод
+func (b *ChatBot) defaultTemplateBody(key string, l *i18n.Localizer) string {
+	texts := b.config.Get().Texts
+	configured := ""
+	switch key {
+	case models.TemplateWelcome:
+		configured = texts.Welcome
+	case models.TemplateMessageReceived:
+		configured = texts.MessageReceived
+	case models.TemplateOutsideBusinessHours:
+		configured = texts.OutsideBusinessHours
+	}
+	if configured != "" {
+		return configured
+	}
+	return l.T(templateCatalogKey(key))
+}
+
+// templateCatalogKey - ключ перевода с текстом шаблона по умолчанию
+func templateCatalogKey(key string) string {
+	switch key {
+	case models.TemplateWelcome:
+		return "client.welcome"
+	case models.TemplateMessageReceived:
+		return "client.message_received"
+	case models.TemplateOutsideBusinessHours:
+		return "client.outside_business_hours"
+	case models.TemplateSupportReply:
+		return "client.support_reply"
+	}
+	return ""
+}
+
+// templateDraft - измененный шаблон, который админ еще не подтвердил после предпросмотра
+type templateDraft struct {
+	key      string
+	language string
+	body     string // Пусто - вернуть текст по умолчанию
+}
+
+// templateDrafts хранит черновики шаблонов по Telegram ID админа, у каждого админа один черновик
+type templateDrafts struct {
+	mu     sync.Mutex
+	drafts map[int64]templateDraft
+}
+
+func (d *templateDrafts) set(adminID int64, draft templateDraft) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.drafts == nil {
+		d.drafts = make(map[int64]templateDraft)
+	}
+	d.drafts[adminID] = draft
+}
+
+func (d *templateDrafts) take(adminID int64) (templateDraft, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	draft, ok := d.drafts[adminID]
+	delete(d.drafts, adminID)
+	return draft, ok
+}
+
+// handleTemplateCommand - управление шаблонами текстов для клиентов.
+// Первая строка - подкоманда и ее аргументы, со второй строки - текст шаблона для set.
+func (b *ChatBot) handleTemplateCommand(ctx context.Context, message *tgbotapi.Message, user *models.User) {
+	args, body, _ := strings.Cut(message.CommandArguments(), "\n")
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		b.sendTemplateList(ctx, message.Chat.ID)
+		return
+	}
+
+	action := fields[0]
+	if len(fields) < 2 {
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "templates.usage"))
+		return
+	}
+	key := fields[1]
+	if !service.IsTemplateKey(key) {
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "templates.unknown_key", format.Escape(key)))
+		return
+	}
+
+	// Язык необязателен: по умолчанию - язык админа
+	lang, rest := b.localizer(ctx).Lang(), fields[2:]
+	if len(rest) > 0 {
+		if _, err := strconv.Atoi(rest[0]); err != nil {
+			if !b.i18n.Supports(rest[0]) {
+				b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "templates.unknown_language", format.Escape(rest[0])))
+				return
+			}
+			lang, rest = rest[0], rest[1:]
+		}
+	}
+
+	switch action {
+	case "show":
+		b.showTemplate(ctx, message.Chat.ID, user, key, lang)
+	case "set":
+		if strings.TrimSpace(body) == "" {
+			b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "templates.empty_body"))
+			return
+		}
+		b.proposeTemplate(ctx, message.Chat.ID, user, templateDraft{key: key, language: lang, body: body})
+	case "reset":
+		b.proposeTemplate(ctx, message.Chat.ID, user, templateDraft{key: key, language: lang})
+	case "history":
+		b.showTemplateHistory(ctx, message.Chat.ID, key, lang)
+	case "rollback":
+		if len(rest) == 0 {
+			b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "templates.usage"))
+			return
+		}
+		version, err := strconv.Atoi(rest[0])
+		if err != nil {
+			b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "templates.usage"))
+			return
+		}
+		b.proposeTemplateVersion(ctx, message.Chat.ID, user, key, lang, version)
+	default:
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "templates.usage"))
+	}
+}
+
+// sendTemplateList показывает шаблоны и на каких языках они изменены
+func (b *ChatBot) sendTemplateList(ctx context.Context, chatID int64) {
+	templates, err := b.templateService.GetAll(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get message templates", "error", err)
+		b.sendMessage(ctx, chatID, b.t(ctx, "common.error"))
+		return
+	}
+
+	custom := make(map[string]models.MessageTemplate, len(templates))
+	for _, tpl := range templates {
+		if tpl.Body != "" {
+			custom[tpl.Key+"/"+tpl.Language] = tpl
+		}
+	}
+
+	var text strings.Builder
+	text.WriteString(b.t(ctx, "templates.title") + "\n\n")
+	for _, key := range service.TemplateKeys {
+		fmt.Fprintf(&text, "• %s - %s\n", format.Code(key), b.t(ctx, "templates.names."+key))
+
+		statuses := make([]string, 0, len(b.i18n.Languages()))
+		for _, lang := range b.i18n.Languages() {
+			status := b.t(ctx, "templates.status_default")
+			if tpl, ok := custom[key+"/"+lang]; ok {
+				status = b.t(ctx, "templates.status_custom", tpl.Version)
+			}
+			statuses = append(statuses, lang+": "+status)
+		}
+		text.WriteString("  " + strings.Join(statuses, " · ") + "\n")
+	}
+	text.WriteString("\n" + b.t(ctx, "templates.usage"))
+
+	b.sendMessage(ctx, chatID, text.String())
+}
+
+// showTemplate показывает текущий текст шаблона и как его увидит клиент
+func (b *ChatBot) showTemplate(ctx context.Context, chatID int64, admin *models.User, key, lang string) {
+	tpl, err := b.templateService.Get(ctx, key, lang)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get message template", "error", err)
+		b.sendMessage(ctx, chatID, b.t(ctx, "common.error"))
+		return
+	}
+
+	body, status := b.defaultTemplateBody(key, b.i18n.Localizer(lang)), b.t(ctx, "templates.status_default")
+	if tpl != nil {
+		body, status = tpl.Body, b.t(ctx, "templates.status_custom", tpl.Version)
+	}
+
+	text := b.t(ctx, "templates.header", b.t(ctx, "templates.names."+key), lang, status) + "\n\n"
+	text += b.templatePreviewText(ctx, admin, key, body)
+	b.sendMessage(ctx, chatID, text)
+}
+
+// proposeTemplate показывает предпросмотр нового текста и ждет подтверждения
+func (b *ChatBot) proposeTemplate(ctx context.Context, chatID int64, admin *models.User, draft templateDraft) {
+	body := draft.body
+	if body == "" {
+		body = b.defaultTemplateBody(draft.key, b.i18n.Localizer(draft.language))
+	}
+
+	if _, err := b.templateService.Preview(draft.key, body, templatePreviewData(admin)); err != nil {
+		b.sendMessage(ctx, chatID, b.t(ctx, "templates.invalid", format.Escape(templateErrorText(err))))
+		return
+	}
+	b.templateDrafts.set(admin.TelegramID, draft)
+
+	text := b.t(ctx, "templates.header", b.t(ctx, "templates.names."+draft.key), draft.language, b.t(ctx, "templates.draft")) + "\n\n"
+	text += b.templatePreviewText(ctx, admin, draft.key, body) + "\n\n"
+	text += b.t(ctx, "templates.confirm")
+
+	msg := newMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "templates.save_button"), templateSaveData()),
+			tgbotapi.NewInlineKeyboardButtonData(b.t(ctx, "templates.cancel_button"), templateCancelData()),
+		),
+	)
+	b.send(ctx, msg, PriorityNormal)
+}
+
+// proposeTemplateVersion предлагает вернуть версию из истории: она сохранится новой версией
+func (b *ChatBot) proposeTemplateVersion(ctx context.Context, chatID int64, admin *models.User, key, lang string, version int) {
+	v, err := b.templateService.GetVersion(ctx, key, lang, version)
+	if err != nil {
+		b.sendMessage(ctx, chatID, b.t(ctx, "templates.version_not_found", version))
+		return
+	}
+	b.proposeTemplate(ctx, chatID, admin, templateDraft{key: key, language: lang, body: v.Body})
+}
+
+// showTemplateHistory показывает последние версии шаблона
+func (b *ChatBot) showTemplateHistory(ctx context.Context, chatID int64, key, lang string) {
+	versions, err := b.templateService.History(ctx, key, lang, templateHistoryLimit)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get template history", "error", err)
+		b.sendMessage(ctx, chatID, b.t(ctx, "common.error"))
+		return
+	}
+	if len(versions) == 0 {
+		b.sendMessage(ctx, chatID, b.t(ctx, "templates.history_empty"))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(b.t(ctx, "templates.history_title", b.t(ctx, "templates.names."+key), lang) + "\n\n")
+	for _, v := range versions {
+		snippet := b.t(ctx, "templates.default_text")
+		if v.Body != "" {
+			snippet = format.Escape(truncateRunes(v.Body, templateHistorySnippet))
+		}
+		text.WriteString(b.t(ctx, "templates.history_item", v.Version, v.CreatedAt.Format(b.t(ctx, "common.date_format")), b.formatUserName(&v.Author)) + "\n")
+		text.WriteString(snippet + "\n\n")
+	}
+	text.WriteString(b.t(ctx, "templates.rollback_hint", key, lang))
+
+	b.sendMessage(ctx, chatID, text.String())
+}
+
+// templatePreviewText показывает исходный текст шаблона с разметкой и как его увидит клиент
+func (b *ChatBot) templatePreviewText(ctx context.Context, admin *models.User, key, body string) string {
+	preview, err := b.templateService.Preview(key, body, templatePreviewData(admin))
+	if err != nil {
+		preview = b.t(ctx, "templates.invalid", format.Escape(templateErrorText(err)))
+	}
+	return b.t(ctx, "templates.body") + "\n" + format.Pre(body) + "\n" + b.t(ctx, "templates.preview") + "\n" + preview
+}
+
+// handleTemplateSaveCallback сохраняет черновик шаблона после предпросмотра
+func (b *ChatBot) handleTemplateSaveCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackArgs) {
+	draft, ok := b.templateDrafts.take(int64(query.From.ID))
+	if !ok {
+		b.answerCallbackQuery(query.ID, b.t(ctx, "templates.no_draft"))
+		return
+	}
+
+	admin, err := b.userService.GetUserByTelegramID(ctx, int64(query.From.ID))
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get admin user", "error", err)
+		b.answerCallbackQuery(query.ID, b.t(ctx, "common.error"))
+		return
+	}
+
+	tpl, err := b.templateService.Save(ctx, draft.key, draft.language, draft.body, admin.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to save message template", "key", draft.key, "error", err)
+		if errors.Is(err, service.ErrInvalidTemplate) {
+			b.answerCallbackQuery(query.ID, b.t(ctx, "templates.invalid", templateErrorText(err)))
+		} else {
+			b.answerCallbackQuery(query.ID, b.t(ctx, "templates.failed"))
+		}
+		return
+	}
+
+	logging.FromContext(ctx).Info("Message template saved", "key", tpl.Key, "language", tpl.Language, "version", tpl.Version)
+	b.removeKeyboard(ctx, query.Message)
+	b.sendMessage(ctx, query.Message.Chat.ID, b.t(ctx, "templates.saved", tpl.Version))
+	b.answerCallbackQuery(query.ID, "")
+}
+
+// handleTemplateCancelCallback отбрасывает черновик шаблона
+func (b *ChatBot) handleTemplateCancelCallback(ctx context.Context, query *tgbotapi.CallbackQuery, _ callbackArgs) {
+	b.templateDrafts.take(int64(query.From.ID))
+	b.removeKeyboard(ctx, query.Message)
+	b.answerCallbackQuery(query.ID, b.t(ctx, "templates.cancelled"))
+}
+
+// templatePreviewData - переменные для предпросмотра: админ в роли клиента и оператора
+func templatePreviewData(admin *models.User) service.TemplateData {
+	return service.NewReplyTemplateData(admin, templatePreviewChatID, admin)
+}
+
+// templateErrorText - ошибка шаблона для админа, без общего префикса
+func templateErrorText(err error) string {
+	return strings.TrimPrefix(err.Error(), service.ErrInvalidTemplate.Error()+": ")
+}
+
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/service"
+	"context"
+	"fmt"
+	"net/url"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleWebLoginCommand отправляет админу одноразовую ссылку для входа в веб-панель
+func (b *ChatBot) handleWebLoginCommand(ctx context.Context, message *tgbotapi.Message, user *models.User) {
+	// Ссылку нельзя показывать в группах - по ней войдет любой, кто успеет открыть
+	if !message.Chat.IsPrivate() {
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "common.private_only"))
+		return
+	}
+
+	token, err := b.authService.CreateLoginToken(ctx, user)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to create login token", "error", err)
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "web_login.failed"))
+		return
+	}
+
+	link := fmt.Sprintf("%s/auth/link?token=%s", b.config.Get().WebBaseURL, url.QueryEscape(token))
+
+	msg := newMessage(message.Chat.ID, b.t(ctx, "web_login.link", format.Escape(link), int(service.LoginTokenTTL.Minutes())))
+	msg.DisableWebPagePreview = true
+	b.send(ctx, msg, PriorityNormal)
+}
+
+// handleWebLogoutCommand отзывает все сессии админа в веб-панели
+func (b *ChatBot) handleWebLogoutCommand(ctx context.Context, message *tgbotapi.Message, user *models.User) {
+	if err := b.authService.RevokeAllSessions(ctx, user.ID); err != nil {
+		logging.FromContext(ctx).Error("Failed to revoke web sessions", "error", err)
+		b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "web_login.logout_failed"))
+		return
+	}
+
+	b.sendMessage(ctx, message.Chat.ID, b.t(ctx, "web_login.logout_done"))
+}
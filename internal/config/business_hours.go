@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BusinessHours - рабочее время поддержки. Без BUSINESS_HOURS_START и BUSINESS_HOURS_END поддержка работает круглосуточно.
+type BusinessHours struct {
+	location *time.Location
+	days     [7]bool // Индекс - time.Weekday
+	start    int     // Минуты от полуночи, -1 - не задано
+	end      int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Enabled - рабочее время задано
+func (h BusinessHours) Enabled() bool {
+	return h.location != nil && h.start >= 0 && h.end >= 0
+}
+
+// IsOpen - поддержка работает в момент t. Если конец раньше начала, смена переходит через полночь.
+func (h BusinessHours) IsOpen(t time.Time) bool {
+	if !h.Enabled() {
+		return true
+	}
+
+	local := t.In(h.location)
+	minute := local.Hour()*60 + local.Minute()
+	if h.start <= h.end {
+		return h.days[local.Weekday()] && minute >= h.start && minute < h.end
+	}
+
+	// Ночная смена: после начала - в рабочий день, до конца - на следующий день после рабочего
+	if minute >= h.start {
+		return h.days[local.Weekday()]
+	}
+	return minute < h.end && h.days[(local.Weekday()+6)%7]
+}
+
+// String описывает рабочее время для логов и текстов: "mon-fri 09:00-18:00 Europe/Moscow"
+func (h BusinessHours) String() string {
+	if !h.Enabled() {
+		return "24/7"
+	}
+
+	var days []string
+	for _, name := range []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"} {
+		if h.days[weekdays[name]] {
+			days = append(days, name)
+		}
+	}
+	return fmt.Sprintf("%s %s-%s %s", strings.Join(days, ","), formatClock(h.start), formatClock(h.end), h.location)
+}
+
+func parseBusinessTimezone(c *Config, value string) error {
+	location, err := time.LoadLocation(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("unknown timezone %q", value)
+	}
+	c.BusinessHours.location = location
+	return nil
+}
+
+func parseBusinessDays(c *Config, value string) error {
+	c.BusinessHours.days = [7]bool{}
+	for _, day := range strings.Split(value, ",") {
+		day = strings.ToLower(strings.TrimSpace(day))
+		weekday, ok := weekdays[day]
+		if !ok {
+			return fmt.Errorf("%q is not a day, expected mon, tue, wed, thu, fri, sat or sun", day)
+		}
+		c.BusinessHours.days[weekday] = true
+	}
+	return nil
+}
+
+// clock разбирает время ЧЧ:ММ в минуты от полуночи; пустое значение - не задано
+func clock(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			*field(c) = -1
+			return nil
+		}
+
+		hours, minutes, ok := strings.Cut(value, ":")
+		h, herr := strconv.Atoi(hours)
+		m, merr := strconv.Atoi(minutes)
+		if !ok || herr != nil || merr != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+			return fmt.Errorf("%q is not a time of day, expected HH:MM", value)
+		}
+		*field(c) = h*60 + m
+		return nil
+	}
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
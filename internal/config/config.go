@@ -1,9 +1,15 @@
+// Package config собирает настройки из нескольких слоев, каждый следующий перекрывает предыдущий:
+// значения по умолчанию, YAML-файл, файл .env, переменные окружения и флаги командной строки.
+//
+// У каждой настройки одно имя: DB_HOST в окружении, -db-host во флагах и db.host (или db_host) в файле.
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"os"
-	"strconv"
-	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -13,10 +19,26 @@ type Config struct {
 	TelegramWebhookURL string
 	ServerPort         string
 	WebhookSecret      string
-	AdminIDs           []int64
+	AdminIDs           []int64 // Меняется без перезапуска
 	Database           DatabaseConfig
 	Redis              RedisConfig
 	EnableWebAdmin     bool
+	WebBaseURL         string            // Внешний адрес веб-панели для ссылок входа из бота
+	WebSessionSecret   string            // Ключ подписи cookie сессий веб-панели
+	WebSessionTTL      time.Duration     // Срок жизни сессии веб-панели
+	SupportGroupID     int64             // Супергруппа с темами: если задана, каждый чат ведется в отдельной теме
+	APIKeys            map[string]string // Ключи публичного API: ключ → имя клиента
+	OpsPort            string            // Порт служебного сервера с /metrics, работает и без веб-панели
+	Log                LogConfig
+	ChatsPerPage       int           // Чатов на странице списков в админке бота
+	MessagesPerPage    int           // Сообщений на странице истории чата в боте
+	DefaultLanguage    string        // Язык пользователей, для которых язык не выбран и не подобран по Telegram
+	LocalesDir         string        // Каталог с переводами поверх встроенных (пусто - только встроенные)
+	Texts              Texts         // Меняются без перезапуска
+	BusinessHours      BusinessHours // Меняются без перезапуска
+
+	File string            // Файл конфигурации, из которого загружены настройки (пусто - без файла)
+	raw  map[string]string // Исходные строковые значения настроек, по ним при перезагрузке видно, что изменилось
 }
 
 type DatabaseConfig struct {
@@ -28,50 +50,115 @@ type DatabaseConfig struct {
 }
 
 type RedisConfig struct {
+	Enabled  bool // REDIS_HOST задан: только тогда Redis проверяется при готовности
 	Host     string
 	Port     string
 	Password string
 }
 
-func Load() (*Config, error) {
-	// Загружаем .env файл если он существует
-	_ = godotenv.Load()
-
-	adminIDsStr := os.Getenv("ADMIN_IDS")
-	var adminIDs []int64
-	if adminIDsStr != "" {
-		for _, idStr := range strings.Split(adminIDsStr, ",") {
-			if id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64); err == nil {
-				adminIDs = append(adminIDs, id)
-			}
+type LogConfig struct {
+	Level              string        // debug, info, warn, error
+	Format             string        // text или json
+	SQLLevel           string        // Уровень SQL-логов отдельно от общего: silent, error, warn, info
+	SlowQueryThreshold time.Duration // Запросы дольше пишутся в лог на уровне warn
+}
+
+// Texts - тексты для клиентов, заданные в конфигурации, в разметке HTML Telegram. Заданный текст
+// отправляется на всех языках вместо перевода, пустой - берется перевод на язык клиента.
+type Texts struct {
+	Welcome              string // Ответ на /start
+	MessageReceived      string // Подтверждение, что сообщение клиента получено
+	OutsideBusinessHours string // Подтверждение вне рабочего времени
+}
+
+// Load собирает конфигурацию из всех слоев и проверяет ее целиком: ошибки всех настроек возвращаются вместе.
+// args - аргументы командной строки без имени программы; файл задается флагом -config или CONFIG_FILE.
+func Load(args []string) (*Config, error) {
+	// .env читаем сами, а не через godotenv.Load: иначе при перезагрузке его значения
+	// уже были бы в окружении процесса и правки файла не применились бы
+	dotenv, _ := godotenv.Read()
+	getenv := func(name string) (string, string) {
+		if value := os.Getenv(name); value != "" {
+			return value, "environment"
+		}
+		return dotenv[name], ".env"
+	}
+	configFile, _ := getenv("CONFIG_FILE")
+
+	fs := flag.NewFlagSet("support-bot", flag.ContinueOnError)
+	file := fs.String("config", configFile, "YAML-файл конфигурации (CONFIG_FILE)")
+
+	// Флаги запоминаются и применяются последними, после файла и окружения
+	var flagValues []layerValue
+	for _, s := range settings {
+		name := s.name
+		fs.Func(s.flagName(), s.usage+" ("+name+")", func(value string) error {
+			flagValues = append(flagValues, layerValue{name: name, value: value, origin: "flag -" + s.flagName()})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]layerValue)
+	for _, s := range settings {
+		values[s.name] = layerValue{name: s.name, value: s.defaultValue, origin: "default"}
+	}
+
+	if *file != "" {
+		fileValues, err := readFile(*file)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range fileValues {
+			values[v.name] = v
+		}
+	}
+
+	for _, s := range settings {
+		// Пустая переменная окружения считается незаданной, как и раньше
+		if value, origin := getenv(s.name); value != "" {
+			values[s.name] = layerValue{name: s.name, value: value, origin: origin}
+		}
+	}
+
+	for _, v := range flagValues {
+		values[v.name] = v
+	}
+
+	cfg := &Config{File: *file, raw: make(map[string]string, len(settings))}
+	var errs []error
+	for _, s := range settings {
+		v := values[s.name]
+		cfg.raw[s.name] = v.value
+		if err := s.parse(cfg, v.value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s (from %s): %w", s.name, v.origin, err))
 		}
 	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
 
-	return &Config{
-		TelegramBotToken:   os.Getenv("TELEGRAM_BOT_TOKEN"),
-		TelegramWebhookURL: os.Getenv("TELEGRAM_WEBHOOK_URL"),
-		ServerPort:         getEnv("SERVER_PORT", "8080"),
-		WebhookSecret:      os.Getenv("WEBHOOK_SECRET"),
-		AdminIDs:           adminIDs,
-		EnableWebAdmin:     getEnv("ENABLE_WEB_ADMIN", "false") == "true",
-		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "6432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			Name:     getEnv("DB_NAME", "support_bot"),
-		},
-		Redis: RedisConfig{
-			Host:     getEnv("REDIS_HOST", "localhost"),
-			Port:     getEnv("REDIS_PORT", "6379"),
-			Password: getEnv("REDIS_PASSWORD", ""),
-		},
-	}, nil
+	cfg.applyDerived()
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// applyDerived заполняет значения, которые зависят от других настроек
+func (c *Config) applyDerived() {
+	if c.WebBaseURL == "" {
+		c.WebBaseURL = "http://localhost:" + c.ServerPort
 	}
-	return defaultValue
+	c.Redis.Enabled = c.Redis.Host != ""
+}
+
+// layerValue - строковое значение настройки и слой, из которого оно пришло
+type layerValue struct {
+	name   string
+	value  string
+	origin string
 }
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readFile читает YAML-файл конфигурации. Вложенные ключи склеиваются через "_" в имя настройки:
+//
+//	db:
+//	  host: postgres     # DB_HOST
+//	admin_ids: [1, 2]    # ADMIN_IDS, списки склеиваются через запятую
+//	api_keys:
+//	  backend: secret    # API_KEYS=backend:secret
+//
+// Неизвестный ключ - ошибка, чтобы опечатка в имени не оставляла настройку со значением по умолчанию.
+func readFile(path string) ([]layerValue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	var values []layerValue
+	if err := flattenNode(path, "", "", doc.Content[0], &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flattenNode(path, prefix, keyPath string, node *yaml.Node, values *[]layerValue) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s:%d: expected a mapping of settings", path, node.Line)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolveAlias(node.Content[i+1])
+		name := strings.ToUpper(strings.ReplaceAll(key.Value, "-", "_"))
+		fullKey := key.Value
+		if prefix != "" {
+			name = prefix + "_" + name
+			fullKey = keyPath + "." + fullKey
+		}
+
+		if !knownSetting(name) {
+			if value.Kind == yaml.MappingNode {
+				if err := flattenNode(path, name, fullKey, value, values); err != nil {
+					return err
+				}
+				continue
+			}
+			return fmt.Errorf("%s:%d: unknown setting %q", path, key.Line, fullKey)
+		}
+
+		str, err := nodeString(value)
+		if err != nil {
+			return fmt.Errorf("%s:%d: invalid %s: %w", path, key.Line, name, err)
+		}
+		*values = append(*values, layerValue{name: name, value: str, origin: fmt.Sprintf("%s:%d", path, key.Line)})
+	}
+
+	return nil
+}
+
+// nodeString переводит значение из файла в ту же строку, что задавалась бы в окружении
+func nodeString(node *yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!null" {
+			return "", nil
+		}
+		return node.Value, nil
+
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			item = resolveAlias(item)
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("list items must be plain values")
+			}
+			items = append(items, item.Value)
+		}
+		return strings.Join(items, ","), nil
+
+	case yaml.MappingNode:
+		pairs := make([]string, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value := resolveAlias(node.Content[i+1])
+			if value.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("mapping values must be plain values")
+			}
+			pairs = append(pairs, node.Content[i].Value+":"+value.Value)
+		}
+		return strings.Join(pairs, ","), nil
+	}
+
+	return "", fmt.Errorf("unsupported value")
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+func knownSetting(name string) bool {
+	for _, s := range settings {
+		if s.name == name {
+			return true
+		}
+	}
+	return false
+}
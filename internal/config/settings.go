@@ -0,0 +1,244 @@
+package config
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting - одна настройка: имя переменной окружения, значение по умолчанию и разбор строки в поле Config
+type setting struct {
+	name         string
+	defaultValue string
+	usage        string
+	reloadable   bool // Применяется по SIGHUP без перезапуска
+	parse        func(cfg *Config, value string) error
+}
+
+// flagName - имя флага: DB_HOST → db-host
+func (s setting) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(s.name), "_", "-")
+}
+
+var settings = []setting{
+	{name: "TELEGRAM_BOT_TOKEN", usage: "токен бота от @BotFather", parse: str(func(c *Config) *string { return &c.TelegramBotToken })},
+	{name: "TELEGRAM_WEBHOOK_URL", usage: "адрес вебхука Telegram", parse: str(func(c *Config) *string { return &c.TelegramWebhookURL })},
+	{name: "WEBHOOK_SECRET", usage: "секрет вебхука Telegram", parse: str(func(c *Config) *string { return &c.WebhookSecret })},
+	{name: "ADMIN_IDS", usage: "Telegram ID админов через запятую", reloadable: true, parse: parseAdminIDs},
+	{name: "SUPPORT_GROUP_ID", usage: "ID супергруппы с темами", parse: parseSupportGroupID},
+
+	{name: "SERVER_PORT", defaultValue: "8080", usage: "порт веб-сервера", parse: port(func(c *Config) *string { return &c.ServerPort })},
+	{name: "ENABLE_WEB_ADMIN", defaultValue: "false", usage: "включить веб-панель", parse: boolean(func(c *Config) *bool { return &c.EnableWebAdmin })},
+	{name: "WEB_BASE_URL", usage: "внешний адрес веб-панели (по умолчанию http://localhost:SERVER_PORT)", parse: parseWebBaseURL},
+	{name: "WEB_SESSION_SECRET", usage: "ключ подписи cookie сессий", parse: str(func(c *Config) *string { return &c.WebSessionSecret })},
+	{name: "WEB_SESSION_TTL", defaultValue: "24h", usage: "срок жизни сессии веб-панели", parse: positiveDuration(func(c *Config) *time.Duration { return &c.WebSessionTTL })},
+	{name: "API_KEYS", usage: "ключи публичного API: имя:ключ через запятую", parse: parseAPIKeysSetting},
+	{name: "OPS_PORT", defaultValue: "9090", usage: "порт служебного сервера (/metrics, /healthz, /readyz)", parse: port(func(c *Config) *string { return &c.OpsPort })},
+
+	{name: "DB_HOST", defaultValue: "localhost", usage: "хост PostgreSQL", parse: required(func(c *Config) *string { return &c.Database.Host })},
+	{name: "DB_PORT", defaultValue: "6432", usage: "порт PostgreSQL", parse: port(func(c *Config) *string { return &c.Database.Port })},
+	{name: "DB_USER", defaultValue: "postgres", usage: "пользователь PostgreSQL", parse: required(func(c *Config) *string { return &c.Database.User })},
+	{name: "DB_PASSWORD", defaultValue: "postgres", usage: "пароль PostgreSQL", parse: str(func(c *Config) *string { return &c.Database.Password })},
+	{name: "DB_NAME", defaultValue: "support_bot", usage: "имя базы PostgreSQL", parse: required(func(c *Config) *string { return &c.Database.Name })},
+
+	{name: "REDIS_HOST", usage: "хост Redis для проверки готовности", parse: str(func(c *Config) *string { return &c.Redis.Host })},
+	{name: "REDIS_PORT", defaultValue: "6379", usage: "порт Redis", parse: port(func(c *Config) *string { return &c.Redis.Port })},
+	{name: "REDIS_PASSWORD", usage: "пароль Redis", parse: str(func(c *Config) *string { return &c.Redis.Password })},
+
+	{name: "LOG_LEVEL", defaultValue: "info", usage: "уровень логов: debug, info, warn, error", parse: oneOf(func(c *Config) *string { return &c.Log.Level }, "debug", "info", "warn", "error")},
+	{name: "LOG_FORMAT", defaultValue: "text", usage: "формат логов: text, json", parse: oneOf(func(c *Config) *string { return &c.Log.Format }, "text", "json")},
+	{name: "DB_LOG_LEVEL", defaultValue: "warn", usage: "уровень SQL-логов: silent, error, warn, info", parse: oneOf(func(c *Config) *string { return &c.Log.SQLLevel }, "silent", "error", "warn", "info")},
+	{name: "DB_SLOW_QUERY_THRESHOLD", defaultValue: "200ms", usage: "порог медленного SQL-запроса", parse: positiveDuration(func(c *Config) *time.Duration { return &c.Log.SlowQueryThreshold })},
+
+	{name: "CHATS_PER_PAGE", defaultValue: "5", usage: "чатов на странице списков в боте", parse: intRange(func(c *Config) *int { return &c.ChatsPerPage }, 1, 20)},
+	{name: "MESSAGES_PER_PAGE", defaultValue: "10", usage: "сообщений на странице истории чата в боте", parse: intRange(func(c *Config) *int { return &c.MessagesPerPage }, 1, 50)},
+
+	{name: "DEFAULT_LANGUAGE", defaultValue: "ru", usage: "язык бота для пользователей без выбранного языка", parse: language(func(c *Config) *string { return &c.DefaultLanguage })},
+	{name: "LOCALES_DIR", usage: "каталог с переводами <язык>.yaml поверх встроенных", parse: str(func(c *Config) *string { return &c.LocalesDir })},
+
+	{name: "TEXT_WELCOME", usage: "ответ на /start вместо перевода", reloadable: true, parse: markup(func(c *Config) *string { return &c.Texts.Welcome })},
+	{name: "TEXT_MESSAGE_RECEIVED", usage: "подтверждение получения сообщения вместо перевода", reloadable: true, parse: markup(func(c *Config) *string { return &c.Texts.MessageReceived })},
+	{name: "TEXT_OUTSIDE_BUSINESS_HOURS", usage: "подтверждение вне рабочего времени вместо перевода", reloadable: true, parse: markup(func(c *Config) *string { return &c.Texts.OutsideBusinessHours })},
+
+	{name: "BUSINESS_HOURS_TIMEZONE", defaultValue: "UTC", usage: "часовой пояс рабочего времени, например Europe/Moscow", reloadable: true, parse: parseBusinessTimezone},
+	{name: "BUSINESS_HOURS_DAYS", defaultValue: "mon,tue,wed,thu,fri", usage: "рабочие дни через запятую: mon, tue, wed, thu, fri, sat, sun", reloadable: true, parse: parseBusinessDays},
+	{name: "BUSINESS_HOURS_START", usage: "начало рабочего дня ЧЧ:ММ (пусто - поддержка работает круглосуточно)", reloadable: true, parse: clock(func(c *Config) *int { return &c.BusinessHours.start })},
+	{name: "BUSINESS_HOURS_END", usage: "конец рабочего дня ЧЧ:ММ", reloadable: true, parse: clock(func(c *Config) *int { return &c.BusinessHours.end })},
+}
+
+func str(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+// markup - текст в разметке HTML Telegram: ошибка в ней видна при запуске, а не когда Telegram отклонит сообщение
+func markup(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		if err := format.Validate(value); err != nil {
+			return err
+		}
+		*field(c) = value
+		return nil
+	}
+}
+
+func required(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("must not be empty")
+		}
+		*field(c) = value
+		return nil
+	}
+}
+
+// language - код языка вида ru или pt-br; есть ли для него перевод, проверяется при загрузке каталогов
+func language(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if len(value) < 2 || len(value) > 8 || strings.Trim(value, "abcdefghijklmnopqrstuvwxyz-") != "" {
+			return fmt.Errorf("%q is not a language code like ru or en", value)
+		}
+		*field(c) = value
+		return nil
+	}
+}
+
+func oneOf(field func(*Config) *string, allowed ...string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		value = strings.ToLower(strings.TrimSpace(value))
+		for _, a := range allowed {
+			if value == a {
+				*field(c) = value
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+func port(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		value = strings.TrimSpace(value)
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("%q is not a port number", value)
+		}
+		*field(c) = value
+		return nil
+	}
+}
+
+func boolean(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func intRange(field func(*Config) *int, min, max int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < min || n > max {
+			return fmt.Errorf("%q is not a number from %d to %d", value, min, max)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func positiveDuration(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		if d <= 0 {
+			return fmt.Errorf("must be positive")
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+// parseAdminIDs не пропускает молча опечатки: каждый ID должен быть положительным числом
+func parseAdminIDs(c *Config, value string) error {
+	c.AdminIDs = nil
+	for _, idStr := range strings.Split(value, ",") {
+		idStr = strings.TrimSpace(idStr)
+		if idStr == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("%q is not a Telegram user ID", idStr)
+		}
+		c.AdminIDs = append(c.AdminIDs, id)
+	}
+	return nil
+}
+
+func parseSupportGroupID(c *Config, value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		c.SupportGroupID = 0
+		return nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return err
+	}
+	// ID супергрупп в Bot API отрицательные: -100...
+	if id >= 0 {
+		return fmt.Errorf("%d is not a supergroup ID, expected a negative number like -1001234567890", id)
+	}
+	c.SupportGroupID = id
+	return nil
+}
+
+func parseWebBaseURL(c *Config, value string) error {
+	value = strings.TrimRight(strings.TrimSpace(value), "/")
+	if value != "" && !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+		return fmt.Errorf("%q must start with http:// or https://", value)
+	}
+	c.WebBaseURL = value
+	return nil
+}
+
+func parseAPIKeysSetting(c *Config, value string) error {
+	keys, err := parseAPIKeys(value)
+	if err != nil {
+		return err
+	}
+	c.APIKeys = keys
+	return nil
+}
+
+// parseAPIKeys разбирает список "имя:ключ,имя:ключ"
+func parseAPIKeys(value string) (map[string]string, error) {
+	keys := make(map[string]string)
+	if value == "" {
+		return keys, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || name == "" || key == "" {
+			return nil, fmt.Errorf("expected name:key, got %q", pair)
+		}
+		if _, exists := keys[key]; exists {
+			return nil, fmt.Errorf("duplicate key for %q", name)
+		}
+		keys[key] = name
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import "sync/atomic"
+
+// Store хранит текущую конфигурацию и подменяет ее при перезагрузке.
+// Читатели берут снимок через Get и не видят полуобновленных значений.
+type Store struct {
+	current atomic.Pointer[Config]
+}
+
+func NewStore(cfg *Config) *Store {
+	s := &Store{}
+	s.current.Store(cfg)
+	return s
+}
+
+// Get возвращает текущую конфигурацию; ее нельзя изменять
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Reload применяет из next настройки, которые безопасно менять на ходу: админов, тексты и рабочее время.
+// Возвращает имена измененных настроек, которые вступят в силу только после перезапуска.
+func (s *Store) Reload(next *Config) (restartRequired []string) {
+	current := s.Get()
+
+	merged := *current
+	merged.AdminIDs = next.AdminIDs
+	merged.Texts = next.Texts
+	merged.BusinessHours = next.BusinessHours
+	merged.raw = make(map[string]string, len(current.raw))
+
+	for _, setting := range settings {
+		merged.raw[setting.name] = current.raw[setting.name]
+		if next.raw[setting.name] == current.raw[setting.name] {
+			continue
+		}
+		if setting.reloadable {
+			merged.raw[setting.name] = next.raw[setting.name]
+		} else {
+			restartRequired = append(restartRequired, setting.name)
+		}
+	}
+
+	s.current.Store(&merged)
+	return restartRequired
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// validate проверяет связи между настройками; каждая настройка по отдельности уже проверена при разборе
+func (c *Config) validate() error {
+	var errs []error
+
+	if c.TelegramBotToken == "" {
+		errs = append(errs, errors.New("TELEGRAM_BOT_TOKEN is required"))
+	}
+	if len(c.AdminIDs) == 0 {
+		errs = append(errs, errors.New("ADMIN_IDS is required: without admins nobody can answer clients"))
+	}
+	if c.OpsPort == c.ServerPort && (c.EnableWebAdmin || len(c.APIKeys) > 0) {
+		errs = append(errs, fmt.Errorf("OPS_PORT and SERVER_PORT must differ, both are %s", c.OpsPort))
+	}
+
+	hours := c.BusinessHours
+	switch {
+	case (hours.start < 0) != (hours.end < 0):
+		errs = append(errs, errors.New("BUSINESS_HOURS_START and BUSINESS_HOURS_END must be set together"))
+	case hours.Enabled() && hours.start == hours.end:
+		errs = append(errs, errors.New("BUSINESS_HOURS_START and BUSINESS_HOURS_END must differ"))
+	}
+
+	return errors.Join(errs...)
+}
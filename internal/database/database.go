@@ -2,14 +2,14 @@ package database
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
 	"ai_support_tg_writer_bot/internal/config"
+	"ai_support_tg_writer_bot/internal/logging"
 	"ai_support_tg_writer_bot/internal/models"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 func Connect(cfg *config.Config) (*gorm.DB, error) {
@@ -21,23 +21,42 @@ func Connect(cfg *config.Config) (*gorm.DB, error) {
 		cfg.Database.Port,
 	)
 
+	sqlLogger, err := logging.NewGormLogger(cfg.Log.SQLLevel, cfg.Log.SlowQueryThreshold)
+	if err != nil {
+		return nil, err
+	}
+
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: sqlLogger,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := registerMetrics(db); err != nil {
+		return nil, err
+	}
+
 	// Автомиграция
 	if err := db.AutoMigrate(
 		&models.User{},
 		&models.Chat{},
 		&models.ChatMessage{},
+		&models.ChatMessageRevision{},
+		&models.MessageMapping{},
+		&models.DeliveryJob{},
 		&models.File{},
+		&models.AdminSession{},
+		&models.LoginToken{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.IdempotencyKey{},
+		&models.MessageTemplate{},
+		&models.MessageTemplateVersion{},
 	); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
-	log.Println("Database connected and migrated successfully")
+	slog.Info("Database connected and migrated successfully")
 	return db, nil
 }
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"ai_support_tg_writer_bot/internal/metrics"
+
+	"gorm.io/gorm"
+)
+
+const metricsStartKey = "metrics:start"
+
+// registerMetrics замеряет каждый запрос всех репозиториев через колбэки gorm
+func registerMetrics(db *gorm.DB) error {
+	callbacks := db.Callback()
+	err := errors.Join(
+		callbacks.Create().Before("*").Register("metrics:before_create", startTimer),
+		callbacks.Create().After("*").Register("metrics:after_create", observeQuery("create")),
+		callbacks.Query().Before("*").Register("metrics:before_query", startTimer),
+		callbacks.Query().After("*").Register("metrics:after_query", observeQuery("query")),
+		callbacks.Update().Before("*").Register("metrics:before_update", startTimer),
+		callbacks.Update().After("*").Register("metrics:after_update", observeQuery("update")),
+		callbacks.Delete().Before("*").Register("metrics:before_delete", startTimer),
+		callbacks.Delete().After("*").Register("metrics:after_delete", observeQuery("delete")),
+		callbacks.Row().Before("*").Register("metrics:before_row", startTimer),
+		callbacks.Row().After("*").Register("metrics:after_row", observeQuery("row")),
+		callbacks.Raw().Before("*").Register("metrics:before_raw", startTimer),
+		callbacks.Raw().After("*").Register("metrics:after_raw", observeQuery("raw")),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register metrics callbacks: %w", err)
+	}
+	return nil
+}
+
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+func observeQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "raw"
+		}
+
+		metrics.DBQueryDuration.Observe(metrics.Since(start), operation, table)
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			metrics.DBErrorsTotal.Inc(operation, table)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package dto переводит модели базы данных в типы публичного API из pkg/api
+package dto
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/pkg/api"
+	"strings"
+)
+
+func User(user *models.User) api.User {
+	return api.User{
+		ID:         user.ID,
+		TelegramID: user.TelegramID,
+		Username:   user.Username,
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		IsAdmin:    user.IsAdmin,
+		Language:   user.Language,
+	}
+}
+
+func Chat(chat *models.Chat) api.Chat {
+	return api.Chat{
+		ID:            chat.ID,
+		Status:        string(chat.Status),
+		User:          User(&chat.User),
+		UnreadCount:   chat.UnreadCount,
+		TopicID:       chat.TopicID,
+		LastMessageAt: chat.LastMessageAt,
+		CreatedAt:     chat.CreatedAt,
+		UpdatedAt:     chat.UpdatedAt,
+	}
+}
+
+func Chats(chats []models.Chat) []api.Chat {
+	result := make([]api.Chat, 0, len(chats))
+	for i := range chats {
+		result = append(result, Chat(&chats[i]))
+	}
+	return result
+}
+
+func File(file *models.File) api.File {
+	return api.File{
+		ID:       file.ID,
+		FileID:   file.FileID,
+		FileName: file.FileName,
+		FileType: file.FileType,
+		FileSize: file.FileSize,
+	}
+}
+
+func Message(message *models.ChatMessage) api.Message {
+	result := api.Message{
+		ID:             message.ID,
+		ChatID:         message.ChatID,
+		UserID:         message.UserID,
+		Content:        message.Content,
+		IsFromUser:     message.IsFromUser,
+		IsRead:         message.IsRead,
+		DeliveryStatus: string(message.DeliveryStatus),
+		DeliveryError:  message.DeliveryError,
+		EditedAt:       message.EditedAt,
+		CreatedAt:      message.CreatedAt,
+		Files:          make([]api.File, 0, len(message.Files)),
+	}
+
+	// Автор загружен не во всех запросах
+	if message.User.ID != 0 {
+		user := User(&message.User)
+		result.User = &user
+	}
+
+	for i := range message.Files {
+		result.Files = append(result.Files, File(&message.Files[i]))
+	}
+
+	return result
+}
+
+func Messages(messages []models.ChatMessage) []api.Message {
+	result := make([]api.Message, 0, len(messages))
+	for i := range messages {
+		result = append(result, Message(&messages[i]))
+	}
+	return result
+}
+
+func Webhook(webhook *models.Webhook) api.Webhook {
+	events := []string{}
+	if webhook.Events != "" {
+		events = strings.Split(webhook.Events, ",")
+	}
+
+	return api.Webhook{
+		ID:          webhook.ID,
+		URL:         webhook.URL,
+		Events:      events,
+		Secret:      webhook.Secret,
+		Description: webhook.Description,
+		IsActive:    webhook.IsActive,
+		CreatedAt:   webhook.CreatedAt,
+		UpdatedAt:   webhook.UpdatedAt,
+	}
+}
+
+func Webhooks(webhooks []models.Webhook) []api.Webhook {
+	result := make([]api.Webhook, 0, len(webhooks))
+	for i := range webhooks {
+		result = append(result, Webhook(&webhooks[i]))
+	}
+	return result
+}
+
+func WebhookDelivery(delivery *models.WebhookDelivery) api.WebhookDelivery {
+	return api.WebhookDelivery{
+		ID:            delivery.ID,
+		WebhookID:     delivery.WebhookID,
+		Event:         delivery.Event,
+		Payload:       delivery.Payload,
+		Status:        string(delivery.Status),
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		ResponseCode:  delivery.ResponseCode,
+		ResponseBody:  delivery.ResponseBody,
+		LastError:     delivery.LastError,
+		DeliveredAt:   delivery.DeliveredAt,
+		CreatedAt:     delivery.CreatedAt,
+	}
+}
+
+func WebhookDeliveries(deliveries []models.WebhookDelivery) []api.WebhookDelivery {
+	result := make([]api.WebhookDelivery, 0, len(deliveries))
+	for i := range deliveries {
+		result = append(result, WebhookDelivery(&deliveries[i]))
+	}
+	return result
+}
+
+func Analytics(report *models.AnalyticsReport) api.Analytics {
+	daily := make([]api.DailyActivity, 0, len(report.Daily))
+	for _, day := range report.Daily {
+		daily = append(daily, api.DailyActivity{
+			Day:             day.Day.Format("2006-01-02"),
+			NewChats:        day.NewChats,
+			ClientMessages:  day.ClientMessages,
+			SupportMessages: day.SupportMessages,
+		})
+	}
+
+	operators := make([]api.OperatorStats, 0, len(report.Operators))
+	for _, operator := range report.Operators {
+		operators = append(operators, api.OperatorStats{
+			User: api.User{
+				ID:        operator.UserID,
+				Username:  operator.Username,
+				FirstName: operator.FirstName,
+				LastName:  operator.LastName,
+			},
+			Chats:    operator.Chats,
+			Messages: operator.Messages,
+		})
+	}
+
+	hours := make([]api.HourlyCount, 0, len(report.BusiestHours))
+	for _, hour := range report.BusiestHours {
+		hours = append(hours, api.HourlyCount{Hour: hour.Hour, Messages: hour.Messages})
+	}
+
+	return api.Analytics{
+		From:          report.From,
+		To:            report.To,
+		ActiveChats:   report.ActiveChats,
+		ArchivedChats: report.ArchivedChats,
+		Daily:         daily,
+		FirstResponse: api.ResponseTime{
+			Answered:       report.FirstResponse.Answered,
+			Unanswered:     report.FirstResponse.Unanswered,
+			MedianSeconds:  report.FirstResponse.MedianSeconds,
+			AverageSeconds: report.FirstResponse.AverageSeconds,
+		},
+		Operators:    operators,
+		BusiestHours: hours,
+		Backlog: api.Backlog{
+			Chats:          report.Backlog.Chats,
+			Messages:       report.Backlog.Messages,
+			OldestUnreadAt: report.Backlog.OldestUnreadAt,
+		},
+	}
+}
@@ -0,0 +1,219 @@
+package events
+
+import (
+	"ai_support_tg_writer_bot/internal/logging"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Mode определяет, как подписчик получает события
+type Mode int
+
+const (
+	// Sync - обработчик вызывается в горутине публикующего, Publish ждет его завершения
+	Sync Mode = iota
+	// Async - у подписчика своя очередь и горутина, события обрабатываются по порядку.
+	// Publish ждет, только когда очередь заполнена.
+	Async
+)
+
+const (
+	// Размер очереди асинхронного подписчика
+	asyncQueueSize = 256
+	// Сколько Publish ждет места в заполненной очереди. Только если подписчик не разобрал ее и за это время,
+	// событие для него отбрасывается: иначе один зависший подписчик остановил бы всех, кто публикует события.
+	asyncPublishTimeout = 5 * time.Second
+)
+
+// Handler получает событие вместе с контекстом публикации: в нем логгер с полями исходного запроса
+type Handler func(ctx context.Context, event Event)
+
+type subscription struct {
+	id      int
+	name    string
+	mode    Mode
+	events  map[Name]bool // nil - все события
+	handler Handler
+	queue   chan envelope
+
+	// Отписка закрывает очередь под записью, отправка идет под чтением: так в закрытую очередь
+	// ничего не отправить, а ожидание места в очереди не держит блокировку всей шины
+	queueMu sync.RWMutex
+	closed  bool
+}
+
+type envelope struct {
+	ctx   context.Context
+	event Event
+}
+
+// Bus - типизированная внутрипроцессная шина событий чатов.
+// Паника в одном подписчике перехватывается и не мешает остальным.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []*subscription
+	nextID        int
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe подписывает обработчик на перечисленные события (на все, если не указаны).
+// name используется в логах. Возвращает функцию отписки.
+func (b *Bus) Subscribe(name string, mode Mode, handler Handler, names ...Name) func() {
+	sub := &subscription{
+		name:    name,
+		mode:    mode,
+		handler: handler,
+	}
+	if len(names) > 0 {
+		sub.events = make(map[Name]bool, len(names))
+		for _, n := range names {
+			sub.events[n] = true
+		}
+	}
+	if mode == Async {
+		sub.queue = make(chan envelope, asyncQueueSize)
+		go sub.run()
+	}
+
+	b.mu.Lock()
+	sub.id = b.nextID
+	b.nextID++
+	b.subscriptions = append(b.subscriptions, sub)
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.unsubscribe(sub) })
+	}
+}
+
+// On подписывает типизированный обработчик на один вид событий
+func On[T Event](b *Bus, name string, mode Mode, handler func(context.Context, T)) func() {
+	var zero T
+	return b.Subscribe(name, mode, func(ctx context.Context, event Event) {
+		if e, ok := event.(T); ok {
+			handler(ctx, e)
+		}
+	}, zero.EventName())
+}
+
+type occurredAtKey struct{}
+
+// OccurredAt возвращает время публикации события, которое обрабатывает подписчик.
+// Асинхронный подписчик получает событие позже, поэтому время берется из контекста, а не из часов.
+func OccurredAt(ctx context.Context) time.Time {
+	if at, ok := ctx.Value(occurredAtKey{}).(time.Time); ok {
+		return at
+	}
+	return time.Now()
+}
+
+// Publish передает событие подписчикам: асинхронным - в их очереди, синхронным - вызывает по порядку.
+// Асинхронные подписчики получают контекст без отмены: они работают дольше запроса, который опубликовал событие.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	name := event.EventName()
+	ctx = context.WithValue(ctx, occurredAtKey{}, time.Now())
+	asyncCtx := context.WithoutCancel(ctx)
+	var syncSubs, asyncSubs []*subscription
+
+	b.mu.RLock()
+	for _, sub := range b.subscriptions {
+		if sub.events != nil && !sub.events[name] {
+			continue
+		}
+
+		if sub.mode == Sync {
+			syncSubs = append(syncSubs, sub)
+		} else {
+			asyncSubs = append(asyncSubs, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	// Подписчики получают событие без блокировки шины: ожидание места в очереди не должно мешать
+	// подпискам и отпискам, а синхронные обработчики могут сами публиковать события
+	for _, sub := range asyncSubs {
+		if !sub.enqueue(envelope{ctx: asyncCtx, event: event}) {
+			logging.FromContext(ctx).Error("Event subscriber is stuck, event dropped", "subscriber", sub.name, "event", name,
+				"timeout", asyncPublishTimeout)
+		}
+	}
+	for _, sub := range syncSubs {
+		sub.handle(ctx, event)
+	}
+}
+
+func (b *Bus) unsubscribe(sub *subscription) {
+	b.mu.Lock()
+	for i, s := range b.subscriptions {
+		if s.id == sub.id {
+			b.subscriptions = append(b.subscriptions[:i], b.subscriptions[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+
+	if sub.queue != nil {
+		sub.close()
+	}
+}
+
+// close закрывает очередь асинхронного подписчика, дождавшись начатых отправок в нее
+func (s *subscription) close() {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	s.closed = true
+	close(s.queue)
+}
+
+// enqueue ставит событие в очередь подписчика, дожидаясь места не дольше asyncPublishTimeout.
+// false - место так и не появилось; событие для отписавшегося подписчика просто пропускается.
+func (s *subscription) enqueue(e envelope) bool {
+	s.queueMu.RLock()
+	defer s.queueMu.RUnlock()
+
+	if s.closed {
+		return true
+	}
+
+	select {
+	case s.queue <- e:
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(asyncPublishTimeout)
+	defer timer.Stop()
+
+	select {
+	case s.queue <- e:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (s *subscription) run() {
+	for e := range s.queue {
+		s.handle(e.ctx, e.event)
+	}
+}
+
+// handle вызывает обработчик, не давая его панике выйти за пределы подписчика
+func (s *subscription) handle(ctx context.Context, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.FromContext(ctx).Error("Event subscriber panicked", "subscriber", s.name, "event", event.EventName(),
+				"panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+		}
+	}()
+
+	s.handler(ctx, event)
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+)
+
+type Name string
+
+const (
+	ChatCreatedEvent     Name = "chat.created"     // Клиент начал новый чат
+	MessageReceivedEvent Name = "message.received" // Клиент написал в чат
+	ReplySentEvent       Name = "reply.sent"       // Поддержка ответила в чат
+	MessageEditedEvent   Name = "message.edited"   // Сообщение чата отредактировано в Telegram
+	ChatArchivedEvent    Name = "chat.archived"    // Чат архивирован
+	ChatReadEvent        Name = "chat.read"        // Чат помечен прочитанным
+)
+
+// Event - событие жизненного цикла чата.
+// Чаты и сообщения передаются копиями на момент публикации: асинхронные подписчики читают их
+// уже после того, как публикующий продолжил менять свои модели (например, статус доставки ответа).
+type Event interface {
+	EventName() Name
+}
+
+type ChatCreated struct {
+	Chat models.Chat
+}
+
+type MessageReceived struct {
+	Message models.ChatMessage  // Сообщение клиента вместе с файлами
+	Quoted  *models.ChatMessage // Сообщение, на которое клиент ответил через "Ответить", если есть. Только для чтения.
+}
+
+type ReplySent struct {
+	Message models.ChatMessage // Ответ поддержки вместе с файлами. Доставка клиенту идет отдельно.
+}
+
+type MessageEdited struct {
+	Message    models.ChatMessage // Сообщение с новым текстом, автором и файлами
+	OldContent string             // Текст до правки
+}
+
+type ChatArchived struct {
+	ChatID uint
+}
+
+type ChatRead struct {
+	ChatID uint
+}
+
+func (ChatCreated) EventName() Name     { return ChatCreatedEvent }
+func (MessageReceived) EventName() Name { return MessageReceivedEvent }
+func (ReplySent) EventName() Name       { return ReplySentEvent }
+func (MessageEdited) EventName() Name   { return MessageEditedEvent }
+func (ChatArchived) EventName() Name    { return ChatArchivedEvent }
+func (ChatRead) EventName() Name        { return ChatReadEvent }
+
+// ChatIDOf возвращает ID чата, к которому относится событие
+func ChatIDOf(event Event) uint {
+	switch e := event.(type) {
+	case ChatCreated:
+		return e.Chat.ID
+	case MessageReceived:
+		return e.Message.ChatID
+	case ReplySent:
+		return e.Message.ChatID
+	case MessageEdited:
+		return e.Message.ChatID
+	case ChatArchived:
+		return e.ChatID
+	case ChatRead:
+		return e.ChatID
+	default:
+		return 0
+	}
+}
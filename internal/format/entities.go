@@ -0,0 +1,115 @@
+package format
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// FromEntities переводит текст сообщения с entities в разметку, чтобы переслать его с тем же оформлением:
+// так оператор пишет ответ клиенту жирным, ссылками и блоками кода прямо в Telegram.
+// Смещения entities считаются в UTF-16, как в Bot API. Упоминания, ссылки и хэштеги
+// без явного оформления Telegram распознает в тексте сам, поэтому они остаются текстом.
+func FromEntities(text string, entities []tgbotapi.MessageEntity) string {
+	if len(entities) == 0 {
+		return Escape(text)
+	}
+
+	// Внешние entities открываются раньше вложенных: по смещению, при равном - сначала длинные
+	sorted := make([]tgbotapi.MessageEntity, len(entities))
+	copy(sorted, entities)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Offset != sorted[j].Offset {
+			return sorted[i].Offset < sorted[j].Offset
+		}
+		return sorted[i].Length > sorted[j].Length
+	})
+
+	type span struct {
+		end      int
+		close    string
+		verbatim bool // code и pre: вложенное оформление внутри них Telegram не показывает
+	}
+
+	var out strings.Builder
+	var stack []span
+	next := 0
+	pos := 0
+
+	for _, r := range text {
+		for len(stack) > 0 && stack[len(stack)-1].end <= pos {
+			out.WriteString(stack[len(stack)-1].close)
+			stack = stack[:len(stack)-1]
+		}
+
+		for next < len(sorted) && sorted[next].Offset <= pos {
+			e := sorted[next]
+			next++
+
+			open, closeTag := entityTags(e)
+			end := e.Offset + e.Length
+			if open == "" || end <= pos {
+				continue
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				if parent.verbatim {
+					continue
+				}
+				// Пересекающиеся entities Telegram не присылает, но разметка должна остаться вложенной
+				if end > parent.end {
+					end = parent.end
+				}
+			}
+
+			out.WriteString(open)
+			stack = append(stack, span{end: end, close: closeTag, verbatim: e.Type == "code" || e.Type == "pre"})
+		}
+
+		out.WriteString(Escape(string(r)))
+		pos += utf16.RuneLen(r)
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		out.WriteString(stack[i].close)
+	}
+
+	return out.String()
+}
+
+// entityTags - открывающий и закрывающий теги для оформления; пустые, если оно остается текстом
+func entityTags(e tgbotapi.MessageEntity) (string, string) {
+	switch e.Type {
+	case "bold":
+		return "<b>", "</b>"
+	case "italic":
+		return "<i>", "</i>"
+	case "underline":
+		return "<u>", "</u>"
+	case "strikethrough":
+		return "<s>", "</s>"
+	case "spoiler":
+		return "<tg-spoiler>", "</tg-spoiler>"
+	case "code":
+		return "<code>", "</code>"
+	case "pre":
+		if e.Language != "" {
+			return `<pre><code class="language-` + Escape(e.Language) + `">`, "</code></pre>"
+		}
+		return "<pre>", "</pre>"
+	case "blockquote":
+		return "<blockquote>", "</blockquote>"
+	case "expandable_blockquote":
+		return "<blockquote expandable>", "</blockquote>"
+	case "text_link":
+		return `<a href="` + Escape(e.URL) + `">`, "</a>"
+	case "text_mention":
+		if e.User != nil {
+			return `<a href="tg://user?id=` + strconv.FormatInt(e.User.ID, 10) + `">`, "</a>"
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,120 @@
+// Package format - разметка сообщений бота в HTML Telegram (parse_mode=HTML).
+//
+// HTML выбран вместо MarkdownV2: в нем экранируются только &, < и >, а не полтора десятка знаков,
+// поэтому имя клиента вроде "*_[" не ломает сообщение. Тексты из каталогов и шаблонов - уже разметка,
+// все, что пришло от пользователей (имена, текст сообщений, ошибки с их вводом), проходит через Escape.
+package format
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// ParseMode - режим разметки всех сообщений бота
+const ParseMode = "HTML"
+
+// Escape экранирует текст для вставки в разметку, в том числе в значения атрибутов
+func Escape(text string) string {
+	return html.EscapeString(text)
+}
+
+// Code - моноширинный фрагмент в строке
+func Code(text string) string {
+	return "<code>" + Escape(text) + "</code>"
+}
+
+// Pre - моноширинный блок, переносы строк сохраняются
+func Pre(text string) string {
+	return "<pre>" + Escape(text) + "</pre>"
+}
+
+// Теги, которые понимает Telegram
+var allowedTags = map[string]bool{
+	"b": true, "strong": true,
+	"i": true, "em": true,
+	"u": true, "ins": true,
+	"s": true, "strike": true, "del": true,
+	"span": true, "tg-spoiler": true,
+	"a": true, "tg-emoji": true,
+	"code": true, "pre": true,
+	"blockquote": true,
+}
+
+// Validate проверяет разметку так же строго, как Telegram: только поддерживаемые теги, все теги закрыты,
+// а &, < и > вне тегов записаны сущностями. Иначе Telegram отклонит все сообщение.
+func Validate(markup string) error {
+	var open []string
+	for i := 0; i < len(markup); i++ {
+		switch markup[i] {
+		case '<':
+			end := strings.IndexByte(markup[i:], '>')
+			if end < 0 {
+				return fmt.Errorf("unclosed tag at position %d, write < as &lt;", i)
+			}
+			tag := markup[i+1 : i+end]
+			i += end
+
+			closing := strings.HasPrefix(tag, "/")
+			name := strings.ToLower(strings.TrimPrefix(tag, "/"))
+			if n := strings.IndexAny(name, " \t\n"); n >= 0 {
+				name = name[:n]
+			}
+			if !allowedTags[name] {
+				return fmt.Errorf("unsupported tag <%s>", tag)
+			}
+
+			if !closing {
+				open = append(open, name)
+				continue
+			}
+			if len(open) == 0 || open[len(open)-1] != name {
+				return fmt.Errorf("unexpected closing tag </%s>", name)
+			}
+			open = open[:len(open)-1]
+
+		case '>':
+			return fmt.Errorf("unescaped > at position %d, write it as &gt;", i)
+
+		case '&':
+			end := strings.IndexByte(markup[i:], ';')
+			if end < 0 || !isEntity(markup[i+1:i+end]) {
+				return fmt.Errorf("unescaped & at position %d, write it as &amp;", i)
+			}
+			i += end
+		}
+	}
+
+	if len(open) > 0 {
+		return fmt.Errorf("tag <%s> is not closed", open[len(open)-1])
+	}
+	return nil
+}
+
+// isEntity - именованные сущности, которые понимает Telegram, и любые числовые
+func isEntity(name string) bool {
+	switch name {
+	case "lt", "gt", "amp", "quot":
+		return true
+	}
+
+	digits := strings.TrimPrefix(name, "#")
+	if digits == name || digits == "" {
+		return false
+	}
+	hex := digits[0] == 'x' || digits[0] == 'X'
+	if hex {
+		digits = digits[1:]
+	}
+	if digits == "" {
+		return false
+	}
+	for _, c := range digits {
+		isDigit := c >= '0' && c <= '9'
+		isHex := (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+		if !isDigit && !(hex && isHex) {
+			return false
+		}
+	}
+	return true
+}
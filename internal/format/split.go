@@ -0,0 +1,208 @@
+package format
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Лимиты Telegram на длину текста после разбора разметки, в UTF-16 символах
+const (
+	MessageLimit = 4096
+	CaptionLimit = 1024
+)
+
+// token - тег, сущность или символ разметки
+type token struct {
+	raw   string
+	width int    // Сколько символов видно в Telegram: у тегов 0, у сущности и символа - длина в UTF-16
+	tag   string // Имя тега, пусто у текста
+	close bool
+}
+
+func tokenize(markup string) []token {
+	tokens := make([]token, 0, len(markup))
+	for i := 0; i < len(markup); {
+		switch markup[i] {
+		case '<':
+			if end := strings.IndexByte(markup[i:], '>'); end >= 0 {
+				raw := markup[i : i+end+1]
+				name := strings.TrimPrefix(raw[1:len(raw)-1], "/")
+				if n := strings.IndexAny(name, " \t\n"); n >= 0 {
+					name = name[:n]
+				}
+				tokens = append(tokens, token{raw: raw, tag: strings.ToLower(name), close: raw[1] == '/'})
+				i += end + 1
+				continue
+			}
+		case '&':
+			if end := strings.IndexByte(markup[i:], ';'); end >= 0 && isEntity(markup[i+1:i+end]) {
+				tokens = append(tokens, token{raw: markup[i : i+end+1], width: 1})
+				i += end + 1
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(markup[i:])
+		tokens = append(tokens, token{raw: markup[i : i+size], width: utf16.RuneLen(r)})
+		i += size
+	}
+	return tokens
+}
+
+// Len - длина разметки так, как ее считает Telegram: без тегов, в UTF-16 символах
+func Len(markup string) int {
+	n := 0
+	for _, t := range tokenize(markup) {
+		n += t.width
+	}
+	return n
+}
+
+// Split делит разметку на части не длиннее limit. Резать старается между абзацами, затем между строками
+// и словами; теги, открытые на месте разреза, закрываются в конце части и открываются заново в следующей.
+func Split(markup string, limit int) []string {
+	return split(markup, limit, limit)
+}
+
+// SplitCaption делит разметку на подпись к медиа и продолжение, которое отправляется отдельными сообщениями
+func SplitCaption(markup string) (caption string, rest []string) {
+	parts := split(markup, CaptionLimit, MessageLimit)
+	return parts[0], parts[1:]
+}
+
+// Truncate укорачивает разметку до limit символов с многоточием; truncated - текст не поместился целиком
+func Truncate(markup string, limit int) (text string, truncated bool) {
+	if Len(markup) <= limit {
+		return markup, false
+	}
+	return split(markup, limit-1, limit-1)[0] + "…", true
+}
+
+// Места разреза по убыванию предпочтения
+const (
+	breakWord = iota
+	breakLine
+	breakParagraph
+	breakKinds
+)
+
+// cut - место, где можно закончить часть: индекс токена и теги, открытые к этому месту
+type cut struct {
+	at    int
+	width int
+	open  []token
+}
+
+func split(markup string, firstLimit, limit int) []string {
+	if Len(markup) <= firstLimit {
+		return []string{markup}
+	}
+
+	tokens := tokenize(markup)
+	var parts []string
+	var open []token // Теги, открытые в начале части
+	start := 0
+
+	for start < len(tokens) {
+		partLimit := limit
+		if len(parts) == 0 {
+			partLimit = firstLimit
+		}
+
+		stack := append([]token(nil), open...)
+		var cuts [breakKinds]*cut
+		end, width := start, 0
+		for end < len(tokens) && width+tokens[end].width <= partLimit {
+			t := tokens[end]
+			stack = applyTag(stack, t)
+			width += t.width
+			end++
+
+			kind := -1
+			switch {
+			case t.raw == "\n" && end-1 > start && tokens[end-2].raw == "\n":
+				kind = breakParagraph
+			case t.raw == "\n":
+				kind = breakLine
+			case t.raw == " ":
+				kind = breakWord
+			}
+			if kind >= 0 {
+				cuts[kind] = &cut{at: end, width: width, open: append([]token(nil), stack...)}
+			}
+		}
+
+		// Последняя часть помещается целиком
+		best := &cut{at: end, width: width, open: stack}
+		if end < len(tokens) {
+			// Разрез ближе к началу дал бы слишком короткую часть, тогда лучше резать по менее удобному месту
+			for kind := breakParagraph; kind >= breakWord; kind-- {
+				if c := cuts[kind]; c != nil && c.width >= partLimit/2 {
+					best = c
+					break
+				}
+			}
+			if best.at == end {
+				for kind := breakParagraph; kind >= breakWord; kind-- {
+					if c := cuts[kind]; c != nil {
+						best = c
+						break
+					}
+				}
+			}
+		}
+		if best.at == start {
+			// Даже один символ не помещается в лимит - такого лимита не бывает, но цикл не должен зависнуть
+			best = &cut{at: start + 1, open: applyTag(append([]token(nil), open...), tokens[start])}
+		}
+
+		var part strings.Builder
+		for _, t := range open {
+			part.WriteString(t.raw)
+		}
+		for _, t := range tokens[start:best.at] {
+			part.WriteString(t.raw)
+		}
+		for i := len(best.open) - 1; i >= 0; i-- {
+			part.WriteString("</" + best.open[i].tag + ">")
+		}
+		if strings.TrimSpace(stripTags(part.String())) != "" {
+			parts = append(parts, strings.TrimRight(part.String(), " \n"))
+		}
+
+		// Пробелы и переводы строк на месте разреза не переносим в начало следующей части
+		start, open = best.at, best.open
+		for start < len(tokens) && (tokens[start].raw == "\n" || tokens[start].raw == " ") {
+			start++
+		}
+	}
+
+	if len(parts) == 0 {
+		return []string{markup}
+	}
+	return parts
+}
+
+// applyTag учитывает открывающий или закрывающий тег в стеке открытых тегов
+func applyTag(stack []token, t token) []token {
+	switch {
+	case t.tag == "":
+		return stack
+	case !t.close:
+		return append(stack, t)
+	case len(stack) > 0 && stack[len(stack)-1].tag == t.tag:
+		return stack[:len(stack)-1]
+	}
+	return stack
+}
+
+func stripTags(markup string) string {
+	var text strings.Builder
+	for _, t := range tokenize(markup) {
+		if t.tag == "" {
+			text.WriteString(t.raw)
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,148 @@
+// Package health - проверки живости и готовности сервиса для оркестратора.
+//
+// Живость (/healthz) говорит только о том, что процесс отвечает. Готовность (/readyz) выполняет
+// все зарегистрированные проверки зависимостей и отвечает 503, если хотя бы одна не прошла.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Сколько ждать все проверки готовности вместе
+const readinessTimeout = 5 * time.Second
+
+// Check проверяет одну зависимость. Ошибка означает, что сервис не готов принимать трафик.
+type Check func(ctx context.Context) error
+
+type Status struct {
+	Status        string        `json:"status"` // ok или fail
+	Version       string        `json:"version"`
+	StartedAt     time.Time     `json:"started_at"`
+	UptimeSeconds int64         `json:"uptime_seconds"`
+	Checks        []CheckResult `json:"checks,omitempty"`
+}
+
+type CheckResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type Checker struct {
+	version   string
+	startedAt time.Time
+
+	mu     sync.Mutex
+	checks []namedCheck
+}
+
+func NewChecker(version string) *Checker {
+	return &Checker{version: version, startedAt: time.Now()}
+}
+
+// Add регистрирует проверку готовности
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// LivenessHandler всегда отвечает 200, пока процесс обслуживает запросы
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, http.StatusOK, c.status("ok", nil))
+	})
+}
+
+// ReadinessHandler выполняет все проверки параллельно и отвечает 503, если какая-то не прошла
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		results := c.run(ctx)
+
+		code, status := http.StatusOK, "ok"
+		for _, result := range results {
+			if result.Status != "ok" {
+				code, status = http.StatusServiceUnavailable, "fail"
+				break
+			}
+		}
+
+		writeStatus(w, code, c.status(status, results))
+	})
+}
+
+func (c *Checker) run(ctx context.Context) []CheckResult {
+	c.mu.Lock()
+	checks := append([]namedCheck(nil), c.checks...)
+	c.mu.Unlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check namedCheck) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runCheck не дает зависшей проверке задержать ответ дольше таймаута
+func runCheck(ctx context.Context, check namedCheck) CheckResult {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Name:       check.name,
+		Status:     "ok",
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = "fail"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (c *Checker) status(status string, checks []CheckResult) Status {
+	return Status{
+		Status:        status,
+		Version:       c.version,
+		StartedAt:     c.startedAt,
+		UptimeSeconds: int64(time.Since(c.startedAt).Seconds()),
+		Checks:        checks,
+	}
+}
+
+func writeStatus(w http.ResponseWriter, code int, status Status) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,58 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// RedisCheck подключается к Redis и выполняет PING (с AUTH, если задан пароль).
+// Протокол RESP достаточно прост, чтобы не тянуть клиентскую библиотеку ради одной команды.
+func RedisCheck(addr, password string) Check {
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to connect to redis: %w", err)
+		}
+		defer conn.Close()
+
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		} else {
+			conn.SetDeadline(time.Now().Add(readinessTimeout))
+		}
+
+		reader := bufio.NewReader(conn)
+		if password != "" {
+			if err := redisCommand(conn, reader, "+OK", "AUTH", password); err != nil {
+				return err
+			}
+		}
+		return redisCommand(conn, reader, "+PONG", "PING")
+	}
+}
+
+func redisCommand(conn net.Conn, reader *bufio.Reader, expected string, args ...string) error {
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := conn.Write([]byte(command.String())); err != nil {
+		return fmt.Errorf("failed to send redis %s: %w", args[0], err)
+	}
+
+	reply, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read redis %s reply: %w", args[0], err)
+	}
+	if reply = strings.TrimRight(reply, "\r\n"); reply != expected {
+		return fmt.Errorf("unexpected redis %s reply: %s", args[0], reply)
+	}
+	return nil
+}
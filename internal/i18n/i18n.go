@@ -0,0 +1,207 @@
+// Package i18n - переводы текстов бота.
+//
+// Каталоги сообщений лежат в YAML-файлах locales/<язык>.yaml и встроены в бинарник; каталог из
+// LOCALES_DIR перекрывает встроенные и может добавить новые языки. Вложенные ключи склеиваются
+// через точку: admin.menu.title. Сообщения форматируются как fmt.Sprintf, порядок аргументов
+// в переводе можно менять через %[2]d.
+//
+// Сообщения - разметка HTML Telegram: <b>, <i>, <code> и т.д., знаки &, < и > пишутся как &amp;, &lt; и &gt;.
+// Аргументы подставляются как есть, поэтому пользовательские данные экранирует вызывающий код.
+package i18n
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed locales/*.yaml
+var embedded embed.FS
+
+// Bundle - каталоги всех языков
+type Bundle struct {
+	defaultLang string
+	catalogs    map[string]map[string]string
+}
+
+// Load читает встроенные каталоги и, если dir не пуст, каталоги из dir поверх них.
+// Ключи, которых нет в каталоге языка по умолчанию, считаются опечатками и дают ошибку, как и ошибки в разметке.
+func Load(dir, defaultLang string) (*Bundle, error) {
+	b := &Bundle{defaultLang: defaultLang, catalogs: make(map[string]map[string]string)}
+
+	if err := b.loadFS(embedded, "locales"); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := b.loadFS(os.DirFS(dir), "."); err != nil {
+			return nil, err
+		}
+	}
+
+	base, ok := b.catalogs[defaultLang]
+	if !ok {
+		return nil, fmt.Errorf("no catalog for default language %q", defaultLang)
+	}
+
+	var errs []error
+	for _, lang := range b.Languages() {
+		for key := range b.catalogs[lang] {
+			if _, ok := base[key]; !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown message key %q", lang, key))
+			}
+			if err := format.Validate(b.catalogs[lang][key]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid markup in %q: %w", lang, key, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return b, nil
+}
+
+func (b *Bundle) loadFS(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.yaml"))
+	if err != nil {
+		return fmt.Errorf("failed to list catalogs: %w", err)
+	}
+
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("failed to read catalog %s: %w", file, err)
+		}
+
+		var tree map[string]interface{}
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return fmt.Errorf("failed to parse catalog %s: %w", file, err)
+		}
+
+		lang := strings.TrimSuffix(path.Base(file), ".yaml")
+		catalog := b.catalogs[lang]
+		if catalog == nil {
+			catalog = make(map[string]string)
+			b.catalogs[lang] = catalog
+		}
+		if err := flatten("", tree, catalog); err != nil {
+			return fmt.Errorf("invalid catalog %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, catalog map[string]string) error {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case string:
+			catalog[key] = v
+		case map[string]interface{}:
+			if err := flatten(key, v, catalog); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s must be a string", key)
+		}
+	}
+	return nil
+}
+
+// Languages - коды языков, для которых есть каталоги
+func (b *Bundle) Languages() []string {
+	langs := make([]string, 0, len(b.catalogs))
+	for lang := range b.catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Default - язык по умолчанию
+func (b *Bundle) Default() string {
+	return b.defaultLang
+}
+
+// Supports - для языка есть каталог
+func (b *Bundle) Supports(lang string) bool {
+	_, ok := b.catalogs[lang]
+	return ok
+}
+
+// Match подбирает язык по language_code из Telegram ("en", "pt-br"); без подходящего каталога - язык по умолчанию
+func (b *Bundle) Match(languageCode string) string {
+	code := strings.ToLower(languageCode)
+	if b.Supports(code) {
+		return code
+	}
+	if base, _, ok := strings.Cut(code, "-"); ok && b.Supports(base) {
+		return base
+	}
+	return b.defaultLang
+}
+
+// Localizer возвращает переводчик на язык lang; неизвестный или пустой язык - язык по умолчанию
+func (b *Bundle) Localizer(lang string) *Localizer {
+	if !b.Supports(lang) {
+		lang = b.defaultLang
+	}
+	return &Localizer{lang: lang, messages: b.catalogs[lang], fallback: b.catalogs[b.defaultLang]}
+}
+
+// Localizer переводит сообщения на один язык; ключи без перевода берутся из языка по умолчанию
+type Localizer struct {
+	lang     string
+	messages map[string]string
+	fallback map[string]string
+}
+
+func (l *Localizer) Lang() string {
+	if l == nil {
+		return ""
+	}
+	return l.lang
+}
+
+// T возвращает сообщение по ключу, подставляя args; ненайденный ключ возвращается как есть, чтобы его было видно
+func (l *Localizer) T(key string, args ...interface{}) string {
+	if l == nil {
+		return key
+	}
+
+	message, ok := l.messages[key]
+	if !ok {
+		if message, ok = l.fallback[key]; !ok {
+			return key
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+type contextKey struct{}
+
+// WithLocalizer кладет в контекст переводчик на язык пользователя, который прислал обновление
+func WithLocalizer(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext возвращает переводчик из контекста или nil
+func FromContext(ctx context.Context) *Localizer {
+	l, _ := ctx.Value(contextKey{}).(*Localizer)
+	return l
+}
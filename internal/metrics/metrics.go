@@ -0,0 +1,287 @@
+// Package metrics - счетчики, гистограммы и датчики в текстовом формате Prometheus.
+//
+// Метрики регистрируются в общем реестре при создании и отдаются обработчиком Handler.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets - границы гистограмм длительности в секундах
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Сколько может длиться один сбор метрик: датчики, которые не успели, пропускаются.
+// Меньше scrape_timeout Prometheus по умолчанию (10 с), чтобы ответ все же дошел.
+const scrapeTimeout = 5 * time.Second
+
+type collector interface {
+	write(ctx context.Context, w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+// Handler отдает все зарегистрированные метрики
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registryMu.Lock()
+		collectors := append([]collector(nil), registry...)
+		registryMu.Unlock()
+
+		ctx, cancel := context.WithTimeout(r.Context(), scrapeTimeout)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		buf := bufio.NewWriter(w)
+		for _, c := range collectors {
+			c.write(ctx, buf)
+		}
+		buf.Flush()
+	})
+}
+
+// Since возвращает прошедшее время в секундах, для Observe
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// CounterVec - монотонный счетчик с метками
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labels []string
+	value  float64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]*counterSeries)}
+	register(c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	key := seriesKey(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	series, ok := c.values[key]
+	if !ok {
+		series = &counterSeries{labels: labelValues}
+		c.values[key] = series
+	}
+	series.value += v
+}
+
+func (c *CounterVec) write(_ context.Context, w io.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range sortedKeys(c.values) {
+		series := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, series.labels), formatValue(series.value))
+	}
+}
+
+// HistogramVec - распределение значений (обычно длительностей) с метками
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labels []string
+	counts []uint64 // По одному на границу, без накопления
+	count  uint64
+	sum    float64
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, values: make(map[string]*histogramSeries)}
+	register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := seriesKey(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	series, ok := h.values[key]
+	if !ok {
+		series = &histogramSeries{labels: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = series
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			series.counts[i]++
+			break
+		}
+	}
+	series.count++
+	series.sum += v
+}
+
+func (h *HistogramVec) write(_ context.Context, w io.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	labelNames := append(append([]string(nil), h.labels...), "le")
+	for _, key := range sortedKeys(h.values) {
+		series := h.values[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += series.counts[i]
+			labels := append(append([]string(nil), series.labels...), formatValue(bound))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(labelNames, labels), cumulative)
+		}
+		labels := append(append([]string(nil), series.labels...), "+Inf")
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(labelNames, labels), series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, series.labels), formatValue(series.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, series.labels), series.count)
+	}
+}
+
+// GaugeFunc - датчик, значение которого вычисляется при каждом сборе метрик.
+// fn получает контекст сбора: запросы к базе в нем ограничены scrapeTimeout.
+type GaugeFunc struct {
+	name  string
+	help  string
+	label string
+	fn    func(ctx context.Context) (map[string]float64, error)
+}
+
+// NewGaugeFunc регистрирует датчик без меток
+func NewGaugeFunc(name, help string, fn func(ctx context.Context) (float64, error)) *GaugeFunc {
+	return NewGaugeVecFunc(name, help, "", func(ctx context.Context) (map[string]float64, error) {
+		v, err := fn(ctx)
+		return map[string]float64{"": v}, err
+	})
+}
+
+// NewGaugeVecFunc регистрирует датчик с одной меткой: fn возвращает значение для каждого ее значения
+func NewGaugeVecFunc(name, help, label string, fn func(ctx context.Context) (map[string]float64, error)) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, label: label, fn: fn}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(ctx context.Context, w io.Writer) {
+	values, err := g.fn(ctx)
+	if err != nil {
+		// Без значения Prometheus отметит пропуск, а не покажет ложный ноль
+		slog.Error("Failed to collect metric", "metric", g.name, "error", err)
+		return
+	}
+
+	writeHeader(w, g.name, g.help, "gauge")
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		labels := ""
+		if g.label != "" {
+			labels = formatLabels([]string{g.label}, []string{key})
+		}
+		fmt.Fprintf(w, "%s%s %s\n", g.name, labels, formatValue(values[key]))
+	}
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, labelValueEscaper.Replace(value))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
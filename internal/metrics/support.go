@@ -0,0 +1,22 @@
+package metrics
+
+// Метрики бота. Датчики состояния базы (чаты, непрочитанные, очередь отправки) регистрируются в main,
+// потому что им нужны сервисы.
+var (
+	UpdatesTotal = NewCounterVec("support_bot_updates_total",
+		"Telegram updates received, by update type.", "type")
+	UpdateDuration = NewHistogramVec("support_bot_update_duration_seconds",
+		"Time spent handling one Telegram update, by update type.", DefaultBuckets, "type")
+
+	TelegramRequestsTotal = NewCounterVec("support_bot_telegram_requests_total",
+		"Bot API requests made, by method.", "method")
+	TelegramRequestDuration = NewHistogramVec("support_bot_telegram_request_duration_seconds",
+		"Bot API request latency, by method. Excludes time spent waiting for rate limits.", DefaultBuckets, "method")
+	TelegramErrorsTotal = NewCounterVec("support_bot_telegram_errors_total",
+		"Failed Bot API requests, by method and error code (network for transport errors).", "method", "code")
+
+	DBQueryDuration = NewHistogramVec("support_bot_db_query_duration_seconds",
+		"Database query latency, by operation and table.", DefaultBuckets, "operation", "table")
+	DBErrorsTotal = NewCounterVec("support_bot_db_errors_total",
+		"Failed database queries, by operation and table. Not found is not counted.", "operation", "table")
+)
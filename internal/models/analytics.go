@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// DailyActivity - активность за один день периода
+type DailyActivity struct {
+	Day             time.Time `json:"day"`
+	NewChats        int64     `json:"new_chats"`
+	ClientMessages  int64     `json:"client_messages"`
+	SupportMessages int64     `json:"support_messages"`
+}
+
+// ResponseTimeStats - время от первого сообщения клиента в чате до первого ответа поддержки
+type ResponseTimeStats struct {
+	Answered       int64   `json:"answered"`   // Чатов с ответом
+	Unanswered     int64   `json:"unanswered"` // Чатов, где клиент еще ждет первого ответа
+	MedianSeconds  float64 `json:"median_seconds"`
+	AverageSeconds float64 `json:"average_seconds"`
+}
+
+// OperatorStats - нагрузка на одного сотрудника поддержки
+type OperatorStats struct {
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Chats     int64  `json:"chats"`    // Чатов, в которые он ответил
+	Messages  int64  `json:"messages"` // Его ответов
+}
+
+// HourlyCount - сообщения клиентов в один час суток (UTC)
+type HourlyCount struct {
+	Hour     int   `json:"hour"`
+	Messages int64 `json:"messages"`
+}
+
+// BacklogStats - непрочитанные сообщения в активных чатах на текущий момент
+type BacklogStats struct {
+	Chats          int64      `json:"chats"`
+	Messages       int64      `json:"messages"`
+	OldestUnreadAt *time.Time `json:"oldest_unread_at"`
+}
+
+// AnalyticsReport - сводка работы поддержки за период [From, To)
+type AnalyticsReport struct {
+	From          time.Time
+	To            time.Time
+	ActiveChats   int64
+	ArchivedChats int64
+	Daily         []DailyActivity
+	FirstResponse ResponseTimeStats
+	Operators     []OperatorStats
+	BusiestHours  []HourlyCount // По убыванию числа сообщений
+	Backlog       BacklogStats
+}
@@ -20,6 +20,7 @@ type Chat struct {
 	Status        ChatStatus     `json:"status" gorm:"default:'active'"`
 	LastMessageAt *time.Time     `json:"last_message_at"`
 	UnreadCount   int            `json:"unread_count" gorm:"default:0"` // Количество непрочитанных сообщений от клиента
+	TopicID       int            `json:"topic_id" gorm:"index"`         // message_thread_id темы в группе поддержки
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index"`
@@ -27,17 +28,46 @@ type Chat struct {
 }
 
 type ChatMessage struct {
-	ID         uint           `json:"id" gorm:"primaryKey"`
-	ChatID     uint           `json:"chat_id" gorm:"not null"`
-	Chat       Chat           `json:"chat" gorm:"foreignKey:ChatID"`
-	UserID     uint           `json:"user_id" gorm:"not null"`
-	User       User           `json:"user" gorm:"foreignKey:UserID"`
-	Content    string         `json:"content"`
-	IsFromUser bool           `json:"is_from_user" gorm:"not null"` // true = от клиента, false = от админа
-	IsRead     bool           `json:"is_read" gorm:"default:false"` // Прочитано ли сообщение админом
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"`
-	Files      []File         `json:"files" gorm:"foreignKey:MessageID"`
+	ID                uint           `json:"id" gorm:"primaryKey"`
+	ChatID            uint           `json:"chat_id" gorm:"not null"`
+	Chat              Chat           `json:"chat" gorm:"foreignKey:ChatID"`
+	UserID            uint           `json:"user_id" gorm:"not null"`
+	User              User           `json:"user" gorm:"foreignKey:UserID"`
+	Content           string         `json:"content"`
+	IsFromUser        bool           `json:"is_from_user" gorm:"not null"`                                // true = от клиента, false = от админа
+	IsRead            bool           `json:"is_read" gorm:"default:false"`                                // Прочитано ли сообщение админом
+	TelegramChatID    int64          `json:"telegram_chat_id" gorm:"index:idx_chat_messages_telegram"`    // Чат Telegram, в котором написано исходное сообщение
+	TelegramMessageID int            `json:"telegram_message_id" gorm:"index:idx_chat_messages_telegram"` // message_id исходного сообщения в Telegram
+	DeliveryStatus    DeliveryStatus `json:"delivery_status"`                                             // Статус доставки ответа админа клиенту
+	DeliveryError     string         `json:"delivery_error"`
+	EditedAt          *time.Time     `json:"edited_at"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	Files             []File         `json:"files" gorm:"foreignKey:MessageID"`
 }
 
+// ChatMessageRevision хранит предыдущую версию отредактированного сообщения
+type ChatMessageRevision struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	MessageID uint      `json:"message_id" gorm:"not null;index"`
+	Content   string    `json:"content"` // Текст сообщения до редактирования
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MessageCopyKind string
+
+const (
+	MessageCopyClient MessageCopyKind = "client" // Копия ответа админа, доставленная клиенту
+	MessageCopyAdmin  MessageCopyKind = "admin"  // Уведомление о сообщении клиента, доставленное админу
+)
+
+// MessageMapping связывает сообщение чата с его копиями в чатах Telegram
+type MessageMapping struct {
+	ID                uint            `json:"id" gorm:"primaryKey"`
+	MessageID         uint            `json:"message_id" gorm:"not null;index"`
+	TelegramChatID    int64           `json:"telegram_chat_id" gorm:"not null;uniqueIndex:idx_message_mappings_telegram"`
+	TelegramMessageID int             `json:"telegram_message_id" gorm:"not null;uniqueIndex:idx_message_mappings_telegram"`
+	Kind              MessageCopyKind `json:"kind" gorm:"not null"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
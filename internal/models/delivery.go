@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending DeliveryStatus = "pending" // Ожидает отправки или повторной попытки
+	DeliveryStatusSent    DeliveryStatus = "sent"    // Доставлено в Telegram
+	DeliveryStatusFailed  DeliveryStatus = "failed"  // Не доставлено, попытки исчерпаны
+	DeliveryStatusBlocked DeliveryStatus = "blocked" // Клиент заблокировал бота
+	DeliveryStatusPartial DeliveryStatus = "partial" // Длинный ответ дошел до клиента не целиком
+)
+
+type DeliveryJobStatus string
+
+const (
+	DeliveryJobQueued DeliveryJobStatus = "queued" // В очереди на повторную отправку
+	DeliveryJobDone   DeliveryJobStatus = "done"   // Отправлено
+	DeliveryJobFailed DeliveryJobStatus = "failed" // Отправить не удалось
+)
+
+// DeliveryJob - сообщение в очереди повторной отправки клиенту
+type DeliveryJob struct {
+	ID               uint              `json:"id" gorm:"primaryKey"`
+	MessageID        uint              `json:"message_id" gorm:"not null;index"`
+	ChatID           uint              `json:"chat_id" gorm:"not null"`
+	TelegramChatID   int64             `json:"telegram_chat_id" gorm:"not null"`
+	Text             string            `json:"text"`
+	ParseMode        string            `json:"parse_mode"` // Пусто у задач, поставленных до разметки HTML: их текст отправляется как есть
+	FileID           string            `json:"file_id"`
+	FileType         string            `json:"file_type"`
+	ReplyToMessageID int               `json:"reply_to_message_id"`
+	SentParts        int               `json:"sent_parts" gorm:"default:0"` // Сколько частей длинного ответа уже доставлено
+	NotifyChatID     int64             `json:"notify_chat_id"`              // Чат, куда сообщить о результате доставки
+	NotifyTopicID    int               `json:"notify_topic_id"`             // Тема группы поддержки, если ответ писали из нее
+	Status           DeliveryJobStatus `json:"status" gorm:"default:'queued';index"`
+	Attempts         int               `json:"attempts" gorm:"default:0"`
+	NextAttemptAt    time.Time         `json:"next_attempt_at" gorm:"index"`
+	LastError        string            `json:"last_error"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// UndeliveredStatus - итог недоставленной задачи: если часть ответа клиент уже получил, ответ доставлен частично
+func (j *DeliveryJob) UndeliveredStatus(status DeliveryStatus) DeliveryStatus {
+	if j.SentParts > 0 {
+		return DeliveryStatusPartial
+	}
+	return status
+}
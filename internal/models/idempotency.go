@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// IdempotencyKey хранит результат запроса к публичному API, чтобы повтор с тем же ключом не выполнял его снова
+type IdempotencyKey struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Client      string    `json:"client" gorm:"not null;uniqueIndex:idx_idempotency_keys_client_key"` // Имя API-ключа
+	Key         string    `json:"key" gorm:"not null;uniqueIndex:idx_idempotency_keys_client_key"`    // Значение заголовка Idempotency-Key
+	RequestHash string    `json:"request_hash" gorm:"not null"`                                       // SHA256 тела запроса
+	StatusCode  int       `json:"status_code"`                                                        // 0 - запрос еще выполняется
+	Response    string    `json:"response" gorm:"type:text"`
+	LeaseToken  string    `json:"-"`          // Кто выполняет запрос; ответ сохраняет только он, даже если бронь перехватили
+	CreatedAt   time.Time `json:"created_at"` // Начало брони: просроченную бронь без ответа может перехватить повтор
+	UpdatedAt   time.Time `json:"updated_at"`
+}
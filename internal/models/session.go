@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// AdminSession - сессия админа в веб-панели. Сам токен не хранится, только его хеш.
+type AdminSession struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	User       User       `json:"user" gorm:"foreignKey:UserID"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"` // Заполняется при выходе или отзыве сессии
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// LoginToken - одноразовая ссылка для входа в веб-панель, которую бот отправляет админу
+type LoginToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	UserID    uint       `json:"user_id" gorm:"not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
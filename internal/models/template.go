@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Ключи шаблонов текстов для клиентов
+const (
+	TemplateWelcome              = "welcome"                // Ответ на /start
+	TemplateMessageReceived      = "message_received"       // Подтверждение, что сообщение клиента получено
+	TemplateOutsideBusinessHours = "outside_business_hours" // Подтверждение вне рабочего времени
+	TemplateSupportReply         = "support_reply"          // Заголовок над ответом поддержки
+)
+
+// MessageTemplate - текст для клиентов на одном языке, измененный админами.
+// Пока записи нет или Body пуст, бот берет текст из конфигурации или перевод.
+type MessageTemplate struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Key         string    `json:"key" gorm:"size:64;not null;uniqueIndex:idx_message_templates_key_language"`
+	Language    string    `json:"language" gorm:"size:8;not null;uniqueIndex:idx_message_templates_key_language"`
+	Body        string    `json:"body" gorm:"type:text"` // Шаблон html/template
+	Version     int       `json:"version" gorm:"not null;default:0"`
+	UpdatedByID uint      `json:"updated_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// MessageTemplateVersion - версия шаблона в истории изменений, любую из них можно вернуть
+type MessageTemplateVersion struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	TemplateID uint      `json:"template_id" gorm:"not null;uniqueIndex:idx_message_template_versions_version"`
+	Version    int       `json:"version" gorm:"not null;uniqueIndex:idx_message_template_versions_version"`
+	Body       string    `json:"body" gorm:"type:text"` // Пусто - возврат к тексту по умолчанию
+	AuthorID   uint      `json:"author_id"`
+	Author     User      `json:"author" gorm:"foreignKey:AuthorID"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -13,11 +13,20 @@ type User struct {
 	FirstName  string         `json:"first_name"`
 	LastName   string         `json:"last_name"`
 	IsAdmin    bool           `json:"is_admin" gorm:"default:false"`
+	Language   string         `json:"language" gorm:"size:8"` // Язык бота для пользователя, пусто - еще не выбран
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
+// SystemTelegramID - Telegram ID служебного пользователя, от имени которого пишут внешние системы через API
+const SystemTelegramID int64 = 0
+
+// IsSystem - служебный пользователь, а не человек из Telegram
+func (u *User) IsSystem() bool {
+	return u.TelegramID == SystemTelegramID
+}
+
 type UserRole string
 
 const (
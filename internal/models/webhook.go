@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook - адрес внешней системы, которой отправляются события чатов
+type Webhook struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	URL         string         `json:"url" gorm:"not null"`
+	Events      string         `json:"events"`           // Имена событий через запятую, пусто - все события
+	Secret      string         `json:"secret,omitempty"` // Ключ HMAC-подписи тела запроса
+	Description string         `json:"description"`
+	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending" // Ожидает отправки или повторной попытки
+	WebhookDeliverySuccess WebhookDeliveryStatus = "success" // Получатель ответил 2xx
+	WebhookDeliveryFailed  WebhookDeliveryStatus = "failed"  // Попытки исчерпаны
+)
+
+// WebhookDelivery - запись журнала отправки события на вебхук
+type WebhookDelivery struct {
+	ID            uint                  `json:"id" gorm:"primaryKey"`
+	WebhookID     uint                  `json:"webhook_id" gorm:"not null;index"`
+	Event         string                `json:"event" gorm:"not null"`
+	Payload       string                `json:"payload" gorm:"type:text"`
+	Status        WebhookDeliveryStatus `json:"status" gorm:"default:'pending';index"`
+	Attempts      int                   `json:"attempts" gorm:"default:0"`
+	NextAttemptAt time.Time             `json:"next_attempt_at" gorm:"index"`
+	ResponseCode  int                   `json:"response_code"`
+	ResponseBody  string                `json:"response_body"` // Начало ответа получателя для диагностики
+	LastError     string                `json:"last_error"`
+	DeliveredAt   *time.Time            `json:"delivered_at"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminSessionRepository interface {
+	Create(ctx context.Context, session *models.AdminSession) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.AdminSession, error)
+	Touch(ctx context.Context, id uint, lastSeenAt time.Time) error
+	Revoke(ctx context.Context, id uint, revokedAt time.Time) error
+	RevokeAllByUserID(ctx context.Context, userID uint, revokedAt time.Time) error
+}
+
+type adminSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminSessionRepository(db *gorm.DB) AdminSessionRepository {
+	return &adminSessionRepository{db: db}
+}
+
+func (r *adminSessionRepository) Create(ctx context.Context, session *models.AdminSession) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *adminSessionRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.AdminSession, error) {
+	var session models.AdminSession
+	err := r.db.WithContext(ctx).Preload("User").Where("token_hash = ?", tokenHash).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *adminSessionRepository) Touch(ctx context.Context, id uint, lastSeenAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.AdminSession{}).Where("id = ?", id).
+		Update("last_seen_at", lastSeenAt).Error
+}
+
+func (r *adminSessionRepository) Revoke(ctx context.Context, id uint, revokedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.AdminSession{}).Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt).Error
+}
+
+func (r *adminSessionRepository) RevokeAllByUserID(ctx context.Context, userID uint, revokedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.AdminSession{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", revokedAt).Error
+}
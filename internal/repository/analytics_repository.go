@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AnalyticsRepository считает статистику агрегирующими запросами, не загружая чаты и сообщения
+type AnalyticsRepository interface {
+	MessagesPerDay(ctx context.Context, from, to time.Time) ([]models.DailyActivity, error)
+	NewChatsPerDay(ctx context.Context, from, to time.Time) ([]models.DailyActivity, error)
+	FirstResponseTimes(ctx context.Context, from, to time.Time) (models.ResponseTimeStats, error)
+	ChatsPerOperator(ctx context.Context, from, to time.Time) ([]models.OperatorStats, error)
+	MessagesPerHour(ctx context.Context, from, to time.Time) ([]models.HourlyCount, error)
+	UnreadBacklog(ctx context.Context) (models.BacklogStats, error)
+}
+
+type analyticsRepository struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsRepository(db *gorm.DB) AnalyticsRepository {
+	return &analyticsRepository{db: db}
+}
+
+func (r *analyticsRepository) MessagesPerDay(ctx context.Context, from, to time.Time) ([]models.DailyActivity, error) {
+	var rows []models.DailyActivity
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT date_trunc('day', created_at) AS day,
+			COUNT(*) FILTER (WHERE is_from_user) AS client_messages,
+			COUNT(*) FILTER (WHERE NOT is_from_user) AS support_messages
+		FROM chat_messages
+		WHERE deleted_at IS NULL AND created_at >= ? AND created_at < ?
+		GROUP BY day
+		ORDER BY day`, from, to).Scan(&rows).Error
+	return rows, err
+}
+
+func (r *analyticsRepository) NewChatsPerDay(ctx context.Context, from, to time.Time) ([]models.DailyActivity, error) {
+	var rows []models.DailyActivity
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT date_trunc('day', created_at) AS day, COUNT(*) AS new_chats
+		FROM chats
+		WHERE deleted_at IS NULL AND created_at >= ? AND created_at < ?
+		GROUP BY day
+		ORDER BY day`, from, to).Scan(&rows).Error
+	return rows, err
+}
+
+// FirstResponseTimes учитывает чаты, в которых клиент впервые написал в течение периода
+func (r *analyticsRepository) FirstResponseTimes(ctx context.Context, from, to time.Time) (models.ResponseTimeStats, error) {
+	var stats models.ResponseTimeStats
+	err := r.db.WithContext(ctx).Raw(`
+		WITH first_question AS (
+			SELECT chat_id, MIN(created_at) AS asked_at
+			FROM chat_messages
+			WHERE is_from_user AND deleted_at IS NULL
+			GROUP BY chat_id
+		), first_answer AS (
+			SELECT q.chat_id, q.asked_at, MIN(m.created_at) AS answered_at
+			FROM first_question q
+			LEFT JOIN chat_messages m ON m.chat_id = q.chat_id
+				AND NOT m.is_from_user AND m.deleted_at IS NULL AND m.created_at >= q.asked_at
+			WHERE q.asked_at >= ? AND q.asked_at < ?
+			GROUP BY q.chat_id, q.asked_at
+		)
+		SELECT COUNT(answered_at) AS answered,
+			COUNT(*) - COUNT(answered_at) AS unanswered,
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM answered_at - asked_at)), 0) AS median_seconds,
+			COALESCE(AVG(EXTRACT(EPOCH FROM answered_at - asked_at)), 0) AS average_seconds
+		FROM first_answer`, from, to).Scan(&stats).Error
+	return stats, err
+}
+
+func (r *analyticsRepository) ChatsPerOperator(ctx context.Context, from, to time.Time) ([]models.OperatorStats, error) {
+	var rows []models.OperatorStats
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT u.id AS user_id, u.username, u.first_name, u.last_name,
+			COUNT(DISTINCT m.chat_id) AS chats, COUNT(*) AS messages
+		FROM chat_messages m
+		JOIN users u ON u.id = m.user_id
+		WHERE NOT m.is_from_user AND m.deleted_at IS NULL AND m.created_at >= ? AND m.created_at < ?
+		GROUP BY u.id, u.username, u.first_name, u.last_name
+		ORDER BY chats DESC, messages DESC`, from, to).Scan(&rows).Error
+	return rows, err
+}
+
+func (r *analyticsRepository) MessagesPerHour(ctx context.Context, from, to time.Time) ([]models.HourlyCount, error) {
+	var rows []models.HourlyCount
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT EXTRACT(HOUR FROM created_at AT TIME ZONE 'UTC')::int AS hour, COUNT(*) AS messages
+		FROM chat_messages
+		WHERE is_from_user AND deleted_at IS NULL AND created_at >= ? AND created_at < ?
+		GROUP BY hour
+		ORDER BY messages DESC, hour`, from, to).Scan(&rows).Error
+	return rows, err
+}
+
+func (r *analyticsRepository) UnreadBacklog(ctx context.Context) (models.BacklogStats, error) {
+	var stats models.BacklogStats
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT COUNT(*) AS chats, COALESCE(SUM(unread_count), 0) AS messages
+		FROM chats
+		WHERE status = ? AND unread_count > 0 AND deleted_at IS NULL`, models.ChatStatusActive).Scan(&stats).Error
+	if err != nil {
+		return stats, err
+	}
+
+	var oldest struct {
+		OldestUnreadAt *time.Time
+	}
+	err = r.db.WithContext(ctx).Raw(`
+		SELECT MIN(m.created_at) AS oldest_unread_at
+		FROM chat_messages m
+		JOIN chats c ON c.id = m.chat_id
+		WHERE c.status = ? AND c.deleted_at IS NULL
+			AND m.is_from_user AND NOT m.is_read AND m.deleted_at IS NULL`, models.ChatStatusActive).Scan(&oldest).Error
+	stats.OldestUnreadAt = oldest.OldestUnreadAt
+	return stats, err
+}
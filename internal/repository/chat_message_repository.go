@@ -2,19 +2,24 @@ package repository
 
 import (
 	"ai_support_tg_writer_bot/internal/models"
+	"context"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type ChatMessageRepository interface {
-	Create(message *models.ChatMessage) error
-	GetByID(id uint) (*models.ChatMessage, error)
-	GetByChatID(chatID uint) ([]models.ChatMessage, error)
-	GetByChatIDPaginated(chatID uint, limit, offset int) ([]models.ChatMessage, error)
-	GetCountByChatID(chatID uint) (int64, error)
-	GetLastMessageByChatID(chatID uint) (*models.ChatMessage, error)
-	Update(message *models.ChatMessage) error
-	Delete(id uint) error
+	Create(ctx context.Context, message *models.ChatMessage) error
+	GetByID(ctx context.Context, id uint) (*models.ChatMessage, error)
+	GetByChatID(ctx context.Context, chatID uint) ([]models.ChatMessage, error)
+	GetByChatIDPaginated(ctx context.Context, chatID uint, limit, offset int) ([]models.ChatMessage, error)
+	GetCountByChatID(ctx context.Context, chatID uint) (int64, error)
+	GetLastMessageByChatID(ctx context.Context, chatID uint) (*models.ChatMessage, error)
+	GetByTelegramMessage(ctx context.Context, telegramChatID int64, telegramMessageID int) (*models.ChatMessage, error)
+	Update(ctx context.Context, message *models.ChatMessage) error
+	UpdateContent(ctx context.Context, revision *models.ChatMessageRevision, content string, editedAt time.Time) error
+	UpdateDeliveryStatus(ctx context.Context, id uint, status models.DeliveryStatus, deliveryError string) error
+	Delete(ctx context.Context, id uint) error
 }
 
 type chatMessageRepository struct {
@@ -25,43 +30,43 @@ func NewChatMessageRepository(db *gorm.DB) ChatMessageRepository {
 	return &chatMessageRepository{db: db}
 }
 
-func (r *chatMessageRepository) Create(message *models.ChatMessage) error {
-	return r.db.Create(message).Error
+func (r *chatMessageRepository) Create(ctx context.Context, message *models.ChatMessage) error {
+	return r.db.WithContext(ctx).Create(message).Error
 }
 
-func (r *chatMessageRepository) GetByID(id uint) (*models.ChatMessage, error) {
+func (r *chatMessageRepository) GetByID(ctx context.Context, id uint) (*models.ChatMessage, error) {
 	var message models.ChatMessage
-	err := r.db.Preload("User").Preload("Chat").Preload("Files").First(&message, id).Error
+	err := r.db.WithContext(ctx).Preload("User").Preload("Chat").Preload("Files").First(&message, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &message, nil
 }
 
-func (r *chatMessageRepository) GetByChatID(chatID uint) ([]models.ChatMessage, error) {
+func (r *chatMessageRepository) GetByChatID(ctx context.Context, chatID uint) ([]models.ChatMessage, error) {
 	var messages []models.ChatMessage
-	err := r.db.Preload("User").Preload("Files").
+	err := r.db.WithContext(ctx).Preload("User").Preload("Files").
 		Where("chat_id = ?", chatID).Order("created_at ASC").Find(&messages).Error
 	return messages, err
 }
 
-func (r *chatMessageRepository) GetByChatIDPaginated(chatID uint, limit, offset int) ([]models.ChatMessage, error) {
+func (r *chatMessageRepository) GetByChatIDPaginated(ctx context.Context, chatID uint, limit, offset int) ([]models.ChatMessage, error) {
 	var messages []models.ChatMessage
-	err := r.db.Preload("User").Preload("Files").
+	err := r.db.WithContext(ctx).Preload("User").Preload("Files").
 		Where("chat_id = ?", chatID).Order("created_at ASC").
 		Limit(limit).Offset(offset).Find(&messages).Error
 	return messages, err
 }
 
-func (r *chatMessageRepository) GetCountByChatID(chatID uint) (int64, error) {
+func (r *chatMessageRepository) GetCountByChatID(ctx context.Context, chatID uint) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.ChatMessage{}).Where("chat_id = ?", chatID).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&models.ChatMessage{}).Where("chat_id = ?", chatID).Count(&count).Error
 	return count, err
 }
 
-func (r *chatMessageRepository) GetLastMessageByChatID(chatID uint) (*models.ChatMessage, error) {
+func (r *chatMessageRepository) GetLastMessageByChatID(ctx context.Context, chatID uint) (*models.ChatMessage, error) {
 	var message models.ChatMessage
-	err := r.db.Preload("User").Preload("Files").
+	err := r.db.WithContext(ctx).Preload("User").Preload("Files").
 		Where("chat_id = ?", chatID).Order("created_at DESC").First(&message).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -72,10 +77,44 @@ func (r *chatMessageRepository) GetLastMessageByChatID(chatID uint) (*models.Cha
 	return &message, nil
 }
 
-func (r *chatMessageRepository) Update(message *models.ChatMessage) error {
-	return r.db.Save(message).Error
+func (r *chatMessageRepository) GetByTelegramMessage(ctx context.Context, telegramChatID int64, telegramMessageID int) (*models.ChatMessage, error) {
+	var message models.ChatMessage
+	err := r.db.WithContext(ctx).Preload("User").Preload("Files").
+		Where("telegram_chat_id = ? AND telegram_message_id = ?", telegramChatID, telegramMessageID).First(&message).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (r *chatMessageRepository) Update(ctx context.Context, message *models.ChatMessage) error {
+	return r.db.WithContext(ctx).Save(message).Error
+}
+
+// UpdateContent заменяет текст сообщения, в той же транзакции сохраняя прежний как ревизию
+func (r *chatMessageRepository) UpdateContent(ctx context.Context, revision *models.ChatMessageRevision, content string, editedAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.ChatMessage{}).Where("id = ?", revision.MessageID).Updates(map[string]interface{}{
+			"content":   content,
+			"edited_at": &editedAt,
+		}).Error
+	})
+}
+
+func (r *chatMessageRepository) UpdateDeliveryStatus(ctx context.Context, id uint, status models.DeliveryStatus, deliveryError string) error {
+	return r.db.WithContext(ctx).Model(&models.ChatMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"delivery_status": status,
+		"delivery_error":  deliveryError,
+	}).Error
 }
 
-func (r *chatMessageRepository) Delete(id uint) error {
-	return r.db.Delete(&models.ChatMessage{}, id).Error
+func (r *chatMessageRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.ChatMessage{}, id).Error
 }
@@ -2,24 +2,32 @@ package repository
 
 import (
 	"ai_support_tg_writer_bot/internal/models"
+	"context"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type ChatRepository interface {
-	Create(chat *models.Chat) error
-	GetByID(id uint) (*models.Chat, error)
-	GetByUserID(userID uint) (*models.Chat, error)
-	GetActiveChats() ([]models.Chat, error)
-	GetActiveChatsPaginated(limit, offset int) ([]models.Chat, error)
-	GetArchivedChats() ([]models.Chat, error)
-	GetArchivedChatsPaginated(limit, offset int) ([]models.Chat, error)
-	Update(chat *models.Chat) error
-	ArchiveChat(chatID uint) error
-	MarkAsRead(chatID uint) error
-	IncrementUnreadCount(chatID uint) error
-	UpdateLastMessageTime(chatID uint) error
+	Create(ctx context.Context, chat *models.Chat) error
+	GetByID(ctx context.Context, id uint) (*models.Chat, error)
+	GetWithUser(ctx context.Context, id uint) (*models.Chat, error)
+	GetByUserID(ctx context.Context, userID uint) (*models.Chat, error)
+	GetLastByUserID(ctx context.Context, userID uint) (*models.Chat, error)
+	GetByTopicID(ctx context.Context, topicID int) (*models.Chat, error)
+	GetActiveChats(ctx context.Context) ([]models.Chat, error)
+	GetActiveChatsPaginated(ctx context.Context, limit, offset int) ([]models.Chat, error)
+	GetArchivedChats(ctx context.Context) ([]models.Chat, error)
+	GetArchivedChatsPaginated(ctx context.Context, limit, offset int) ([]models.Chat, error)
+	CountByStatus(ctx context.Context, status models.ChatStatus) (int64, error)
+	CountUnread(ctx context.Context) (int64, error)
+	Update(ctx context.Context, chat *models.Chat) error
+	ArchiveChat(ctx context.Context, chatID uint) error
+	MarkAsRead(ctx context.Context, chatID uint) error
+	IncrementUnreadCount(ctx context.Context, chatID uint) error
+	UpdateLastMessageTime(ctx context.Context, chatID uint) error
+	UpdateTopicID(ctx context.Context, chatID uint, topicID int) error
+	QueuePosition(ctx context.Context, chatID uint) (int, error)
 }
 
 type chatRepository struct {
@@ -30,13 +38,13 @@ func NewChatRepository(db *gorm.DB) ChatRepository {
 	return &chatRepository{db: db}
 }
 
-func (r *chatRepository) Create(chat *models.Chat) error {
-	return r.db.Create(chat).Error
+func (r *chatRepository) Create(ctx context.Context, chat *models.Chat) error {
+	return r.db.WithContext(ctx).Create(chat).Error
 }
 
-func (r *chatRepository) GetByID(id uint) (*models.Chat, error) {
+func (r *chatRepository) GetByID(ctx context.Context, id uint) (*models.Chat, error) {
 	var chat models.Chat
-	err := r.db.Preload("User").Preload("Messages").Preload("Messages.User").Preload("Messages.Files").
+	err := r.db.WithContext(ctx).Preload("User").Preload("Messages").Preload("Messages.User").Preload("Messages.Files").
 		First(&chat, id).Error
 	if err != nil {
 		return nil, err
@@ -44,9 +52,19 @@ func (r *chatRepository) GetByID(id uint) (*models.Chat, error) {
 	return &chat, nil
 }
 
-func (r *chatRepository) GetByUserID(userID uint) (*models.Chat, error) {
+// GetWithUser загружает чат только с клиентом, без истории сообщений
+func (r *chatRepository) GetWithUser(ctx context.Context, id uint) (*models.Chat, error) {
 	var chat models.Chat
-	err := r.db.Preload("User").Preload("Messages").Preload("Messages.User").Preload("Messages.Files").
+	err := r.db.WithContext(ctx).Preload("User").First(&chat, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &chat, nil
+}
+
+func (r *chatRepository) GetByUserID(ctx context.Context, userID uint) (*models.Chat, error) {
+	var chat models.Chat
+	err := r.db.WithContext(ctx).Preload("User").Preload("Messages").Preload("Messages.User").Preload("Messages.Files").
 		Where("user_id = ? AND status = ?", userID, models.ChatStatusActive).First(&chat).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -57,62 +75,132 @@ func (r *chatRepository) GetByUserID(userID uint) (*models.Chat, error) {
 	return &chat, nil
 }
 
-func (r *chatRepository) GetActiveChats() ([]models.Chat, error) {
+// GetLastByUserID возвращает последний чат пользователя независимо от статуса
+func (r *chatRepository) GetLastByUserID(ctx context.Context, userID uint) (*models.Chat, error) {
+	var chat models.Chat
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").First(&chat).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &chat, nil
+}
+
+// GetByTopicID возвращает последний чат, который ведется в указанной теме группы
+func (r *chatRepository) GetByTopicID(ctx context.Context, topicID int) (*models.Chat, error) {
+	var chat models.Chat
+	err := r.db.WithContext(ctx).Preload("User").Where("topic_id = ?", topicID).Order("created_at DESC").First(&chat).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &chat, nil
+}
+
+func (r *chatRepository) GetActiveChats(ctx context.Context) ([]models.Chat, error) {
 	var chats []models.Chat
-	err := r.db.Preload("User").Preload("Messages").Preload("Messages.User").Preload("Messages.Files").
+	err := r.db.WithContext(ctx).Preload("User").Preload("Messages").Preload("Messages.User").Preload("Messages.Files").
 		Where("status = ?", models.ChatStatusActive).Order("last_message_at DESC NULLS LAST, created_at DESC").Find(&chats).Error
 	return chats, err
 }
 
-func (r *chatRepository) GetActiveChatsPaginated(limit, offset int) ([]models.Chat, error) {
+func (r *chatRepository) GetActiveChatsPaginated(ctx context.Context, limit, offset int) ([]models.Chat, error) {
 	var chats []models.Chat
-	err := r.db.Preload("User").
+	err := r.db.WithContext(ctx).Preload("User").
 		Where("status = ?", models.ChatStatusActive).Order("last_message_at DESC NULLS LAST, created_at DESC").
 		Limit(limit).Offset(offset).Find(&chats).Error
 	return chats, err
 }
 
-func (r *chatRepository) GetArchivedChats() ([]models.Chat, error) {
+func (r *chatRepository) GetArchivedChats(ctx context.Context) ([]models.Chat, error) {
 	var chats []models.Chat
-	err := r.db.Preload("User").Preload("Messages").Preload("Messages.User").Preload("Messages.Files").
+	err := r.db.WithContext(ctx).Preload("User").Preload("Messages").Preload("Messages.User").Preload("Messages.Files").
 		Where("status = ?", models.ChatStatusArchived).Order("updated_at DESC").Find(&chats).Error
 	return chats, err
 }
 
-func (r *chatRepository) GetArchivedChatsPaginated(limit, offset int) ([]models.Chat, error) {
+func (r *chatRepository) GetArchivedChatsPaginated(ctx context.Context, limit, offset int) ([]models.Chat, error) {
 	var chats []models.Chat
-	err := r.db.Preload("User").
+	err := r.db.WithContext(ctx).Preload("User").
 		Where("status = ?", models.ChatStatusArchived).Order("updated_at DESC").
 		Limit(limit).Offset(offset).Find(&chats).Error
 	return chats, err
 }
 
-func (r *chatRepository) Update(chat *models.Chat) error {
-	return r.db.Save(chat).Error
+func (r *chatRepository) CountByStatus(ctx context.Context, status models.ChatStatus) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Chat{}).Where("status = ?", status).Count(&count).Error
+	return count, err
 }
 
-func (r *chatRepository) ArchiveChat(chatID uint) error {
-	return r.db.Model(&models.Chat{}).Where("id = ?", chatID).Updates(map[string]interface{}{
+// CountUnread считает активные чаты с непрочитанными сообщениями
+func (r *chatRepository) CountUnread(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Chat{}).
+		Where("status = ? AND unread_count > 0", models.ChatStatusActive).Count(&count).Error
+	return count, err
+}
+
+func (r *chatRepository) Update(ctx context.Context, chat *models.Chat) error {
+	return r.db.WithContext(ctx).Save(chat).Error
+}
+
+func (r *chatRepository) ArchiveChat(ctx context.Context, chatID uint) error {
+	return r.db.WithContext(ctx).Model(&models.Chat{}).Where("id = ?", chatID).Updates(map[string]interface{}{
 		"status": models.ChatStatusArchived,
 	}).Error
 }
 
-func (r *chatRepository) MarkAsRead(chatID uint) error {
+func (r *chatRepository) MarkAsRead(ctx context.Context, chatID uint) error {
 	// Обнуляем счетчик непрочитанных сообщений
-	err := r.db.Model(&models.Chat{}).Where("id = ?", chatID).Update("unread_count", 0).Error
+	err := r.db.WithContext(ctx).Model(&models.Chat{}).Where("id = ?", chatID).Update("unread_count", 0).Error
 	if err != nil {
 		return err
 	}
 
 	// Помечаем все сообщения от пользователя как прочитанные
-	return r.db.Model(&models.ChatMessage{}).Where("chat_id = ? AND is_from_user = ?", chatID, true).Update("is_read", true).Error
+	return r.db.WithContext(ctx).Model(&models.ChatMessage{}).Where("chat_id = ? AND is_from_user = ?", chatID, true).Update("is_read", true).Error
 }
 
-func (r *chatRepository) IncrementUnreadCount(chatID uint) error {
-	return r.db.Model(&models.Chat{}).Where("id = ?", chatID).UpdateColumn("unread_count", gorm.Expr("unread_count + 1")).Error
+func (r *chatRepository) IncrementUnreadCount(ctx context.Context, chatID uint) error {
+	return r.db.WithContext(ctx).Model(&models.Chat{}).Where("id = ?", chatID).UpdateColumn("unread_count", gorm.Expr("unread_count + 1")).Error
 }
 
-func (r *chatRepository) UpdateLastMessageTime(chatID uint) error {
+func (r *chatRepository) UpdateLastMessageTime(ctx context.Context, chatID uint) error {
 	now := time.Now()
-	return r.db.Model(&models.Chat{}).Where("id = ?", chatID).Update("last_message_at", &now).Error
+	return r.db.WithContext(ctx).Model(&models.Chat{}).Where("id = ?", chatID).Update("last_message_at", &now).Error
+}
+
+func (r *chatRepository) UpdateTopicID(ctx context.Context, chatID uint, topicID int) error {
+	return r.db.WithContext(ctx).Model(&models.Chat{}).Where("id = ?", chatID).Update("topic_id", topicID).Error
+}
+
+// QueuePosition - место чата в очереди ожидающих ответа, 0 - чат ответа не ждет.
+// Ждут ответа активные чаты, в которых после последнего ответа поддержки писал клиент;
+// раньше в очереди тот, чье первое неотвеченное сообщение старше.
+func (r *chatRepository) QueuePosition(ctx context.Context, chatID uint) (int, error) {
+	var position int
+	err := r.db.WithContext(ctx).Raw(`
+		WITH last_reply AS (
+			SELECT chat_id, MAX(created_at) AS replied_at
+			FROM chat_messages
+			WHERE NOT is_from_user AND deleted_at IS NULL
+			GROUP BY chat_id
+		), waiting AS (
+			SELECT m.chat_id, MIN(m.created_at) AS since
+			FROM chat_messages m
+			JOIN chats c ON c.id = m.chat_id AND c.status = ? AND c.deleted_at IS NULL
+			LEFT JOIN last_reply r ON r.chat_id = m.chat_id
+			WHERE m.is_from_user AND m.deleted_at IS NULL AND (r.replied_at IS NULL OR m.created_at > r.replied_at)
+			GROUP BY m.chat_id
+		)
+		SELECT COUNT(*)
+		FROM waiting w
+		JOIN waiting self ON self.chat_id = ?
+		WHERE (w.since, w.chat_id) <= (self.since, self.chat_id)`, models.ChatStatusActive, chatID).Scan(&position).Error
+	return position, err
 }
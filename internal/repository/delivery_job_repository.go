@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DeliveryJobRepository interface {
+	Create(ctx context.Context, job *models.DeliveryJob) error
+	GetDue(ctx context.Context, now time.Time, limit int) ([]models.DeliveryJob, error)
+	Update(ctx context.Context, job *models.DeliveryJob) error
+	CountQueued(ctx context.Context) (int64, error)
+}
+
+type deliveryJobRepository struct {
+	db *gorm.DB
+}
+
+func NewDeliveryJobRepository(db *gorm.DB) DeliveryJobRepository {
+	return &deliveryJobRepository{db: db}
+}
+
+func (r *deliveryJobRepository) Create(ctx context.Context, job *models.DeliveryJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *deliveryJobRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]models.DeliveryJob, error) {
+	var jobs []models.DeliveryJob
+	err := r.db.WithContext(ctx).Where("status = ? AND next_attempt_at <= ?", models.DeliveryJobQueued, now).
+		Order("next_attempt_at ASC").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *deliveryJobRepository) Update(ctx context.Context, job *models.DeliveryJob) error {
+	return r.db.WithContext(ctx).Save(job).Error
+}
+
+func (r *deliveryJobRepository) CountQueued(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.DeliveryJob{}).Where("status = ?", models.DeliveryJobQueued).Count(&count).Error
+	return count, err
+}
@@ -2,16 +2,17 @@ package repository
 
 import (
 	"ai_support_tg_writer_bot/internal/models"
+	"context"
 
 	"gorm.io/gorm"
 )
 
 type FileRepository interface {
-	Create(file *models.File) error
-	GetByID(id uint) (*models.File, error)
-	GetByMessageID(messageID uint) ([]models.File, error)
-	Update(file *models.File) error
-	Delete(id uint) error
+	Create(ctx context.Context, file *models.File) error
+	GetByID(ctx context.Context, id uint) (*models.File, error)
+	GetByMessageID(ctx context.Context, messageID uint) ([]models.File, error)
+	Update(ctx context.Context, file *models.File) error
+	Delete(ctx context.Context, id uint) error
 }
 
 type fileRepository struct {
@@ -22,29 +23,29 @@ func NewFileRepository(db *gorm.DB) FileRepository {
 	return &fileRepository{db: db}
 }
 
-func (r *fileRepository) Create(file *models.File) error {
-	return r.db.Create(file).Error
+func (r *fileRepository) Create(ctx context.Context, file *models.File) error {
+	return r.db.WithContext(ctx).Create(file).Error
 }
 
-func (r *fileRepository) GetByID(id uint) (*models.File, error) {
+func (r *fileRepository) GetByID(ctx context.Context, id uint) (*models.File, error) {
 	var file models.File
-	err := r.db.Preload("Message").First(&file, id).Error
+	err := r.db.WithContext(ctx).Preload("Message").First(&file, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &file, nil
 }
 
-func (r *fileRepository) GetByMessageID(messageID uint) ([]models.File, error) {
+func (r *fileRepository) GetByMessageID(ctx context.Context, messageID uint) ([]models.File, error) {
 	var files []models.File
-	err := r.db.Where("message_id = ?", messageID).Find(&files).Error
+	err := r.db.WithContext(ctx).Where("message_id = ?", messageID).Find(&files).Error
 	return files, err
 }
 
-func (r *fileRepository) Update(file *models.File) error {
-	return r.db.Save(file).Error
+func (r *fileRepository) Update(ctx context.Context, file *models.File) error {
+	return r.db.WithContext(ctx).Save(file).Error
 }
 
-func (r *fileRepository) Delete(id uint) error {
-	return r.db.Delete(&models.File{}, id).Error
+func (r *fileRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.File{}, id).Error
 }
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyKeyRepository interface {
+	// Reserve создает запись, если ключа еще нет. false - ключ уже занят.
+	Reserve(ctx context.Context, record *models.IdempotencyKey) (bool, error)
+	GetByKey(ctx context.Context, client, key string) (*models.IdempotencyKey, error)
+	// TakeOver передает новому исполнителю бронь без ответа, начатую раньше staleBefore. false - бронь не просрочена или уже занята.
+	TakeOver(ctx context.Context, id uint, leaseToken string, staleBefore time.Time) (bool, error)
+	// Complete и Delete меняют запись, только пока ее бронь у leaseToken
+	Complete(ctx context.Context, id uint, leaseToken string, statusCode int, response string) error
+	Delete(ctx context.Context, id uint, leaseToken string) error
+}
+
+type idempotencyKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyKeyRepository(db *gorm.DB) IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{db: db}
+}
+
+func (r *idempotencyKeyRepository) Reserve(ctx context.Context, record *models.IdempotencyKey) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *idempotencyKeyRepository) GetByKey(ctx context.Context, client, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	err := r.db.WithContext(ctx).Where("client = ? AND key = ?", client, key).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *idempotencyKeyRepository) TakeOver(ctx context.Context, id uint, leaseToken string, staleBefore time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("id = ? AND status_code = 0 AND created_at < ?", id, staleBefore).
+		Updates(map[string]interface{}{"lease_token": leaseToken, "created_at": time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *idempotencyKeyRepository) Complete(ctx context.Context, id uint, leaseToken string, statusCode int, response string) error {
+	return r.db.WithContext(ctx).Model(&models.IdempotencyKey{}).Where("id = ? AND lease_token = ?", id, leaseToken).
+		Updates(map[string]interface{}{"status_code": statusCode, "response": response}).Error
+}
+
+func (r *idempotencyKeyRepository) Delete(ctx context.Context, id uint, leaseToken string) error {
+	return r.db.WithContext(ctx).Where("lease_token = ?", leaseToken).Delete(&models.IdempotencyKey{}, id).Error
+}
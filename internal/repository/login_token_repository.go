@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LoginTokenRepository interface {
+	Create(ctx context.Context, token *models.LoginToken) error
+	Consume(ctx context.Context, tokenHash string, now time.Time) (*models.LoginToken, error)
+}
+
+type loginTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginTokenRepository(db *gorm.DB) LoginTokenRepository {
+	return &loginTokenRepository{db: db}
+}
+
+func (r *loginTokenRepository) Create(ctx context.Context, token *models.LoginToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// Consume атомарно помечает действующий токен использованным. Возвращает nil, если токен не найден, истек или уже использован.
+func (r *loginTokenRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (*models.LoginToken, error) {
+	result := r.db.WithContext(ctx).Model(&models.LoginToken{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	var token models.LoginToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
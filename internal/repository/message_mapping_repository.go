@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type MessageMappingRepository interface {
+	Create(ctx context.Context, mapping *models.MessageMapping) error
+	GetByTelegramMessage(ctx context.Context, telegramChatID int64, telegramMessageID int) (*models.MessageMapping, error)
+	GetByMessageID(ctx context.Context, messageID uint) ([]models.MessageMapping, error)
+}
+
+type messageMappingRepository struct {
+	db *gorm.DB
+}
+
+func NewMessageMappingRepository(db *gorm.DB) MessageMappingRepository {
+	return &messageMappingRepository{db: db}
+}
+
+func (r *messageMappingRepository) Create(ctx context.Context, mapping *models.MessageMapping) error {
+	return r.db.WithContext(ctx).Create(mapping).Error
+}
+
+func (r *messageMappingRepository) GetByTelegramMessage(ctx context.Context, telegramChatID int64, telegramMessageID int) (*models.MessageMapping, error) {
+	var mapping models.MessageMapping
+	err := r.db.WithContext(ctx).Where("telegram_chat_id = ? AND telegram_message_id = ?", telegramChatID, telegramMessageID).First(&mapping).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+func (r *messageMappingRepository) GetByMessageID(ctx context.Context, messageID uint) ([]models.MessageMapping, error) {
+	var mappings []models.MessageMapping
+	err := r.db.WithContext(ctx).Where("message_id = ?", messageID).Order("created_at ASC").Find(&mappings).Error
+	return mappings, err
+}
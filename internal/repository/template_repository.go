@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TemplateRepository interface {
+	Get(ctx context.Context, key, language string) (*models.MessageTemplate, error)
+	GetAll(ctx context.Context) ([]models.MessageTemplate, error)
+	// SaveVersion меняет текст шаблона и записывает его следующей версией в историю
+	SaveVersion(ctx context.Context, key, language, body string, authorID uint) (*models.MessageTemplate, error)
+	GetVersions(ctx context.Context, templateID uint, limit int) ([]models.MessageTemplateVersion, error)
+	GetVersion(ctx context.Context, templateID uint, version int) (*models.MessageTemplateVersion, error)
+}
+
+type templateRepository struct {
+	db *gorm.DB
+}
+
+func NewTemplateRepository(db *gorm.DB) TemplateRepository {
+	return &templateRepository{db: db}
+}
+
+func (r *templateRepository) Get(ctx context.Context, key, language string) (*models.MessageTemplate, error) {
+	var template models.MessageTemplate
+	err := r.db.WithContext(ctx).Where("key = ? AND language = ?", key, language).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *templateRepository) GetAll(ctx context.Context) ([]models.MessageTemplate, error) {
+	var templates []models.MessageTemplate
+	err := r.db.WithContext(ctx).Order("key ASC, language ASC").Find(&templates).Error
+	return templates, err
+}
+
+func (r *templateRepository) SaveVersion(ctx context.Context, key, language, body string, authorID uint) (*models.MessageTemplate, error) {
+	var template models.MessageTemplate
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Первое изменение создает запись; одновременные правки одного шаблона выстраиваются в очередь на блокировке
+		seed := &models.MessageTemplate{Key: key, Language: language}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(seed).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("key = ? AND language = ?", key, language).
+			First(&template).Error; err != nil {
+			return err
+		}
+
+		template.Version++
+		template.Body = body
+		template.UpdatedByID = authorID
+		if err := tx.Model(&template).Select("body", "version", "updated_by_id", "updated_at").Updates(&template).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.MessageTemplateVersion{
+			TemplateID: template.ID,
+			Version:    template.Version,
+			Body:       body,
+			AuthorID:   authorID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+func (r *templateRepository) GetVersions(ctx context.Context, templateID uint, limit int) ([]models.MessageTemplateVersion, error) {
+	var versions []models.MessageTemplateVersion
+	err := r.db.WithContext(ctx).
+		Preload("Author").
+		Where("template_id = ?", templateID).
+		Order("version DESC").
+		Limit(limit).
+		Find(&versions).Error
+	return versions, err
+}
+
+func (r *templateRepository) GetVersion(ctx context.Context, templateID uint, version int) (*models.MessageTemplateVersion, error) {
+	var v models.MessageTemplateVersion
+	err := r.db.WithContext(ctx).Where("template_id = ? AND version = ?", templateID, version).First(&v).Error
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
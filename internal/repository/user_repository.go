@@ -2,17 +2,21 @@ package repository
 
 import (
 	"ai_support_tg_writer_bot/internal/models"
+	"context"
 
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	Create(user *models.User) error
-	GetByTelegramID(telegramID int64) (*models.User, error)
-	GetByID(id uint) (*models.User, error)
-	Update(user *models.User) error
-	IsAdmin(telegramID int64) (bool, error)
-	GetAllAdmins() ([]models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	GetByTelegramID(ctx context.Context, telegramID int64) (*models.User, error)
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	IsAdmin(ctx context.Context, telegramID int64) (bool, error)
+	GetAllAdmins(ctx context.Context) ([]models.User, error)
+	// SetAdmins делает админами ровно пользователей из списка, у остальных права снимаются
+	SetAdmins(ctx context.Context, telegramIDs []int64) error
+	SetLanguage(ctx context.Context, telegramID int64, language string) error
 }
 
 type userRepository struct {
@@ -23,35 +27,35 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *userRepository) GetByTelegramID(telegramID int64) (*models.User, error) {
+func (r *userRepository) GetByTelegramID(ctx context.Context, telegramID int64) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("telegram_id = ?", telegramID).First(&user).Error
+	err := r.db.WithContext(ctx).Where("telegram_id = ?", telegramID).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) GetByID(id uint) (*models.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
-	err := r.db.First(&user, id).Error
+	err := r.db.WithContext(ctx).First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
 }
 
-func (r *userRepository) IsAdmin(telegramID int64) (bool, error) {
+func (r *userRepository) IsAdmin(ctx context.Context, telegramID int64) (bool, error) {
 	var user models.User
-	err := r.db.Where("telegram_id = ? AND is_admin = ?", telegramID, true).First(&user).Error
+	err := r.db.WithContext(ctx).Where("telegram_id = ? AND is_admin = ?", telegramID, true).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return false, nil
@@ -61,8 +65,33 @@ func (r *userRepository) IsAdmin(telegramID int64) (bool, error) {
 	return true, nil
 }
 
-func (r *userRepository) GetAllAdmins() ([]models.User, error) {
+func (r *userRepository) GetAllAdmins(ctx context.Context) ([]models.User, error) {
 	var admins []models.User
-	err := r.db.Where("is_admin = ?", true).Find(&admins).Error
+	err := r.db.WithContext(ctx).Where("is_admin = ?", true).Find(&admins).Error
 	return admins, err
 }
+
+func (r *userRepository) SetAdmins(ctx context.Context, telegramIDs []int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		revoke := tx.Model(&models.User{}).Where("is_admin = ?", true)
+		if len(telegramIDs) > 0 {
+			revoke = revoke.Where("telegram_id NOT IN ?", telegramIDs)
+		}
+		if err := revoke.Update("is_admin", false).Error; err != nil {
+			return err
+		}
+
+		if len(telegramIDs) == 0 {
+			return nil
+		}
+		return tx.Model(&models.User{}).
+			Where("telegram_id IN ? AND is_admin = ?", telegramIDs, false).
+			Update("is_admin", true).Error
+	})
+}
+
+func (r *userRepository) SetLanguage(ctx context.Context, telegramID int64, language string) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).
+		Where("telegram_id = ?", telegramID).
+		Update("language", language).Error
+}
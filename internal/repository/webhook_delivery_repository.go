@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetByID(ctx context.Context, id uint) (*models.WebhookDelivery, error)
+	GetDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	GetByWebhookIDPaginated(ctx context.Context, webhookID uint, limit, offset int) ([]models.WebhookDelivery, error)
+	GetCountByWebhookID(ctx context.Context, webhookID uint) (int64, error)
+	Update(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+type webhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: db}
+}
+
+func (r *webhookDeliveryRepository) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *webhookDeliveryRepository) GetByID(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.WithContext(ctx).First(&delivery, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookDeliveryRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.WithContext(ctx).Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Order("next_attempt_at ASC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookDeliveryRepository) GetByWebhookIDPaginated(ctx context.Context, webhookID uint, limit, offset int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.WithContext(ctx).Where("webhook_id = ?", webhookID).Order("created_at DESC").
+		Limit(limit).Offset(offset).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookDeliveryRepository) GetCountByWebhookID(ctx context.Context, webhookID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID).Count(&count).Error
+	return count, err
+}
+
+func (r *webhookDeliveryRepository) Update(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
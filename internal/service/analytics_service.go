@@ -0,0 +1,128 @@
+package service
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Периоды отчета, которые можно выбрать в боте и в API
+const (
+	PeriodToday = "today"
+	PeriodWeek  = "7d"
+	PeriodMonth = "30d"
+	PeriodYear  = "365d"
+)
+
+// Максимальная длина произвольного периода
+const maxAnalyticsPeriod = 366 * 24 * time.Hour
+
+var ErrInvalidPeriod = errors.New("invalid analytics period")
+
+type AnalyticsService interface {
+	// GetReport собирает отчет за [from, to)
+	GetReport(ctx context.Context, from, to time.Time) (*models.AnalyticsReport, error)
+	// PeriodRange переводит имя периода (today, 7d, 30d, 365d) в границы, заканчивающиеся сейчас
+	PeriodRange(period string) (time.Time, time.Time, error)
+}
+
+type analyticsService struct {
+	analyticsRepo repository.AnalyticsRepository
+	chatRepo      repository.ChatRepository
+}
+
+func NewAnalyticsService(analyticsRepo repository.AnalyticsRepository, chatRepo repository.ChatRepository) AnalyticsService {
+	return &analyticsService{
+		analyticsRepo: analyticsRepo,
+		chatRepo:      chatRepo,
+	}
+}
+
+func (s *analyticsService) PeriodRange(period string) (time.Time, time.Time, error) {
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+
+	switch period {
+	case PeriodToday:
+		return today, now, nil
+	case PeriodWeek, "":
+		return today.AddDate(0, 0, -6), now, nil
+	case PeriodMonth:
+		return today.AddDate(0, 0, -29), now, nil
+	case PeriodYear:
+		return today.AddDate(0, 0, -364), now, nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %q", ErrInvalidPeriod, period)
+	}
+}
+
+func (s *analyticsService) GetReport(ctx context.Context, from, to time.Time) (*models.AnalyticsReport, error) {
+	if !from.Before(to) || to.Sub(from) > maxAnalyticsPeriod {
+		return nil, fmt.Errorf("%w: from must be before to and the period must not exceed a year", ErrInvalidPeriod)
+	}
+
+	report := &models.AnalyticsReport{From: from, To: to}
+	var err error
+
+	if report.ActiveChats, err = s.chatRepo.CountByStatus(ctx, models.ChatStatusActive); err != nil {
+		return nil, fmt.Errorf("failed to count active chats: %w", err)
+	}
+	if report.ArchivedChats, err = s.chatRepo.CountByStatus(ctx, models.ChatStatusArchived); err != nil {
+		return nil, fmt.Errorf("failed to count archived chats: %w", err)
+	}
+
+	messages, err := s.analyticsRepo.MessagesPerDay(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count messages per day: %w", err)
+	}
+	chats, err := s.analyticsRepo.NewChatsPerDay(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count new chats per day: %w", err)
+	}
+	report.Daily = mergeDaily(from, to, messages, chats)
+
+	if report.FirstResponse, err = s.analyticsRepo.FirstResponseTimes(ctx, from, to); err != nil {
+		return nil, fmt.Errorf("failed to get first response times: %w", err)
+	}
+	if report.Operators, err = s.analyticsRepo.ChatsPerOperator(ctx, from, to); err != nil {
+		return nil, fmt.Errorf("failed to count chats per operator: %w", err)
+	}
+	if report.BusiestHours, err = s.analyticsRepo.MessagesPerHour(ctx, from, to); err != nil {
+		return nil, fmt.Errorf("failed to count messages per hour: %w", err)
+	}
+	if report.Backlog, err = s.analyticsRepo.UnreadBacklog(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get unread backlog: %w", err)
+	}
+
+	return report, nil
+}
+
+// mergeDaily сводит счетчики сообщений и чатов в непрерывный ряд по дням, без пропусков
+func mergeDaily(from, to time.Time, messages, chats []models.DailyActivity) []models.DailyActivity {
+	byDay := make(map[string]*models.DailyActivity)
+	var days []models.DailyActivity
+
+	for day := from.UTC().Truncate(24 * time.Hour); day.Before(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, models.DailyActivity{Day: day})
+	}
+	for i := range days {
+		byDay[days[i].Day.Format("2006-01-02")] = &days[i]
+	}
+
+	for _, row := range messages {
+		if day, ok := byDay[row.Day.UTC().Format("2006-01-02")]; ok {
+			day.ClientMessages = row.ClientMessages
+			day.SupportMessages = row.SupportMessages
+		}
+	}
+	for _, row := range chats {
+		if day, ok := byDay[row.Day.UTC().Format("2006-01-02")]; ok {
+			day.NewChats = row.NewChats
+		}
+	}
+
+	return days
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/repository"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Сколько действует одноразовая ссылка для входа
+	LoginTokenTTL = 10 * time.Minute
+	// Насколько старыми могут быть данные Telegram Login Widget
+	telegramAuthMaxAge = 24 * time.Hour
+	// Как часто обновлять время последней активности сессии
+	sessionTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrNotAdmin           = errors.New("user is not an admin")
+	ErrSessionInvalid     = errors.New("session is invalid or expired")
+)
+
+type AuthService interface {
+	// LoginWithTelegram проверяет данные Telegram Login Widget и возвращает админа
+	LoginWithTelegram(ctx context.Context, data map[string]string) (*models.User, error)
+	// CreateLoginToken выпускает одноразовый токен для входа по ссылке из бота
+	CreateLoginToken(ctx context.Context, user *models.User) (string, error)
+	// LoginWithToken погашает одноразовый токен и возвращает админа
+	LoginWithToken(ctx context.Context, token string) (*models.User, error)
+	// CreateSession открывает сессию и возвращает подписанное значение для cookie
+	CreateSession(ctx context.Context, user *models.User, userAgent, ip string) (string, *models.AdminSession, error)
+	// ValidateSession проверяет подпись, срок и отзыв сессии, а также что пользователь все еще админ
+	ValidateSession(ctx context.Context, cookie string) (*models.AdminSession, error)
+	RevokeSession(ctx context.Context, sessionID uint) error
+	RevokeAllSessions(ctx context.Context, userID uint) error
+}
+
+type authService struct {
+	userRepo       repository.UserRepository
+	sessionRepo    repository.AdminSessionRepository
+	loginTokenRepo repository.LoginTokenRepository
+	botToken       string
+	sessionSecret  []byte
+	sessionTTL     time.Duration
+}
+
+func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.AdminSessionRepository, loginTokenRepo repository.LoginTokenRepository, botToken, sessionSecret string, sessionTTL time.Duration) AuthService {
+	// Без отдельного секрета ключ подписи выводится из токена бота
+	secret := sha256.Sum256([]byte("web-session:" + botToken))
+	if sessionSecret != "" {
+		secret = sha256.Sum256([]byte(sessionSecret))
+	}
+
+	return &authService{
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		loginTokenRepo: loginTokenRepo,
+		botToken:       botToken,
+		sessionSecret:  secret[:],
+		sessionTTL:     sessionTTL,
+	}
+}
+
+func (s *authService) LoginWithTelegram(ctx context.Context, data map[string]string) (*models.User, error) {
+	if !checkTelegramAuth(data, s.botToken) {
+		return nil, ErrInvalidCredentials
+	}
+
+	authDate, err := strconv.ParseInt(data["auth_date"], 10, 64)
+	if err != nil || time.Since(time.Unix(authDate, 0)) > telegramAuthMaxAge {
+		return nil, ErrInvalidCredentials
+	}
+
+	telegramID, err := strconv.ParseInt(data["id"], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.adminByTelegramID(ctx, telegramID)
+}
+
+func (s *authService) CreateLoginToken(ctx context.Context, user *models.User) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	loginToken := &models.LoginToken{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(LoginTokenTTL),
+	}
+	if err := s.loginTokenRepo.Create(ctx, loginToken); err != nil {
+		return "", fmt.Errorf("failed to create login token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (s *authService) LoginWithToken(ctx context.Context, token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	loginToken, err := s.loginTokenRepo.Consume(ctx, hashToken(token), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume login token: %w", err)
+	}
+	if loginToken == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := s.userRepo.GetByID(ctx, loginToken.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsAdmin {
+		return nil, ErrNotAdmin
+	}
+
+	return user, nil
+}
+
+func (s *authService) CreateSession(ctx context.Context, user *models.User, userAgent, ip string) (string, *models.AdminSession, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	session := &models.AdminSession{
+		TokenHash:  hashToken(token),
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		ExpiresAt:  now.Add(s.sessionTTL),
+		LastSeenAt: now,
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return "", nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	session.User = *user
+
+	return token + "." + s.sign(token), session, nil
+}
+
+func (s *authService) ValidateSession(ctx context.Context, cookie string) (*models.AdminSession, error) {
+	token, signature, ok := strings.Cut(cookie, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(token))) {
+		return nil, ErrSessionInvalid
+	}
+
+	session, err := s.sessionRepo.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	now := time.Now()
+	if session == nil || session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return nil, ErrSessionInvalid
+	}
+
+	// Права могли отобрать после входа
+	if !session.User.IsAdmin {
+		return nil, ErrNotAdmin
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := s.sessionRepo.Touch(ctx, session.ID, now); err != nil {
+			return nil, fmt.Errorf("failed to update session: %w", err)
+		}
+		session.LastSeenAt = now
+	}
+
+	return session, nil
+}
+
+func (s *authService) RevokeSession(ctx context.Context, sessionID uint) error {
+	return s.sessionRepo.Revoke(ctx, sessionID, time.Now())
+}
+
+func (s *authService) RevokeAllSessions(ctx context.Context, userID uint) error {
+	return s.sessionRepo.RevokeAllByUserID(ctx, userID, time.Now())
+}
+
+func (s *authService) adminByTelegramID(ctx context.Context, telegramID int64) (*models.User, error) {
+	isAdmin, err := s.userRepo.IsAdmin(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check admin: %w", err)
+	}
+	if !isAdmin {
+		return nil, ErrNotAdmin
+	}
+
+	return s.userRepo.GetByTelegramID(ctx, telegramID)
+}
+
+func (s *authService) sign(token string) string {
+	mac := hmac.New(sha256.New, s.sessionSecret)
+	mac.Write([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// checkTelegramAuth проверяет подпись Telegram Login Widget:
+// hash = HMAC-SHA256(строка "ключ=значение" по алфавиту через \n, ключ SHA256(токен бота))
+func checkTelegramAuth(data map[string]string, botToken string) bool {
+	hash, ok := data["hash"]
+	if !ok || hash == "" {
+		return false
+	}
+
+	pairs := make([]string, 0, len(data))
+	for key, value := range data {
+		if key == "hash" {
+			continue
+		}
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(pairs, "\n")))
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(hash))
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,43 +1,90 @@
 package service
 
 import (
+	"ai_support_tg_writer_bot/internal/events"
 	"ai_support_tg_writer_bot/internal/models"
 	"ai_support_tg_writer_bot/internal/repository"
+	"context"
 	"fmt"
+	"time"
 )
 
+// NewMessage - содержимое нового сообщения чата
+type NewMessage struct {
+	Content           string
+	TelegramChatID    int64 // Исходное сообщение в Telegram, если сообщение пришло оттуда
+	TelegramMessageID int
+	Files             []models.File
+	Quoted            *models.ChatMessage // Сообщение, на которое ответили через "Ответить"
+}
+
+// ClientChatState - что происходит с обращением клиента
+type ClientChatState string
+
+const (
+	ClientChatWaiting  ClientChatState = "waiting"  // Клиент написал, поддержка еще не прочитала
+	ClientChatRead     ClientChatState = "read"     // Поддержка прочитала, но еще не ответила
+	ClientChatAnswered ClientChatState = "answered" // Последнее сообщение - ответ поддержки
+	ClientChatClosed   ClientChatState = "closed"   // Чат архивирован
+)
+
+// ClientChatStatus - состояние последнего обращения клиента для команды /status
+type ClientChatStatus struct {
+	Chat          *models.Chat
+	State         ClientChatState
+	LastMessage   *models.ChatMessage
+	QueuePosition int // Место в очереди ожидающих ответа, 0 - не в очереди
+}
+
 type ChatService interface {
-	CreateOrGetChat(userID uint) (*models.Chat, error)
-	GetChatByID(id uint) (*models.Chat, error)
-	GetActiveChats() ([]models.Chat, error)
-	GetActiveChatsPaginated(limit, offset int) ([]models.Chat, error)
-	GetArchivedChats() ([]models.Chat, error)
-	GetArchivedChatsPaginated(limit, offset int) ([]models.Chat, error)
-	ArchiveChat(chatID uint) error
-	MarkChatAsRead(chatID uint) error
-	AddMessage(chatID uint, userID uint, content string, isFromUser bool) (*models.ChatMessage, error)
-	GetUnreadChatsCount() (int, error)
-	GetChatMessagesPaginated(chatID uint, limit, offset int) ([]models.ChatMessage, error)
-	GetChatMessagesCount(chatID uint) (int64, error)
+	CreateOrGetChat(ctx context.Context, userID uint) (*models.Chat, error)
+	GetChatByID(ctx context.Context, id uint) (*models.Chat, error)
+	GetChatWithUser(ctx context.Context, id uint) (*models.Chat, error)
+	GetChatByTopicID(ctx context.Context, topicID int) (*models.Chat, error)
+	SetChatTopic(ctx context.Context, chatID uint, topicID int) error
+	GetActiveChats(ctx context.Context) ([]models.Chat, error)
+	GetActiveChatsPaginated(ctx context.Context, limit, offset int) ([]models.Chat, error)
+	GetArchivedChats(ctx context.Context) ([]models.Chat, error)
+	GetArchivedChatsPaginated(ctx context.Context, limit, offset int) ([]models.Chat, error)
+	GetChatsCount(ctx context.Context, status models.ChatStatus) (int64, error)
+	ArchiveChat(ctx context.Context, chatID uint) error
+	MarkChatAsRead(ctx context.Context, chatID uint) error
+	AddMessage(ctx context.Context, chatID uint, userID uint, content string, isFromUser bool) (*models.ChatMessage, error)
+	PostMessage(ctx context.Context, chatID uint, userID uint, isFromUser bool, msg NewMessage) (*models.ChatMessage, error)
+	GetUnreadChatsCount(ctx context.Context) (int, error)
+	GetChatMessagesPaginated(ctx context.Context, chatID uint, limit, offset int) ([]models.ChatMessage, error)
+	GetChatMessagesCount(ctx context.Context, chatID uint) (int64, error)
+	AddMessageCopy(ctx context.Context, messageID uint, telegramChatID int64, telegramMessageID int, kind models.MessageCopyKind) error
+	GetMessageByID(ctx context.Context, id uint) (*models.ChatMessage, error)
+	GetMessageByTelegramID(ctx context.Context, telegramChatID int64, telegramMessageID int) (*models.ChatMessage, error)
+	ResolveTelegramMessage(ctx context.Context, telegramChatID int64, telegramMessageID int) (*models.ChatMessage, error)
+	FindTelegramMessageID(ctx context.Context, message *models.ChatMessage, telegramChatID int64) (int, error)
+	EditMessage(ctx context.Context, messageID uint, content string) (*models.ChatMessage, *models.ChatMessageRevision, error)
+	GetClientChat(ctx context.Context, userID uint) (*models.Chat, error)
+	GetClientStatus(ctx context.Context, userID uint) (*ClientChatStatus, error)
 }
 
 type chatService struct {
 	chatRepo        repository.ChatRepository
 	chatMessageRepo repository.ChatMessageRepository
 	fileRepo        repository.FileRepository
+	mappingRepo     repository.MessageMappingRepository
+	bus             *events.Bus
 }
 
-func NewChatService(chatRepo repository.ChatRepository, chatMessageRepo repository.ChatMessageRepository, fileRepo repository.FileRepository) ChatService {
+func NewChatService(chatRepo repository.ChatRepository, chatMessageRepo repository.ChatMessageRepository, fileRepo repository.FileRepository, mappingRepo repository.MessageMappingRepository, bus *events.Bus) ChatService {
 	return &chatService{
 		chatRepo:        chatRepo,
 		chatMessageRepo: chatMessageRepo,
 		fileRepo:        fileRepo,
+		mappingRepo:     mappingRepo,
+		bus:             bus,
 	}
 }
 
-func (s *chatService) CreateOrGetChat(userID uint) (*models.Chat, error) {
+func (s *chatService) CreateOrGetChat(ctx context.Context, userID uint) (*models.Chat, error) {
 	// Сначала пытаемся найти активный чат пользователя
-	chat, err := s.chatRepo.GetByUserID(userID)
+	chat, err := s.chatRepo.GetByUserID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chat: %w", err)
 	}
@@ -46,96 +93,295 @@ func (s *chatService) CreateOrGetChat(userID uint) (*models.Chat, error) {
 		return chat, nil
 	}
 
+	// Новый чат продолжает тему предыдущего, чтобы переписка с клиентом оставалась в одной теме
+	previous, err := s.chatRepo.GetLastByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous chat: %w", err)
+	}
+
+	topicID := 0
+	if previous != nil {
+		topicID = previous.TopicID
+	}
+
 	// Создаем новый чат
 	chat = &models.Chat{
 		UserID:      userID,
 		Status:      models.ChatStatusActive,
 		UnreadCount: 0,
+		TopicID:     topicID,
 	}
 
-	if err := s.chatRepo.Create(chat); err != nil {
+	if err := s.chatRepo.Create(ctx, chat); err != nil {
 		return nil, fmt.Errorf("failed to create chat: %w", err)
 	}
 
+	s.bus.Publish(ctx, events.ChatCreated{Chat: *chat})
+
 	return chat, nil
 }
 
-func (s *chatService) GetChatByID(id uint) (*models.Chat, error) {
-	return s.chatRepo.GetByID(id)
+func (s *chatService) GetChatByID(ctx context.Context, id uint) (*models.Chat, error) {
+	return s.chatRepo.GetByID(ctx, id)
+}
+
+// GetChatWithUser загружает чат с клиентом, но без истории: для заголовка, когда сообщения грузятся постранично
+func (s *chatService) GetChatWithUser(ctx context.Context, id uint) (*models.Chat, error) {
+	return s.chatRepo.GetWithUser(ctx, id)
 }
 
-func (s *chatService) GetActiveChats() ([]models.Chat, error) {
-	return s.chatRepo.GetActiveChats()
+func (s *chatService) GetChatByTopicID(ctx context.Context, topicID int) (*models.Chat, error) {
+	return s.chatRepo.GetByTopicID(ctx, topicID)
 }
 
-func (s *chatService) GetArchivedChats() ([]models.Chat, error) {
-	return s.chatRepo.GetArchivedChats()
+func (s *chatService) SetChatTopic(ctx context.Context, chatID uint, topicID int) error {
+	return s.chatRepo.UpdateTopicID(ctx, chatID, topicID)
+}
+
+func (s *chatService) GetActiveChats(ctx context.Context) ([]models.Chat, error) {
+	return s.chatRepo.GetActiveChats(ctx)
+}
+
+func (s *chatService) GetArchivedChats(ctx context.Context) ([]models.Chat, error) {
+	return s.chatRepo.GetArchivedChats(ctx)
+}
+
+func (s *chatService) ArchiveChat(ctx context.Context, chatID uint) error {
+	if err := s.chatRepo.ArchiveChat(ctx, chatID); err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, events.ChatArchived{ChatID: chatID})
+	return nil
 }
 
-func (s *chatService) ArchiveChat(chatID uint) error {
-	return s.chatRepo.ArchiveChat(chatID)
+func (s *chatService) MarkChatAsRead(ctx context.Context, chatID uint) error {
+	if err := s.chatRepo.MarkAsRead(ctx, chatID); err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, events.ChatRead{ChatID: chatID})
+	return nil
 }
 
-func (s *chatService) MarkChatAsRead(chatID uint) error {
-	return s.chatRepo.MarkAsRead(chatID)
+func (s *chatService) AddMessage(ctx context.Context, chatID uint, userID uint, content string, isFromUser bool) (*models.ChatMessage, error) {
+	return s.PostMessage(ctx, chatID, userID, isFromUser, NewMessage{Content: content})
 }
 
-func (s *chatService) AddMessage(chatID uint, userID uint, content string, isFromUser bool) (*models.ChatMessage, error) {
+// PostMessage сохраняет сообщение вместе с файлами и сообщает о нем подписчикам:
+// MessageReceived для сообщения клиента, ReplySent для ответа поддержки
+func (s *chatService) PostMessage(ctx context.Context, chatID uint, userID uint, isFromUser bool, msg NewMessage) (*models.ChatMessage, error) {
 	message := &models.ChatMessage{
-		ChatID:     chatID,
-		UserID:     userID,
-		Content:    content,
-		IsFromUser: isFromUser,
-		IsRead:     !isFromUser, // Сообщения от админа считаются прочитанными сразу
+		ChatID:            chatID,
+		UserID:            userID,
+		Content:           msg.Content,
+		IsFromUser:        isFromUser,
+		IsRead:            !isFromUser, // Сообщения от админа считаются прочитанными сразу
+		TelegramChatID:    msg.TelegramChatID,
+		TelegramMessageID: msg.TelegramMessageID,
+	}
+
+	// Ответы админа еще предстоит доставить клиенту
+	if !isFromUser {
+		message.DeliveryStatus = models.DeliveryStatusPending
 	}
 
-	if err := s.chatMessageRepo.Create(message); err != nil {
+	if err := s.chatMessageRepo.Create(ctx, message); err != nil {
 		return nil, fmt.Errorf("failed to create message: %w", err)
 	}
 
+	for i := range msg.Files {
+		file := msg.Files[i]
+		file.MessageID = message.ID
+		if err := s.fileRepo.Create(ctx, &file); err != nil {
+			return nil, fmt.Errorf("failed to create file: %w", err)
+		}
+		message.Files = append(message.Files, file)
+	}
+
 	// Обновляем время последнего сообщения
-	if err := s.chatRepo.UpdateLastMessageTime(chatID); err != nil {
+	if err := s.chatRepo.UpdateLastMessageTime(ctx, chatID); err != nil {
 		return nil, fmt.Errorf("failed to update last message time: %w", err)
 	}
 
 	// Если сообщение от пользователя, увеличиваем счетчик непрочитанных
 	if isFromUser {
-		if err := s.chatRepo.IncrementUnreadCount(chatID); err != nil {
+		if err := s.chatRepo.IncrementUnreadCount(ctx, chatID); err != nil {
 			return nil, fmt.Errorf("failed to increment unread count: %w", err)
 		}
 	}
 
+	// Цитировать можно только сообщения этого же чата
+	quoted := msg.Quoted
+	if quoted != nil && quoted.ChatID != chatID {
+		quoted = nil
+	}
+
+	if isFromUser {
+		s.bus.Publish(ctx, events.MessageReceived{Message: *message, Quoted: quoted})
+	} else {
+		s.bus.Publish(ctx, events.ReplySent{Message: *message})
+	}
+
 	return message, nil
 }
 
-func (s *chatService) GetUnreadChatsCount() (int, error) {
-	chats, err := s.chatRepo.GetActiveChats()
+func (s *chatService) GetUnreadChatsCount(ctx context.Context) (int, error) {
+	count, err := s.chatRepo.CountUnread(ctx)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("failed to count unread chats: %w", err)
 	}
 
-	count := 0
-	for _, chat := range chats {
-		if chat.UnreadCount > 0 {
-			count++
-		}
+	return int(count), nil
+}
+
+func (s *chatService) GetActiveChatsPaginated(ctx context.Context, limit, offset int) ([]models.Chat, error) {
+	return s.chatRepo.GetActiveChatsPaginated(ctx, limit, offset)
+}
+
+func (s *chatService) GetArchivedChatsPaginated(ctx context.Context, limit, offset int) ([]models.Chat, error) {
+	return s.chatRepo.GetArchivedChatsPaginated(ctx, limit, offset)
+}
+
+func (s *chatService) GetChatsCount(ctx context.Context, status models.ChatStatus) (int64, error) {
+	return s.chatRepo.CountByStatus(ctx, status)
+}
+
+func (s *chatService) GetChatMessagesPaginated(ctx context.Context, chatID uint, limit, offset int) ([]models.ChatMessage, error) {
+	return s.chatMessageRepo.GetByChatIDPaginated(ctx, chatID, limit, offset)
+}
+
+func (s *chatService) GetChatMessagesCount(ctx context.Context, chatID uint) (int64, error) {
+	return s.chatMessageRepo.GetCountByChatID(ctx, chatID)
+}
+
+// AddMessageCopy запоминает копию сообщения, отправленную ботом в чат Telegram
+func (s *chatService) AddMessageCopy(ctx context.Context, messageID uint, telegramChatID int64, telegramMessageID int, kind models.MessageCopyKind) error {
+	mapping := &models.MessageMapping{
+		MessageID:         messageID,
+		TelegramChatID:    telegramChatID,
+		TelegramMessageID: telegramMessageID,
+		Kind:              kind,
+	}
+
+	if err := s.mappingRepo.Create(ctx, mapping); err != nil {
+		return fmt.Errorf("failed to create message mapping: %w", err)
 	}
 
-	return count, nil
+	return nil
 }
 
-func (s *chatService) GetActiveChatsPaginated(limit, offset int) ([]models.Chat, error) {
-	return s.chatRepo.GetActiveChatsPaginated(limit, offset)
+// GetMessageByID загружает сообщение чата с автором, чатом и файлами
+func (s *chatService) GetMessageByID(ctx context.Context, id uint) (*models.ChatMessage, error) {
+	return s.chatMessageRepo.GetByID(ctx, id)
 }
 
-func (s *chatService) GetArchivedChatsPaginated(limit, offset int) ([]models.Chat, error) {
-	return s.chatRepo.GetArchivedChatsPaginated(limit, offset)
+// GetMessageByTelegramID ищет сообщение чата по исходному сообщению в Telegram
+func (s *chatService) GetMessageByTelegramID(ctx context.Context, telegramChatID int64, telegramMessageID int) (*models.ChatMessage, error) {
+	return s.chatMessageRepo.GetByTelegramMessage(ctx, telegramChatID, telegramMessageID)
 }
 
-func (s *chatService) GetChatMessagesPaginated(chatID uint, limit, offset int) ([]models.ChatMessage, error) {
-	return s.chatMessageRepo.GetByChatIDPaginated(chatID, limit, offset)
+// ResolveTelegramMessage ищет сообщение чата по исходному сообщению или по любой его копии в Telegram
+func (s *chatService) ResolveTelegramMessage(ctx context.Context, telegramChatID int64, telegramMessageID int) (*models.ChatMessage, error) {
+	message, err := s.chatMessageRepo.GetByTelegramMessage(ctx, telegramChatID, telegramMessageID)
+	if err != nil || message != nil {
+		return message, err
+	}
+
+	mapping, err := s.mappingRepo.GetByTelegramMessage(ctx, telegramChatID, telegramMessageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message mapping: %w", err)
+	}
+	if mapping == nil {
+		return nil, nil
+	}
+
+	return s.chatMessageRepo.GetByID(ctx, mapping.MessageID)
 }
 
-func (s *chatService) GetChatMessagesCount(chatID uint) (int64, error) {
-	return s.chatMessageRepo.GetCountByChatID(chatID)
+// FindTelegramMessageID возвращает message_id сообщения (исходного или копии) в указанном чате Telegram, 0 если его там нет
+func (s *chatService) FindTelegramMessageID(ctx context.Context, message *models.ChatMessage, telegramChatID int64) (int, error) {
+	if message.TelegramChatID == telegramChatID && message.TelegramMessageID != 0 {
+		return message.TelegramMessageID, nil
+	}
+
+	mappings, err := s.mappingRepo.GetByMessageID(ctx, message.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get message mappings: %w", err)
+	}
+
+	for _, mapping := range mappings {
+		if mapping.TelegramChatID == telegramChatID {
+			return mapping.TelegramMessageID, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// EditMessage сохраняет текущий текст сообщения как ревизию и заменяет его новым
+func (s *chatService) EditMessage(ctx context.Context, messageID uint, content string) (*models.ChatMessage, *models.ChatMessageRevision, error) {
+	message, err := s.chatMessageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	revision := &models.ChatMessageRevision{
+		MessageID: message.ID,
+		Content:   message.Content,
+	}
+	now := time.Now()
+	if err := s.chatMessageRepo.UpdateContent(ctx, revision, content, now); err != nil {
+		return nil, nil, fmt.Errorf("failed to update message: %w", err)
+	}
+
+	message.Content = content
+	message.EditedAt = &now
+
+	s.bus.Publish(ctx, events.MessageEdited{Message: *message, OldContent: revision.Content})
+
+	return message, revision, nil
+}
+
+// GetClientChat возвращает последнее обращение клиента, активное или архивированное; nil - клиент еще не писал
+func (s *chatService) GetClientChat(ctx context.Context, userID uint) (*models.Chat, error) {
+	chat, err := s.chatRepo.GetLastByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client chat: %w", err)
+	}
+	return chat, nil
+}
+
+// GetClientStatus описывает последнее обращение клиента; nil - клиент еще не писал
+func (s *chatService) GetClientStatus(ctx context.Context, userID uint) (*ClientChatStatus, error) {
+	chat, err := s.GetClientChat(ctx, userID)
+	if err != nil || chat == nil {
+		return nil, err
+	}
+
+	last, err := s.chatMessageRepo.GetLastMessageByChatID(ctx, chat.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last message: %w", err)
+	}
+
+	status := &ClientChatStatus{Chat: chat, LastMessage: last}
+	switch {
+	case chat.Status == models.ChatStatusArchived:
+		status.State = ClientChatClosed
+	case last != nil && !last.IsFromUser:
+		status.State = ClientChatAnswered
+	case last != nil && last.IsRead:
+		status.State = ClientChatRead
+	default:
+		status.State = ClientChatWaiting
+	}
+
+	if status.State == ClientChatWaiting || status.State == ClientChatRead {
+		status.QueuePosition, err = s.chatRepo.QueuePosition(ctx, chat.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get queue position: %w", err)
+		}
+	}
+
+	return status, nil
 }
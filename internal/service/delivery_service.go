@@ -0,0 +1,125 @@
+package service
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/repository"
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// Максимальное число попыток доставки одного сообщения
+	MaxDeliveryAttempts = 8
+	// Базовая задержка перед повторной отправкой, удваивается с каждой попыткой
+	deliveryBaseBackoff = 5 * time.Second
+	// Максимальная задержка между попытками
+	deliveryMaxBackoff = 30 * time.Minute
+)
+
+type DeliveryService interface {
+	MarkSent(ctx context.Context, messageID uint) error
+	MarkUndelivered(ctx context.Context, messageID uint, status models.DeliveryStatus, reason string) error
+	EnqueueRetry(ctx context.Context, job *models.DeliveryJob, reason string, retryAfter time.Duration) error
+	GetDueJobs(ctx context.Context, limit int) ([]models.DeliveryJob, error)
+	GetQueuedCount(ctx context.Context) (int64, error)
+	CompleteJob(ctx context.Context, job *models.DeliveryJob) error
+	RescheduleJob(ctx context.Context, job *models.DeliveryJob, reason string, retryAfter time.Duration) (bool, error)
+	FailJob(ctx context.Context, job *models.DeliveryJob, status models.DeliveryStatus, reason string) error
+}
+
+type deliveryService struct {
+	jobRepo         repository.DeliveryJobRepository
+	chatMessageRepo repository.ChatMessageRepository
+}
+
+func NewDeliveryService(jobRepo repository.DeliveryJobRepository, chatMessageRepo repository.ChatMessageRepository) DeliveryService {
+	return &deliveryService{
+		jobRepo:         jobRepo,
+		chatMessageRepo: chatMessageRepo,
+	}
+}
+
+// MarkSent отмечает сообщение как доставленное клиенту
+func (s *deliveryService) MarkSent(ctx context.Context, messageID uint) error {
+	return s.chatMessageRepo.UpdateDeliveryStatus(ctx, messageID, models.DeliveryStatusSent, "")
+}
+
+// MarkUndelivered отмечает сообщение как окончательно не доставленное
+func (s *deliveryService) MarkUndelivered(ctx context.Context, messageID uint, status models.DeliveryStatus, reason string) error {
+	return s.chatMessageRepo.UpdateDeliveryStatus(ctx, messageID, status, reason)
+}
+
+// EnqueueRetry ставит неудавшуюся отправку в очередь повторных попыток
+func (s *deliveryService) EnqueueRetry(ctx context.Context, job *models.DeliveryJob, reason string, retryAfter time.Duration) error {
+	job.Status = models.DeliveryJobQueued
+	job.Attempts = 1
+	job.LastError = reason
+	job.NextAttemptAt = time.Now().Add(retryDelay(job.Attempts, retryAfter))
+
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return fmt.Errorf("failed to create delivery job: %w", err)
+	}
+
+	return s.chatMessageRepo.UpdateDeliveryStatus(ctx, job.MessageID, models.DeliveryStatusPending, reason)
+}
+
+func (s *deliveryService) GetDueJobs(ctx context.Context, limit int) ([]models.DeliveryJob, error) {
+	return s.jobRepo.GetDue(ctx, time.Now(), limit)
+}
+
+// GetQueuedCount возвращает число сообщений, ожидающих повторной отправки
+func (s *deliveryService) GetQueuedCount(ctx context.Context) (int64, error) {
+	return s.jobRepo.CountQueued(ctx)
+}
+
+// CompleteJob закрывает задачу после успешной доставки
+func (s *deliveryService) CompleteJob(ctx context.Context, job *models.DeliveryJob) error {
+	job.Status = models.DeliveryJobDone
+	job.Attempts++
+	job.LastError = ""
+	if err := s.jobRepo.Update(ctx, job); err != nil {
+		return fmt.Errorf("failed to update delivery job: %w", err)
+	}
+
+	return s.MarkSent(ctx, job.MessageID)
+}
+
+// RescheduleJob откладывает следующую попытку. Возвращает false, если попытки исчерпаны и задача провалена.
+func (s *deliveryService) RescheduleJob(ctx context.Context, job *models.DeliveryJob, reason string, retryAfter time.Duration) (bool, error) {
+	job.Attempts++
+	if job.Attempts >= MaxDeliveryAttempts {
+		return false, s.FailJob(ctx, job, models.DeliveryStatusFailed, reason)
+	}
+
+	job.LastError = reason
+	job.NextAttemptAt = time.Now().Add(retryDelay(job.Attempts, retryAfter))
+	if err := s.jobRepo.Update(ctx, job); err != nil {
+		return false, fmt.Errorf("failed to update delivery job: %w", err)
+	}
+
+	return true, nil
+}
+
+// FailJob закрывает задачу без доставки. Если клиент уже получил часть ответа, ответ доставлен частично.
+func (s *deliveryService) FailJob(ctx context.Context, job *models.DeliveryJob, status models.DeliveryStatus, reason string) error {
+	job.Status = models.DeliveryJobFailed
+	job.LastError = reason
+	if err := s.jobRepo.Update(ctx, job); err != nil {
+		return fmt.Errorf("failed to update delivery job: %w", err)
+	}
+
+	return s.MarkUndelivered(ctx, job.MessageID, job.UndeliveredStatus(status), reason)
+}
+
+// retryDelay рассчитывает экспоненциальную задержку, но не меньше retry_after от Telegram
+func retryDelay(attempts int, retryAfter time.Duration) time.Duration {
+	delay := deliveryBaseBackoff << (attempts - 1)
+	if delay > deliveryMaxBackoff || delay <= 0 {
+		delay = deliveryMaxBackoff
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
@@ -3,14 +3,15 @@ package service
 import (
 	"ai_support_tg_writer_bot/internal/models"
 	"ai_support_tg_writer_bot/internal/repository"
+	"context"
 	"fmt"
 )
 
 type FileService interface {
-	CreateFile(messageID uint, fileID, fileName, fileType string, fileSize int64) (*models.File, error)
-	GetFileByID(id uint) (*models.File, error)
-	GetFilesByMessageID(messageID uint) ([]models.File, error)
-	DeleteFile(id uint) error
+	CreateFile(ctx context.Context, messageID uint, fileID, fileName, fileType string, fileSize int64) (*models.File, error)
+	GetFileByID(ctx context.Context, id uint) (*models.File, error)
+	GetFilesByMessageID(ctx context.Context, messageID uint) ([]models.File, error)
+	DeleteFile(ctx context.Context, id uint) error
 }
 
 type fileService struct {
@@ -23,7 +24,7 @@ func NewFileService(fileRepo repository.FileRepository) FileService {
 	}
 }
 
-func (s *fileService) CreateFile(messageID uint, fileID, fileName, fileType string, fileSize int64) (*models.File, error) {
+func (s *fileService) CreateFile(ctx context.Context, messageID uint, fileID, fileName, fileType string, fileSize int64) (*models.File, error) {
 	file := &models.File{
 		MessageID: messageID,
 		FileID:    fileID,
@@ -32,21 +33,21 @@ func (s *fileService) CreateFile(messageID uint, fileID, fileName, fileType stri
 		FileSize:  fileSize,
 	}
 
-	if err := s.fileRepo.Create(file); err != nil {
+	if err := s.fileRepo.Create(ctx, file); err != nil {
 		return nil, fmt.Errorf("failed to create file: %w", err)
 	}
 
 	return file, nil
 }
 
-func (s *fileService) GetFileByID(id uint) (*models.File, error) {
-	return s.fileRepo.GetByID(id)
+func (s *fileService) GetFileByID(ctx context.Context, id uint) (*models.File, error) {
+	return s.fileRepo.GetByID(ctx, id)
 }
 
-func (s *fileService) GetFilesByMessageID(messageID uint) ([]models.File, error) {
-	return s.fileRepo.GetByMessageID(messageID)
+func (s *fileService) GetFilesByMessageID(ctx context.Context, messageID uint) ([]models.File, error) {
+	return s.fileRepo.GetByMessageID(ctx, messageID)
 }
 
-func (s *fileService) DeleteFile(id uint) error {
-	return s.fileRepo.Delete(id)
+func (s *fileService) DeleteFile(ctx context.Context, id uint) error {
+	return s.fileRepo.Delete(ctx, id)
 }
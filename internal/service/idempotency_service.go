@@ -0,0 +1,102 @@
+package service
+
+import (
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Сколько держится бронь ключа без ответа. Запрос, оборванный падением процесса или таймаутом,
+// не сохраняет ответ и не освобождает ключ - после этого срока его повтор выполняется заново.
+const idempotencyLease = 5 * time.Minute
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is still in progress")
+)
+
+type IdempotencyService interface {
+	// Begin занимает ключ под новый запрос или перехватывает просроченную бронь. Если запрос с этим ключом
+	// уже выполнен, возвращает его запись с заполненным StatusCode - ответ нужно отдать повторно.
+	Begin(ctx context.Context, client, key, requestHash string) (*models.IdempotencyKey, error)
+	// Complete сохраняет ответ на выполненный запрос
+	Complete(ctx context.Context, record *models.IdempotencyKey, statusCode int, response string) error
+	// Release освобождает ключ, если запрос не выполнился и его можно повторить
+	Release(ctx context.Context, record *models.IdempotencyKey) error
+}
+
+type idempotencyService struct {
+	repo repository.IdempotencyKeyRepository
+}
+
+func NewIdempotencyService(repo repository.IdempotencyKeyRepository) IdempotencyService {
+	return &idempotencyService{repo: repo}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, client, key, requestHash string) (*models.IdempotencyKey, error) {
+	leaseToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.IdempotencyKey{
+		Client:      client,
+		Key:         key,
+		RequestHash: requestHash,
+		LeaseToken:  leaseToken,
+	}
+
+	reserved, err := s.repo.Reserve(ctx, record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if reserved {
+		return record, nil
+	}
+
+	existing, err := s.repo.GetByKey(ctx, client, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	if existing == nil {
+		// Ключ освободили между попытками - просим клиента повторить
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	if existing.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if existing.StatusCode != 0 {
+		return existing, nil
+	}
+
+	// Ответа нет: запрос еще выполняется или оборвался, не успев ни сохранить ответ, ни освободить ключ
+	if time.Since(existing.CreatedAt) < idempotencyLease {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	taken, err := s.repo.TakeOver(ctx, existing.ID, leaseToken, time.Now().Add(-idempotencyLease))
+	if err != nil {
+		return nil, fmt.Errorf("failed to take over idempotency key: %w", err)
+	}
+	if !taken {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	existing.LeaseToken = leaseToken
+	return existing, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, record *models.IdempotencyKey, statusCode int, response string) error {
+	if err := s.repo.Complete(ctx, record.ID, record.LeaseToken, statusCode, response); err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", err)
+	}
+	record.StatusCode = statusCode
+	record.Response = response
+	return nil
+}
+
+func (s *idempotencyService) Release(ctx context.Context, record *models.IdempotencyKey) error {
+	return s.repo.Delete(ctx, record.ID, record.LeaseToken)
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"ai_support_tg_writer_bot/internal/format"
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Максимальная длина текста сообщения в Telegram
+const maxTemplateLength = 4096
+
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// TemplateKeys - шаблоны, которые можно менять
+var TemplateKeys = []string{
+	models.TemplateWelcome,
+	models.TemplateMessageReceived,
+	models.TemplateOutsideBusinessHours,
+	models.TemplateSupportReply,
+}
+
+// TemplateData - переменные шаблонов: {{.FirstName}}, {{.ChatID}} и т.д.
+type TemplateData struct {
+	Name      string // Имя и фамилия клиента, без них - username
+	FirstName string
+	LastName  string
+	Username  string // С ведущим "@", может быть пустым
+	ChatID    uint   // Номер чата поддержки, 0 - чата еще нет (например, в ответе на /start)
+	Operator  string // Имя ответившего сотрудника, только в support_reply
+}
+
+// NewTemplateData собирает переменные шаблона по клиенту и чату
+func NewTemplateData(user *models.User, chatID uint) TemplateData {
+	return TemplateData{
+		Name:      displayName(user),
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Username:  user.Username,
+		ChatID:    chatID,
+	}
+}
+
+// NewReplyTemplateData - переменные заголовка ответа поддержки; operator может быть nil
+func NewReplyTemplateData(client *models.User, chatID uint, operator *models.User) TemplateData {
+	data := NewTemplateData(client, chatID)
+	if operator != nil {
+		data.Operator = displayName(operator)
+	}
+	return data
+}
+
+func displayName(user *models.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		name = user.Username
+	}
+	return name
+}
+
+// RenderTemplate подставляет переменные в шаблон. Шаблон - разметка HTML Telegram,
+// переменные экранируются, поэтому имя клиента не может сломать или подменить разметку.
+func RenderTemplate(body string, data TemplateData) (string, error) {
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	if err := tmpl.Execute(&text, data); err != nil {
+		return "", err
+	}
+	return text.String(), nil
+}
+
+type TemplateService interface {
+	// Get возвращает измененный админами шаблон или nil, если используется текст по умолчанию
+	Get(ctx context.Context, key, language string) (*models.MessageTemplate, error)
+	GetAll(ctx context.Context) ([]models.MessageTemplate, error)
+	// Preview проверяет шаблон и возвращает текст с подставленными data
+	Preview(key, body string, data TemplateData) (string, error)
+	// Save сохраняет новую версию шаблона; пустой body возвращает текст по умолчанию
+	Save(ctx context.Context, key, language, body string, authorID uint) (*models.MessageTemplate, error)
+	// History возвращает последние версии шаблона, новые первыми
+	History(ctx context.Context, key, language string, limit int) ([]models.MessageTemplateVersion, error)
+	GetVersion(ctx context.Context, key, language string, version int) (*models.MessageTemplateVersion, error)
+}
+
+type templateService struct {
+	templateRepo repository.TemplateRepository
+}
+
+func NewTemplateService(templateRepo repository.TemplateRepository) TemplateService {
+	return &templateService{templateRepo: templateRepo}
+}
+
+func (s *templateService) Get(ctx context.Context, key, language string) (*models.MessageTemplate, error) {
+	tpl, err := s.templateRepo.Get(ctx, key, language)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	if tpl.Body == "" {
+		return nil, nil
+	}
+	return tpl, nil
+}
+
+func (s *templateService) GetAll(ctx context.Context) ([]models.MessageTemplate, error) {
+	return s.templateRepo.GetAll(ctx)
+}
+
+func (s *templateService) Preview(key, body string, data TemplateData) (string, error) {
+	if !IsTemplateKey(key) {
+		return "", fmt.Errorf("%w: unknown template %q", ErrInvalidTemplate, key)
+	}
+	if utf8.RuneCountInString(body) > maxTemplateLength {
+		return "", fmt.Errorf("%w: longer than %d characters", ErrInvalidTemplate, maxTemplateLength)
+	}
+
+	text, err := RenderTemplate(body, data)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	if err := format.Validate(text); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	// Telegram считает длину без тегов
+	if format.Len(text) > format.MessageLimit {
+		return "", fmt.Errorf("%w: text is longer than %d characters", ErrInvalidTemplate, format.MessageLimit)
+	}
+	return text, nil
+}
+
+func (s *templateService) Save(ctx context.Context, key, language, body string, authorID uint) (*models.MessageTemplate, error) {
+	if body != "" {
+		// Проверяем на примере, что шаблон разбирается и не ссылается на несуществующие переменные
+		if _, err := s.Preview(key, body, TemplateData{}); err != nil {
+			return nil, err
+		}
+	} else if !IsTemplateKey(key) {
+		return nil, fmt.Errorf("%w: unknown template %q", ErrInvalidTemplate, key)
+	}
+
+	tpl, err := s.templateRepo.SaveVersion(ctx, key, language, body, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save template: %w", err)
+	}
+	return tpl, nil
+}
+
+func (s *templateService) History(ctx context.Context, key, language string, limit int) ([]models.MessageTemplateVersion, error) {
+	tpl, err := s.templateRepo.Get(ctx, key, language)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	versions, err := s.templateRepo.GetVersions(ctx, tpl.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template versions: %w", err)
+	}
+	return versions, nil
+}
+
+func (s *templateService) GetVersion(ctx context.Context, key, language string, version int) (*models.MessageTemplateVersion, error) {
+	tpl, err := s.templateRepo.Get(ctx, key, language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return s.templateRepo.GetVersion(ctx, tpl.ID, version)
+}
+
+// IsTemplateKey проверяет, что шаблон с таким ключом можно редактировать
+func IsTemplateKey(key string) bool {
+	for _, k := range TemplateKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
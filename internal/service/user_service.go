@@ -3,16 +3,27 @@ package service
 import (
 	"ai_support_tg_writer_bot/internal/models"
 	"ai_support_tg_writer_bot/internal/repository"
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+
+	"gorm.io/gorm"
 )
 
 type UserService interface {
-	CreateOrGetUser(telegramID int64, username, firstName, lastName string) (*models.User, error)
-	GetUserByTelegramID(telegramID int64) (*models.User, error)
-	IsAdmin(telegramID int64) (bool, error)
-	SetAdmin(telegramID int64, isAdmin bool) error
-	GetAllAdmins() ([]models.User, error)
+	CreateOrGetUser(ctx context.Context, telegramID int64, username, firstName, lastName string) (*models.User, error)
+	GetUserByTelegramID(ctx context.Context, telegramID int64) (*models.User, error)
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+	// GetSystemUser возвращает служебного пользователя для сообщений, отправленных через API
+	GetSystemUser(ctx context.Context) (*models.User, error)
+	IsAdmin(ctx context.Context, telegramID int64) (bool, error)
+	SetAdmin(ctx context.Context, telegramID int64, isAdmin bool) error
+	GetAllAdmins(ctx context.Context) ([]models.User, error)
+	// SyncAdmins приводит права админов в базе к списку из конфигурации: веб-панель проверяет права по базе
+	SyncAdmins(ctx context.Context, telegramIDs []int64) error
+	// SetLanguage сохраняет язык, на котором бот пишет пользователю
+	SetLanguage(ctx context.Context, telegramID int64, language string) error
 }
 
 type userService struct {
@@ -25,20 +36,20 @@ func NewUserService(userRepo repository.UserRepository) UserService {
 	}
 }
 
-func (s *userService) CreateOrGetUser(telegramID int64, username, firstName, lastName string) (*models.User, error) {
+func (s *userService) CreateOrGetUser(ctx context.Context, telegramID int64, username, firstName, lastName string) (*models.User, error) {
 	// Добавляем @ к username если его нет
 	if username != "" && !strings.HasPrefix(username, "@") {
 		username = "@" + username
 	}
 
 	// Сначала пытаемся найти существующего пользователя
-	user, err := s.userRepo.GetByTelegramID(telegramID)
+	user, err := s.userRepo.GetByTelegramID(ctx, telegramID)
 	if err == nil {
 		// Пользователь найден, обновляем информацию
 		user.Username = username
 		user.FirstName = firstName
 		user.LastName = lastName
-		if err := s.userRepo.Update(user); err != nil {
+		if err := s.userRepo.Update(ctx, user); err != nil {
 			return nil, fmt.Errorf("failed to update user: %w", err)
 		}
 		return user, nil
@@ -53,31 +64,69 @@ func (s *userService) CreateOrGetUser(telegramID int64, username, firstName, las
 		IsAdmin:    false,
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
 	return user, nil
 }
 
-func (s *userService) GetUserByTelegramID(telegramID int64) (*models.User, error) {
-	return s.userRepo.GetByTelegramID(telegramID)
+func (s *userService) GetUserByTelegramID(ctx context.Context, telegramID int64) (*models.User, error) {
+	return s.userRepo.GetByTelegramID(ctx, telegramID)
 }
 
-func (s *userService) IsAdmin(telegramID int64) (bool, error) {
-	return s.userRepo.IsAdmin(telegramID)
+func (s *userService) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	return s.userRepo.GetByID(ctx, id)
 }
 
-func (s *userService) SetAdmin(telegramID int64, isAdmin bool) error {
-	user, err := s.userRepo.GetByTelegramID(telegramID)
+func (s *userService) GetSystemUser(ctx context.Context) (*models.User, error) {
+	user, err := s.userRepo.GetByTelegramID(ctx, models.SystemTelegramID)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get system user: %w", err)
+	}
+
+	user = &models.User{
+		TelegramID: models.SystemTelegramID,
+		FirstName:  "API",
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create system user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (s *userService) IsAdmin(ctx context.Context, telegramID int64) (bool, error) {
+	return s.userRepo.IsAdmin(ctx, telegramID)
+}
+
+func (s *userService) SetAdmin(ctx context.Context, telegramID int64, isAdmin bool) error {
+	user, err := s.userRepo.GetByTelegramID(ctx, telegramID)
 	if err != nil {
 		return fmt.Errorf("user not found: %w", err)
 	}
 
 	user.IsAdmin = isAdmin
-	return s.userRepo.Update(user)
+	return s.userRepo.Update(ctx, user)
+}
+
+func (s *userService) GetAllAdmins(ctx context.Context) ([]models.User, error) {
+	return s.userRepo.GetAllAdmins(ctx)
 }
 
-func (s *userService) GetAllAdmins() ([]models.User, error) {
-	return s.userRepo.GetAllAdmins()
+func (s *userService) SyncAdmins(ctx context.Context, telegramIDs []int64) error {
+	if err := s.userRepo.SetAdmins(ctx, telegramIDs); err != nil {
+		return fmt.Errorf("failed to sync admins: %w", err)
+	}
+	return nil
+}
+
+func (s *userService) SetLanguage(ctx context.Context, telegramID int64, language string) error {
+	if err := s.userRepo.SetLanguage(ctx, telegramID, language); err != nil {
+		return fmt.Errorf("failed to set user language: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"ai_support_tg_writer_bot/internal/dto"
+	"ai_support_tg_writer_bot/internal/events"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/repository"
+	"ai_support_tg_writer_bot/pkg/api"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Максимальное число попыток доставки события на вебхук
+	MaxWebhookAttempts = 10
+	// Базовая задержка перед повторной отправкой, удваивается с каждой попыткой
+	webhookBaseBackoff = 10 * time.Second
+	// Максимальная задержка между попытками
+	webhookMaxBackoff = time.Hour
+	// Сколько ждать ответа получателя
+	webhookRequestTimeout = 10 * time.Second
+	// Как часто проверять очередь доставок
+	webhookPollInterval = 5 * time.Second
+	// Сколько доставок обрабатывать за один проход
+	webhookBatchSize = 20
+	// Сколько байт ответа получателя сохранять в журнале
+	webhookResponseLimit = 1024
+)
+
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// WebhookEvents - события, которые можно отправлять на вебхуки
+var WebhookEvents = []events.Name{
+	events.ChatCreatedEvent,
+	events.MessageReceivedEvent,
+	events.ReplySentEvent,
+	events.ChatArchivedEvent,
+}
+
+// WebhookInput - настройки вебхука, которые задает админ
+type WebhookInput struct {
+	URL         string
+	Events      []string // Пусто - все события из WebhookEvents
+	Secret      string   // Пусто - при создании сгенерировать, при изменении оставить прежний
+	Description string
+	IsActive    bool
+}
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, input WebhookInput) (*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, id uint, input WebhookInput) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id uint) error
+	GetWebhook(ctx context.Context, id uint) (*models.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetDeliveriesPaginated(ctx context.Context, webhookID uint, limit, offset int) ([]models.WebhookDelivery, error)
+	GetDeliveriesCount(ctx context.Context, webhookID uint) (int64, error)
+	// Redeliver ставит событие из журнала в очередь повторно, отдельной записью
+	Redeliver(ctx context.Context, webhookID, deliveryID uint) (*models.WebhookDelivery, error)
+	// Run отправляет события из очереди, пока работает приложение
+	Run()
+}
+
+type webhookService struct {
+	webhookRepo  repository.WebhookRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+	chatRepo     repository.ChatRepository
+	client       *http.Client
+	wake         chan struct{}
+	logger       *slog.Logger
+}
+
+func NewWebhookService(webhookRepo repository.WebhookRepository, deliveryRepo repository.WebhookDeliveryRepository, chatRepo repository.ChatRepository, bus *events.Bus, logger *slog.Logger) WebhookService {
+	s := &webhookService{
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+		chatRepo:     chatRepo,
+		client:       &http.Client{Timeout: webhookRequestTimeout},
+		wake:         make(chan struct{}, 1),
+		logger:       logger.With("component", "webhooks"),
+	}
+
+	// Асинхронно: получатели не должны задерживать обработку сообщений
+	bus.Subscribe("webhooks", events.Async, s.handleEvent, WebhookEvents...)
+
+	return s
+}
+
+func (s *webhookService) CreateWebhook(ctx context.Context, input WebhookInput) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	if err := applyWebhookInput(webhook, input); err != nil {
+		return nil, err
+	}
+
+	if webhook.Secret == "" {
+		secret, err := randomToken()
+		if err != nil {
+			return nil, err
+		}
+		webhook.Secret = secret
+	}
+
+	if err := s.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (s *webhookService) UpdateWebhook(ctx context.Context, id uint, input WebhookInput) (*models.Webhook, error) {
+	webhook, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	if err := applyWebhookInput(webhook, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.webhookRepo.Update(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, id uint) error {
+	return s.webhookRepo.Delete(ctx, id)
+}
+
+func (s *webhookService) GetWebhook(ctx context.Context, id uint) (*models.Webhook, error) {
+	return s.webhookRepo.GetByID(ctx, id)
+}
+
+func (s *webhookService) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	return s.webhookRepo.GetAll(ctx)
+}
+
+func (s *webhookService) GetDeliveriesPaginated(ctx context.Context, webhookID uint, limit, offset int) ([]models.WebhookDelivery, error) {
+	return s.deliveryRepo.GetByWebhookIDPaginated(ctx, webhookID, limit, offset)
+}
+
+func (s *webhookService) GetDeliveriesCount(ctx context.Context, webhookID uint) (int64, error) {
+	return s.deliveryRepo.GetCountByWebhookID(ctx, webhookID)
+}
+
+func (s *webhookService) Redeliver(ctx context.Context, webhookID, deliveryID uint) (*models.WebhookDelivery, error) {
+	original, err := s.deliveryRepo.GetByID(ctx, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	if original.WebhookID != webhookID {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	delivery := &models.WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := s.deliveryRepo.Create(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	s.wakeWorker()
+	return delivery, nil
+}
+
+func (s *webhookService) Run() {
+	ctx := logging.WithLogger(context.Background(), s.logger)
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		}
+		s.processDue(ctx)
+	}
+}
+
+// wakeWorker будит воркер, не дожидаясь очередного тика
+func (s *webhookService) wakeWorker() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// handleEvent записывает событие в журнал каждого подходящего вебхука
+func (s *webhookService) handleEvent(ctx context.Context, event events.Event) {
+	name := string(event.EventName())
+	logger := logging.FromContext(ctx).With("component", "webhooks", "event", name)
+
+	webhooks, err := s.webhookRepo.GetActive(ctx)
+	if err != nil {
+		logger.Error("Failed to get webhooks", "error", err)
+		return
+	}
+
+	var targets []models.Webhook
+	for _, webhook := range webhooks {
+		if webhookWantsEvent(&webhook, name) {
+			targets = append(targets, webhook)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	payload, err := s.buildPayload(ctx, event)
+	if err != nil {
+		logger.Error("Failed to build webhook payload", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for _, webhook := range targets {
+		delivery := &models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         name,
+			Payload:       string(payload),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		}
+		if err := s.deliveryRepo.Create(ctx, delivery); err != nil {
+			logger.Error("Failed to create webhook delivery", "webhook_id", webhook.ID, "error", err)
+		}
+	}
+
+	s.wakeWorker()
+}
+
+func (s *webhookService) processDue(ctx context.Context) {
+	deliveries, err := s.deliveryRepo.GetDue(ctx, time.Now(), webhookBatchSize)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get webhook deliveries", "error", err)
+		return
+	}
+
+	for i := range deliveries {
+		s.attempt(ctx, &deliveries[i])
+	}
+}
+
+// attempt выполняет одну попытку доставки и планирует следующую при неудаче
+func (s *webhookService) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	ctx = logging.With(ctx, "webhook_id", delivery.WebhookID, "delivery_id", delivery.ID)
+	webhook, err := s.webhookRepo.GetByID(ctx, delivery.WebhookID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = "webhook deleted"
+		s.saveDelivery(ctx, delivery)
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get webhook", "error", err)
+		return
+	}
+	if !webhook.IsActive {
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = "webhook disabled"
+		s.saveDelivery(ctx, delivery)
+		return
+	}
+
+	delivery.Attempts++
+	code, body, err := s.post(ctx, webhook, delivery)
+	delivery.ResponseCode = code
+	delivery.ResponseBody = body
+
+	if err == nil && code >= 200 && code < 300 {
+		now := time.Now()
+		delivery.Status = models.WebhookDeliverySuccess
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		s.saveDelivery(ctx, delivery)
+		return
+	}
+
+	if err != nil {
+		delivery.LastError = err.Error()
+	} else {
+		delivery.LastError = fmt.Sprintf("unexpected status %d", code)
+	}
+
+	if delivery.Attempts >= MaxWebhookAttempts {
+		delivery.Status = models.WebhookDeliveryFailed
+		logging.FromContext(ctx).Warn("Webhook delivery failed", "url", webhook.URL, "attempts", delivery.Attempts, "error", delivery.LastError)
+	} else {
+		delivery.NextAttemptAt = time.Now().Add(webhookRetryDelay(delivery.Attempts))
+	}
+	s.saveDelivery(ctx, delivery)
+}
+
+// post отправляет тело доставки с подписью:
+// X-Webhook-Signature = "sha256=" + hex(HMAC-SHA256(секрет, timestamp + "." + тело))
+func (s *webhookService) post(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write([]byte(timestamp + "." + delivery.Payload))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ai-support-bot-webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	return resp.StatusCode, string(body), nil
+}
+
+func (s *webhookService) saveDelivery(ctx context.Context, delivery *models.WebhookDelivery) {
+	if err := s.deliveryRepo.Update(ctx, delivery); err != nil {
+		logging.FromContext(ctx).Error("Failed to update webhook delivery", "error", err)
+	}
+}
+
+func (s *webhookService) buildPayload(ctx context.Context, event events.Event) ([]byte, error) {
+	// Чат перечитываем: в событии может не быть клиента, а архивация передает только ID
+	chat, err := s.chatRepo.GetWithUser(ctx, events.ChatIDOf(event))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	payload := api.WebhookEvent{
+		Event:      string(event.EventName()),
+		OccurredAt: events.OccurredAt(ctx),
+		Chat:       dto.Chat(chat),
+	}
+
+	switch e := event.(type) {
+	case events.MessageReceived:
+		message := dto.Message(&e.Message)
+		payload.Message = &message
+	case events.ReplySent:
+		// Ответ публикуется до доставки клиенту, поэтому ее статус здесь еще ничего не значит
+		message := dto.Message(&e.Message)
+		message.DeliveryStatus = ""
+		message.DeliveryError = ""
+		payload.Message = &message
+	}
+
+	return json.Marshal(payload)
+}
+
+// applyWebhookInput проверяет настройки и переносит их в модель
+func applyWebhookInput(webhook *models.Webhook, input WebhookInput) error {
+	target, err := url.Parse(strings.TrimSpace(input.URL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+	}
+
+	names := make([]string, 0, len(input.Events))
+	for _, name := range input.Events {
+		name = strings.TrimSpace(name)
+		if !isWebhookEvent(name) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, name)
+		}
+		names = append(names, name)
+	}
+
+	webhook.URL = target.String()
+	webhook.Events = strings.Join(names, ",")
+	webhook.Description = input.Description
+	webhook.IsActive = input.IsActive
+	if input.Secret != "" {
+		webhook.Secret = input.Secret
+	}
+	return nil
+}
+
+func isWebhookEvent(name string) bool {
+	for _, known := range WebhookEvents {
+		if string(known) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func webhookWantsEvent(webhook *models.Webhook, name string) bool {
+	if webhook.Events == "" {
+		return true
+	}
+	for _, wanted := range strings.Split(webhook.Events, ",") {
+		if wanted == name {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookRetryDelay рассчитывает экспоненциальную задержку перед следующей попыткой
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookBaseBackoff << (attempts - 1)
+	if delay > webhookMaxBackoff || delay <= 0 {
+		delay = webhookMaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"ai_support_tg_writer_bot/internal/dto"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/service"
+	"ai_support_tg_writer_bot/pkg/api"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Отчет о работе поддержки: ?period=today|7d|30d|365d или ?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *WebHandlers) GetAnalytics(c *gin.Context) {
+	ctx := c.Request.Context()
+	from, to, err := h.analyticsPeriod(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, api.Error{Error: err.Error()})
+		return
+	}
+
+	report, err := h.analyticsService.GetReport(ctx, from, to)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPeriod) {
+			c.JSON(http.StatusBadRequest, api.Error{Error: err.Error()})
+			return
+		}
+		logging.FromContext(ctx).Error("Failed to build analytics report", "error", err)
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to get analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Analytics(report))
+}
+
+// analyticsPeriod берет явные даты from/to, а без них - именованный период
+func (h *WebHandlers) analyticsPeriod(c *gin.Context) (time.Time, time.Time, error) {
+	fromParam := c.Query("from")
+	if fromParam == "" {
+		return h.analyticsService.PeriodRange(c.Query("period"))
+	}
+
+	from, err := time.Parse("2006-01-02", fromParam)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid from date, expected YYYY-MM-DD")
+	}
+
+	// to включительно: отчет идет до конца этого дня
+	to := time.Now().UTC()
+	if toParam := c.Query("to"); toParam != "" {
+		day, err := time.Parse("2006-01-02", toParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid to date, expected YYYY-MM-DD")
+		}
+		to = day.AddDate(0, 0, 1)
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,212 @@
+package web
+
+import (
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/service"
+	"ai_support_tg_writer_bot/pkg/api"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyHeader         = "X-API-Key"
+	idempotencyKeyHeader = "Idempotency-Key"
+	// Максимальная длина ключа идемпотентности
+	maxIdempotencyKeyLength = 255
+	// Максимальный размер тела запроса публичного API
+	maxAPIRequestSize = 64 << 10
+)
+
+// Middleware публичного API: пускает только с ключом из API_KEYS
+func (h *WebHandlers) APIKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader(apiKeyHeader)
+
+		client := ""
+		for key, name := range h.config.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+				client = name
+			}
+		}
+
+		if provided == "" || client == "" {
+			c.JSON(http.StatusUnauthorized, api.Error{Error: "Invalid API key"})
+			c.Abort()
+			return
+		}
+
+		c.Set("api_client", client)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "api_client", client))
+		c.Next()
+	}
+}
+
+// Отправить сообщение клиенту от имени поддержки
+func (h *WebHandlers) SendMessage(c *gin.Context) {
+	ctx := c.Request.Context()
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAPIRequestSize+1))
+	if err != nil || len(body) > maxAPIRequestSize {
+		c.JSON(http.StatusBadRequest, api.Error{Error: "Invalid request"})
+		return
+	}
+
+	var request api.SendMessageRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		c.JSON(http.StatusBadRequest, api.Error{Error: "Invalid request"})
+		return
+	}
+	if msg := validateSendMessage(&request); msg != "" {
+		c.JSON(http.StatusBadRequest, api.Error{Error: msg})
+		return
+	}
+
+	key := c.GetHeader(idempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		c.JSON(http.StatusBadRequest, api.Error{Error: "Idempotency-Key is too long"})
+		return
+	}
+
+	// Без ключа идемпотентности запрос просто выполняется
+	if key == "" {
+		status, response := h.sendMessage(ctx, &request)
+		c.JSON(status, response)
+		return
+	}
+
+	client := c.GetString("api_client")
+	hash := sha256.Sum256(body)
+	record, err := h.idempotencyService.Begin(ctx, client, key, hex.EncodeToString(hash[:]))
+	switch {
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		c.JSON(http.StatusUnprocessableEntity, api.Error{Error: "Idempotency-Key was already used with a different request"})
+		return
+	case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+		c.JSON(http.StatusConflict, api.Error{Error: "Request with this Idempotency-Key is in progress"})
+		return
+	case err != nil:
+		logging.FromContext(ctx).Error("Failed to begin idempotent request", "error", err)
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to send message"})
+		return
+	}
+
+	// Запрос с этим ключом уже выполнен - отдаем сохраненный ответ
+	if record.StatusCode != 0 {
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(record.StatusCode, "application/json; charset=utf-8", []byte(record.Response))
+		return
+	}
+
+	status, response := h.sendMessage(ctx, &request)
+
+	// Ответ запоминаем, только если сообщение создано; иначе ключ можно повторить.
+	// Клиент мог не дождаться ответа, но ключ все равно нужно закрыть, иначе повторы получат 409.
+	doneCtx := context.WithoutCancel(ctx)
+	if status == http.StatusCreated {
+		encoded, _ := json.Marshal(response)
+		if err := h.idempotencyService.Complete(doneCtx, record, status, string(encoded)); err != nil {
+			logging.FromContext(ctx).Error("Failed to complete idempotent request", "error", err)
+		}
+	} else if err := h.idempotencyService.Release(doneCtx, record); err != nil {
+		logging.FromContext(ctx).Error("Failed to release idempotency key", "error", err)
+	}
+
+	c.JSON(status, response)
+}
+
+// sendMessage сохраняет сообщение в чате клиента и доставляет его в Telegram
+// Возвращает код ответа и api.SendMessageResponse или api.Error
+func (h *WebHandlers) sendMessage(ctx context.Context, request *api.SendMessageRequest) (int, interface{}) {
+	var user *models.User
+	var err error
+	if request.TelegramID != 0 {
+		user, err = h.userService.GetUserByTelegramID(ctx, request.TelegramID)
+	} else {
+		user, err = h.userService.GetUserByID(ctx, request.UserID)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && user.IsSystem()) {
+		return http.StatusNotFound, api.Error{Error: "User not found"}
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get user", "error", err)
+		return http.StatusInternalServerError, api.Error{Error: "Failed to send message"}
+	}
+
+	systemUser, err := h.userService.GetSystemUser(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get system user", "error", err)
+		return http.StatusInternalServerError, api.Error{Error: "Failed to send message"}
+	}
+
+	chat, err := h.chatService.CreateOrGetChat(ctx, user.ID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get chat for API message", "error", err)
+		return http.StatusInternalServerError, api.Error{Error: "Failed to send message"}
+	}
+
+	var message *models.ChatMessage
+	if request.Attachment == nil {
+		message, err = h.chatService.AddMessage(ctx, chat.ID, systemUser.ID, request.Text, false)
+	} else {
+		message, err = h.chatService.PostMessage(ctx, chat.ID, systemUser.ID, false, service.NewMessage{
+			Content: request.Text,
+			Files: []models.File{{
+				FileID:   request.Attachment.URL,
+				FileName: request.Attachment.FileName,
+				FileType: request.Attachment.Type,
+			}},
+		})
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to save API message", "error", err)
+		return http.StatusInternalServerError, api.Error{Error: "Failed to send message"}
+	}
+
+	status, err := h.messenger.DeliverReply(ctx, message)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to deliver API message", "message_id", message.ID, "error", err)
+	}
+
+	return http.StatusCreated, api.SendMessageResponse{
+		MessageID:      message.ID,
+		ChatID:         chat.ID,
+		UserID:         user.ID,
+		DeliveryStatus: string(status),
+	}
+}
+
+// validateSendMessage возвращает текст ошибки или пустую строку
+func validateSendMessage(r *api.SendMessageRequest) string {
+	if (r.TelegramID == 0) == (r.UserID == 0) {
+		return "Exactly one of telegram_id or user_id is required"
+	}
+	if strings.TrimSpace(r.Text) == "" {
+		return "text is required"
+	}
+
+	if r.Attachment != nil {
+		switch r.Attachment.Type {
+		case "photo", "video", "document":
+		default:
+			return "attachment.type must be photo, video or document"
+		}
+
+		target, err := url.Parse(r.Attachment.URL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return "attachment.url must be an absolute http(s) URL"
+		}
+	}
+
+	return ""
+}
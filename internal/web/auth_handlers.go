@@ -0,0 +1,177 @@
+package web
+
+import (
+	"ai_support_tg_writer_bot/internal/dto"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/models"
+	"ai_support_tg_writer_bot/internal/service"
+	"ai_support_tg_writer_bot/pkg/api"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const sessionCookieName = "admin_session"
+
+// Middleware для проверки админских прав: принимает только действующую сессию из cookie
+func (h *WebHandlers) AdminAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		cookie, err := c.Cookie(sessionCookieName)
+		if err != nil || cookie == "" {
+			c.JSON(http.StatusUnauthorized, api.Error{Error: "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		session, err := h.authService.ValidateSession(ctx, cookie)
+		if err != nil {
+			if !errors.Is(err, service.ErrSessionInvalid) && !errors.Is(err, service.ErrNotAdmin) {
+				logging.FromContext(ctx).Error("Failed to validate session", "error", err)
+			}
+			h.clearSessionCookie(c)
+			c.JSON(http.StatusUnauthorized, api.Error{Error: "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		c.Set("session", session)
+		c.Set("admin", &session.User)
+		c.Request = c.Request.WithContext(logging.With(ctx, "admin_id", session.User.TelegramID))
+		c.Next()
+	}
+}
+
+// Вход через Telegram Login Widget
+func (h *WebHandlers) TelegramLogin(c *gin.Context) {
+	ctx := c.Request.Context()
+	// Числа оставляем в исходном виде: от их записи зависит проверка подписи
+	var raw map[string]interface{}
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		c.JSON(http.StatusBadRequest, api.Error{Error: "Invalid request"})
+		return
+	}
+
+	data := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			data[key] = v
+		case json.Number:
+			data[key] = v.String()
+		default:
+			c.JSON(http.StatusBadRequest, api.Error{Error: "Invalid request"})
+			return
+		}
+	}
+
+	user, err := h.authService.LoginWithTelegram(ctx, data)
+	if err != nil {
+		h.loginFailed(c, err)
+		return
+	}
+
+	if err := h.startSession(c, user); err != nil {
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to create session"})
+		return
+	}
+	c.JSON(http.StatusOK, api.LoginResponse{User: dto.User(user)})
+}
+
+// Вход по одноразовой ссылке, которую бот отправляет по команде /weblogin
+func (h *WebHandlers) LinkLogin(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := h.authService.LoginWithToken(ctx, c.Query("token"))
+	if err != nil {
+		if !errors.Is(err, service.ErrInvalidCredentials) && !errors.Is(err, service.ErrNotAdmin) {
+			logging.FromContext(ctx).Error("Failed to login with token", "error", err)
+		}
+		c.Redirect(http.StatusFound, "/?login_error=link")
+		return
+	}
+
+	if err := h.startSession(c, user); err != nil {
+		c.Redirect(http.StatusFound, "/?login_error=session")
+		return
+	}
+	c.Redirect(http.StatusFound, "/")
+}
+
+// Текущий админ
+func (h *WebHandlers) Me(c *gin.Context) {
+	session := c.MustGet("session").(*models.AdminSession)
+
+	c.JSON(http.StatusOK, api.MeResponse{
+		User:      dto.User(&session.User),
+		ExpiresAt: session.ExpiresAt,
+	})
+}
+
+// Выход из текущей сессии
+func (h *WebHandlers) Logout(c *gin.Context) {
+	ctx := c.Request.Context()
+	session := c.MustGet("session").(*models.AdminSession)
+
+	if err := h.authService.RevokeSession(ctx, session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to logout"})
+		return
+	}
+
+	h.clearSessionCookie(c)
+	c.JSON(http.StatusOK, api.StatusMessage{Message: "Logged out"})
+}
+
+// Выход из всех сессий админа
+func (h *WebHandlers) LogoutAll(c *gin.Context) {
+	ctx := c.Request.Context()
+	session := c.MustGet("session").(*models.AdminSession)
+
+	if err := h.authService.RevokeAllSessions(ctx, session.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to logout"})
+		return
+	}
+
+	h.clearSessionCookie(c)
+	c.JSON(http.StatusOK, api.StatusMessage{Message: "All sessions revoked"})
+}
+
+func (h *WebHandlers) loginFailed(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotAdmin):
+		c.JSON(http.StatusForbidden, api.Error{Error: "Access denied"})
+	case errors.Is(err, service.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, api.Error{Error: "Invalid credentials"})
+	default:
+		logging.FromContext(c.Request.Context()).Error("Failed to login", "error", err)
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to login"})
+	}
+}
+
+// startSession открывает сессию и выставляет cookie
+func (h *WebHandlers) startSession(c *gin.Context, user *models.User) error {
+	ctx := c.Request.Context()
+	value, _, err := h.authService.CreateSession(ctx, user, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to create session", "error", err)
+		return err
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookieName, value, int(h.config.WebSessionTTL.Seconds()), "/", "", h.secureCookies(), true)
+	return nil
+}
+
+func (h *WebHandlers) clearSessionCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookieName, "", -1, "/", "", h.secureCookies(), true)
+}
+
+// secureCookies - отдавать cookie только по HTTPS, если панель открыта по HTTPS
+func (h *WebHandlers) secureCookies() bool {
+	return strings.HasPrefix(h.config.WebBaseURL, "https://")
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"ai_support_tg_writer_bot/internal/events"
+	"ai_support_tg_writer_bot/pkg/api"
+	"context"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Сколько событий держать в буфере одного подключения
+	eventStreamBuffer = 64
+	// Как часто слать пустой комментарий, чтобы прокси не закрывали простаивающее соединение
+	eventStreamHeartbeat = 25 * time.Second
+)
+
+// newStreamEvent - событие чата в том виде, в каком его получает браузер
+func newStreamEvent(event events.Event) api.ChatEvent {
+	e := api.ChatEvent{
+		Type:   string(event.EventName()),
+		ChatID: events.ChatIDOf(event),
+		At:     time.Now(),
+	}
+
+	switch ev := event.(type) {
+	case events.MessageReceived:
+		e.MessageID = ev.Message.ID
+	case events.ReplySent:
+		e.MessageID = ev.Message.ID
+	case events.MessageEdited:
+		e.MessageID = ev.Message.ID
+	}
+
+	return e
+}
+
+// Поток событий чатов для веб-панели (Server-Sent Events)
+func (h *WebHandlers) StreamEvents(c *gin.Context) {
+	ch := make(chan api.ChatEvent, eventStreamBuffer)
+
+	// Подписчик только перекладывает события в канал подключения, отбрасывая их, если браузер не успевает
+	unsubscribe := h.bus.Subscribe("web.stream", events.Sync, func(_ context.Context, event events.Event) {
+		select {
+		case ch <- newStreamEvent(event):
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Отключаем буферизацию ответа в nginx
+	c.Header("X-Accel-Buffering", "no")
+
+	// Сообщаем браузеру, что подписка активна
+	c.SSEvent("ready", gin.H{"at": time.Now()})
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-ch:
+			c.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return false
+			}
+			return true
+		}
+	})
+}
@@ -2,222 +2,300 @@ package web
 
 import (
 	"ai_support_tg_writer_bot/internal/config"
+	"ai_support_tg_writer_bot/internal/dto"
+	"ai_support_tg_writer_bot/internal/events"
+	"ai_support_tg_writer_bot/internal/logging"
 	"ai_support_tg_writer_bot/internal/models"
 	"ai_support_tg_writer_bot/internal/service"
+	"ai_support_tg_writer_bot/pkg/api"
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-type WebHandlers struct {
-	userService   service.UserService
-	ticketService service.TicketService
-	fileService   service.FileService
-	config        *config.Config
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ClientMessenger доставляет клиенту в Telegram действия, совершенные в веб-панели
+type ClientMessenger interface {
+	DeliverReply(ctx context.Context, message *models.ChatMessage) (models.DeliveryStatus, error)
+	GetBotUsername() string
 }
 
-func NewWebHandlers(userService service.UserService, ticketService service.TicketService, fileService service.FileService, config *config.Config) *WebHandlers {
-	return &WebHandlers{
-		userService:   userService,
-		ticketService: ticketService,
-		fileService:   fileService,
-		config:        config,
-	}
+type WebHandlers struct {
+	userService        service.UserService
+	chatService        service.ChatService
+	fileService        service.FileService
+	authService        service.AuthService
+	webhookService     service.WebhookService
+	idempotencyService service.IdempotencyService
+	analyticsService   service.AnalyticsService
+	messenger          ClientMessenger
+	bus                *events.Bus
+	config             *config.Config
 }
 
-// Middleware для проверки админских прав
-func (h *WebHandlers) AdminAuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// В реальном приложении здесь должна быть проверка JWT токена или сессии
-		// Для простоты используем заголовок X-Admin-ID
-		adminIDStr := c.GetHeader("X-Admin-ID")
-		if adminIDStr == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			c.Abort()
-			return
-		}
-
-		adminID, err := strconv.ParseInt(adminIDStr, 10, 64)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin ID"})
-			c.Abort()
-			return
-		}
-
-		isAdmin, err := h.userService.IsAdmin(adminID)
-		if err != nil || !isAdmin {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
-			c.Abort()
-			return
-		}
-
-		c.Set("admin_id", adminID)
-		c.Next()
+func NewWebHandlers(userService service.UserService, chatService service.ChatService, fileService service.FileService, authService service.AuthService, webhookService service.WebhookService, idempotencyService service.IdempotencyService, analyticsService service.AnalyticsService, messenger ClientMessenger, bus *events.Bus, config *config.Config) *WebHandlers {
+	return &WebHandlers{
+		userService:        userService,
+		chatService:        chatService,
+		fileService:        fileService,
+		authService:        authService,
+		webhookService:     webhookService,
+		idempotencyService: idempotencyService,
+		analyticsService:   analyticsService,
+		messenger:          messenger,
+		bus:                bus,
+		config:             config,
 	}
 }
 
 // Главная страница админки
 func (h *WebHandlers) AdminDashboard(c *gin.Context) {
-	openTickets, err := h.ticketService.GetOpenTickets()
+	ctx := c.Request.Context()
+	activeChats, err := h.chatService.GetActiveChatsPaginated(ctx, defaultPageSize, 0)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get open tickets"})
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to get active chats"})
 		return
 	}
 
-	answeredTickets, err := h.ticketService.GetAnsweredTickets()
+	stats, err := h.chatStats(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get answered tickets"})
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to get stats"})
 		return
 	}
 
-	closedTickets, err := h.ticketService.GetClosedTickets()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get closed tickets"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"open_tickets":     len(openTickets),
-		"answered_tickets": len(answeredTickets),
-		"closed_tickets":   len(closedTickets),
-		"tickets": gin.H{
-			"open":     openTickets,
-			"answered": answeredTickets,
-			"closed":   closedTickets,
-		},
+	c.JSON(http.StatusOK, api.Dashboard{
+		Stats:       stats,
+		ActiveChats: dto.Chats(activeChats),
 	})
 }
 
-// Получить все тикеты
-func (h *WebHandlers) GetTickets(c *gin.Context) {
-	status := c.Query("status")
+// Получить список чатов с пагинацией
+func (h *WebHandlers) GetChats(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, limit := paginationParams(c)
+	offset := (page - 1) * limit
 
-	var tickets []models.Ticket
+	status := models.ChatStatus(c.DefaultQuery("status", string(models.ChatStatusActive)))
+
+	var chats []models.Chat
 	var err error
 
 	switch status {
-	case "open":
-		tickets, err = h.ticketService.GetOpenTickets()
-	case "answered":
-		tickets, err = h.ticketService.GetAnsweredTickets()
-	case "closed":
-		tickets, err = h.ticketService.GetClosedTickets()
+	case models.ChatStatusActive:
+		chats, err = h.chatService.GetActiveChatsPaginated(ctx, limit, offset)
+	case models.ChatStatusArchived:
+		chats, err = h.chatService.GetArchivedChatsPaginated(ctx, limit, offset)
 	default:
-		// Получаем все тикеты
-		openTickets, _ := h.ticketService.GetOpenTickets()
-		answeredTickets, _ := h.ticketService.GetAnsweredTickets()
-		closedTickets, _ := h.ticketService.GetClosedTickets()
+		c.JSON(http.StatusBadRequest, api.Error{Error: "Invalid status"})
+		return
+	}
 
-		tickets = append(tickets, openTickets...)
-		tickets = append(tickets, answeredTickets...)
-		tickets = append(tickets, closedTickets...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to get chats"})
+		return
 	}
 
+	total, err := h.chatService.GetChatsCount(ctx, status)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tickets"})
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to count chats"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"tickets": tickets})
+	c.JSON(http.StatusOK, api.ChatList{
+		Chats: dto.Chats(chats),
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
 }
 
-// Получить конкретный тикет
-func (h *WebHandlers) GetTicket(c *gin.Context) {
-	ticketIDStr := c.Param("id")
-	ticketID, err := strconv.ParseUint(ticketIDStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+// Получить чат с сообщениями и файлами
+func (h *WebHandlers) GetChat(c *gin.Context) {
+	ctx := c.Request.Context()
+	chatID, ok := chatIDParam(c)
+	if !ok {
 		return
 	}
 
-	ticket, err := h.ticketService.GetTicketByID(uint(ticketID))
+	chat, err := h.chatService.GetChatWithUser(ctx, chatID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		chatLoadFailed(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"ticket": ticket})
-}
+	page, limit := paginationParams(c)
+	messages, err := h.chatService.GetChatMessagesPaginated(ctx, chatID, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to get messages"})
+		return
+	}
 
-// Отправить ответ на тикет
-func (h *WebHandlers) ReplyToTicket(c *gin.Context) {
-	ticketIDStr := c.Param("id")
-	ticketID, err := strconv.ParseUint(ticketIDStr, 10, 32)
+	total, err := h.chatService.GetChatMessagesCount(ctx, chatID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to count messages"})
 		return
 	}
 
-	var request struct {
-		Message string `json:"message" binding:"required"`
+	c.JSON(http.StatusOK, api.ChatDetail{
+		Chat:     dto.Chat(chat),
+		Messages: dto.Messages(messages),
+		Page:     page,
+		Limit:    limit,
+		Total:    total,
+	})
+}
+
+// Ответить клиенту в чат
+func (h *WebHandlers) ReplyToChat(c *gin.Context) {
+	ctx := c.Request.Context()
+	chatID, ok := chatIDParam(c)
+	if !ok {
+		return
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	var request api.ReplyRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Message == "" {
+		c.JSON(http.StatusBadRequest, api.Error{Error: "Invalid request"})
 		return
 	}
 
-	adminID := c.GetInt64("admin_id")
-	admin, err := h.userService.GetUserByTelegramID(adminID)
+	chat, err := h.chatService.GetChatWithUser(ctx, chatID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Admin not found"})
+		chatLoadFailed(c, err)
+		return
+	}
+	if chat.Status != models.ChatStatusActive {
+		c.JSON(http.StatusConflict, api.Error{Error: "Chat is archived"})
 		return
 	}
 
+	admin := c.MustGet("admin").(*models.User)
+
 	// Добавляем сообщение от админа
-	_, err = h.ticketService.AddMessage(uint(ticketID), admin.ID, request.Message, false)
+	message, err := h.chatService.AddMessage(ctx, chatID, admin.ID, request.Message, false)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reply"})
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to save reply"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Reply sent successfully"})
+	// Отправляем ответ клиенту через бота
+	status, err := h.messenger.DeliverReply(ctx, message)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to deliver web reply", "error", err)
+	}
+	message.DeliveryStatus = status
+
+	c.JSON(http.StatusOK, api.ReplyResponse{
+		Message:        dto.Message(message),
+		DeliveryStatus: string(status),
+	})
 }
 
-// Закрыть тикет
-func (h *WebHandlers) CloseTicket(c *gin.Context) {
-	ticketIDStr := c.Param("id")
-	ticketID, err := strconv.ParseUint(ticketIDStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+// Архивировать чат
+func (h *WebHandlers) ArchiveChat(c *gin.Context) {
+	ctx := c.Request.Context()
+	chatID, ok := chatIDParam(c)
+	if !ok {
 		return
 	}
 
-	err = h.ticketService.CloseTicket(uint(ticketID))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close ticket"})
+	if err := h.chatService.ArchiveChat(ctx, chatID); err != nil {
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to archive chat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, api.StatusMessage{Message: "Chat archived successfully"})
+}
+
+// Пометить чат прочитанным
+func (h *WebHandlers) MarkChatAsRead(c *gin.Context) {
+	ctx := c.Request.Context()
+	chatID, ok := chatIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.chatService.MarkChatAsRead(ctx, chatID); err != nil {
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to mark chat as read"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Ticket closed successfully"})
+	c.JSON(http.StatusOK, api.StatusMessage{Message: "Chat marked as read"})
 }
 
 // Получить статистику
 func (h *WebHandlers) GetStats(c *gin.Context) {
-	openTickets, _ := h.ticketService.GetOpenTickets()
-	answeredTickets, _ := h.ticketService.GetAnsweredTickets()
-	closedTickets, _ := h.ticketService.GetClosedTickets()
+	stats, err := h.chatStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to get stats"})
+		return
+	}
 
-	// Подсчитываем статистику за последние 7 дней
-	weekAgo := time.Now().AddDate(0, 0, -7)
+	c.JSON(http.StatusOK, stats)
+}
 
-	var recentTickets int
-	allTickets := append(openTickets, answeredTickets...)
-	allTickets = append(allTickets, closedTickets...)
+func (h *WebHandlers) chatStats(ctx context.Context) (api.Stats, error) {
+	activeCount, err := h.chatService.GetChatsCount(ctx, models.ChatStatusActive)
+	if err != nil {
+		return api.Stats{}, err
+	}
 
-	for _, ticket := range allTickets {
-		if ticket.CreatedAt.After(weekAgo) {
-			recentTickets++
-		}
+	archivedCount, err := h.chatService.GetChatsCount(ctx, models.ChatStatusArchived)
+	if err != nil {
+		return api.Stats{}, err
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"total_tickets":    len(allTickets),
-		"open_tickets":     len(openTickets),
-		"answered_tickets": len(answeredTickets),
-		"closed_tickets":   len(closedTickets),
-		"recent_tickets":   recentTickets,
-	})
+	unreadCount, err := h.chatService.GetUnreadChatsCount(ctx)
+	if err != nil {
+		return api.Stats{}, err
+	}
+
+	return api.Stats{
+		ActiveChats:   activeCount,
+		ArchivedChats: archivedCount,
+		UnreadChats:   unreadCount,
+		TotalChats:    activeCount + archivedCount,
+	}, nil
+}
+
+// chatIDParam разбирает ID чата из пути, при ошибке сам отвечает клиенту
+func chatIDParam(c *gin.Context) (uint, bool) {
+	return idParam(c, "id", "Invalid chat ID")
+}
+
+// chatLoadFailed отвечает 404, если чата нет, и 500 при ошибке базы
+func chatLoadFailed(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, api.Error{Error: "Chat not found"})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Error("Failed to get chat", "error", err)
+	c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to get chat"})
+}
+
+// paginationParams разбирает page (с 1) и limit из query
+func paginationParams(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if err != nil || limit < 1 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	return page, limit
 }
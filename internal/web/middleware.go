@@ -0,0 +1,55 @@
+package web
+
+import (
+	"ai_support_tg_writer_bot/internal/logging"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+// requestLogger кладет в контекст запроса логгер с request_id, методом и путем
+// и пишет по строке access-лога на каждый запрос. Request ID берется из заголовка X-Request-ID
+// (чтобы связать логи с балансировщиком) или генерируется и возвращается в ответе.
+func requestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = newRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		requestLogger := logger.With("request_id", requestID, "method", c.Request.Method, "path", c.Request.URL.Path)
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), requestLogger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		// Логгер берем из контекста: middleware авторизации могли добавить в него поля
+		logging.FromContext(c.Request.Context()).Log(c.Request.Context(), level, "HTTP request",
+			"status", status,
+			"elapsed", time.Since(start),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
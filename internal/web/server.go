@@ -2,8 +2,10 @@ package web
 
 import (
 	"ai_support_tg_writer_bot/internal/config"
+	"ai_support_tg_writer_bot/internal/events"
 	"ai_support_tg_writer_bot/internal/service"
-	"log"
+	"ai_support_tg_writer_bot/pkg/api"
+	"log/slog"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,19 +14,22 @@ type Server struct {
 	router   *gin.Engine
 	handlers *WebHandlers
 	config   *config.Config
+	logger   *slog.Logger
 }
 
-func NewServer(config *config.Config, userService service.UserService, ticketService service.TicketService, fileService service.FileService) *Server {
+func NewServer(config *config.Config, userService service.UserService, chatService service.ChatService, fileService service.FileService, authService service.AuthService, webhookService service.WebhookService, idempotencyService service.IdempotencyService, analyticsService service.AnalyticsService, messenger ClientMessenger, bus *events.Bus, logger *slog.Logger) *Server {
 	gin.SetMode(gin.ReleaseMode)
-	router := gin.Default()
+	logger = logger.With("component", "web")
+	router := gin.New()
+	router.Use(requestLogger(logger), gin.Recovery())
 
-	handlers := NewWebHandlers(userService, ticketService, fileService, config)
+	handlers := NewWebHandlers(userService, chatService, fileService, authService, webhookService, idempotencyService, analyticsService, messenger, bus, config)
 
 	// CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Admin-ID")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-API-Key, Idempotency-Key")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -35,45 +40,90 @@ func NewServer(config *config.Config, userService service.UserService, ticketSer
 	})
 
 	// API routes
-	api := router.Group("/api/v1")
+	v1 := router.Group("/api/v1")
 	{
 		// Публичные маршруты
-		api.GET("/health", func(c *gin.Context) {
-			c.JSON(200, gin.H{"status": "ok"})
+		v1.GET("/health", func(c *gin.Context) {
+			c.JSON(200, api.Health{Status: "ok"})
 		})
 
-		// Админские маршруты
-		admin := api.Group("/admin")
-		admin.Use(handlers.AdminAuthMiddleware())
-		{
-			admin.GET("/dashboard", handlers.AdminDashboard)
-			admin.GET("/tickets", handlers.GetTickets)
-			admin.GET("/tickets/:id", handlers.GetTicket)
-			admin.POST("/tickets/:id/reply", handlers.ReplyToTicket)
-			admin.POST("/tickets/:id/close", handlers.CloseTicket)
-			admin.GET("/stats", handlers.GetStats)
+		// Описание API в формате OpenAPI 3
+		openAPI := api.OpenAPI(config.WebBaseURL)
+		v1.GET("/openapi.json", func(c *gin.Context) {
+			c.JSON(200, openAPI)
+		})
+
+		// Публичный API для внешних систем, только при заданных API_KEYS
+		if len(config.APIKeys) > 0 {
+			public := v1.Group("")
+			public.Use(handlers.APIKeyMiddleware())
+			{
+				public.POST("/messages", handlers.SendMessage)
+			}
+		}
+
+		if config.EnableWebAdmin {
+			// Вход и выход
+			v1.POST("/auth/telegram", handlers.TelegramLogin)
+			auth := v1.Group("/auth")
+			auth.Use(handlers.AdminAuthMiddleware())
+			{
+				auth.GET("/me", handlers.Me)
+				auth.POST("/logout", handlers.Logout)
+				auth.POST("/logout-all", handlers.LogoutAll)
+			}
+
+			// Админские маршруты
+			admin := v1.Group("/admin")
+			admin.Use(handlers.AdminAuthMiddleware())
+			{
+				admin.GET("/dashboard", handlers.AdminDashboard)
+				admin.GET("/chats", handlers.GetChats)
+				admin.GET("/chats/:id", handlers.GetChat)
+				admin.POST("/chats/:id/reply", handlers.ReplyToChat)
+				admin.POST("/chats/:id/archive", handlers.ArchiveChat)
+				admin.POST("/chats/:id/read", handlers.MarkChatAsRead)
+				admin.GET("/stats", handlers.GetStats)
+				admin.GET("/analytics", handlers.GetAnalytics)
+				admin.GET("/events", handlers.StreamEvents)
+
+				// Исходящие вебхуки и журнал их доставок
+				admin.GET("/webhooks", handlers.GetWebhooks)
+				admin.POST("/webhooks", handlers.CreateWebhook)
+				admin.PUT("/webhooks/:id", handlers.UpdateWebhook)
+				admin.DELETE("/webhooks/:id", handlers.DeleteWebhook)
+				admin.GET("/webhooks/:id/deliveries", handlers.GetWebhookDeliveries)
+				admin.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", handlers.RedeliverWebhook)
+			}
 		}
 	}
 
-	// Статические файлы для админской панели
-	router.Static("/static", "./web/static")
-	router.LoadHTMLGlob("web/templates/*")
+	if config.EnableWebAdmin {
+		// Статические файлы для админской панели
+		router.Static("/static", "./web/static")
+		router.LoadHTMLGlob("web/templates/*")
+
+		// Вход по одноразовой ссылке из бота
+		router.GET("/auth/link", handlers.LinkLogin)
 
-	// Главная страница админки
-	router.GET("/", func(c *gin.Context) {
-		c.HTML(200, "admin.html", gin.H{
-			"title": "Social Flow Support Admin",
+		// Главная страница админки
+		router.GET("/", func(c *gin.Context) {
+			c.HTML(200, "admin.html", gin.H{
+				"title":        "Social Flow Support Admin",
+				"bot_username": messenger.GetBotUsername(),
+			})
 		})
-	})
+	}
 
 	return &Server{
 		router:   router,
 		handlers: handlers,
 		config:   config,
+		logger:   logger,
 	}
 }
 
 func (s *Server) Start() error {
-	log.Printf("Starting web server on port %s", s.config.ServerPort)
+	s.logger.Info("Starting web server", "port", s.config.ServerPort)
 	return s.router.Run(":" + s.config.ServerPort)
 }
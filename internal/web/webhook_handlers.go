@@ -0,0 +1,180 @@
+package web
+
+import (
+	"ai_support_tg_writer_bot/internal/dto"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/service"
+	"ai_support_tg_writer_bot/pkg/api"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func webhookInput(r *api.WebhookRequest) service.WebhookInput {
+	// Без явного is_active вебхук включен
+	isActive := r.IsActive == nil || *r.IsActive
+	return service.WebhookInput{
+		URL:         r.URL,
+		Events:      r.Events,
+		Secret:      r.Secret,
+		Description: r.Description,
+		IsActive:    isActive,
+	}
+}
+
+// Список вебхуков и доступных событий
+func (h *WebHandlers) GetWebhooks(c *gin.Context) {
+	ctx := c.Request.Context()
+	webhooks, err := h.webhookService.GetWebhooks(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to get webhooks"})
+		return
+	}
+
+	names := make([]string, 0, len(service.WebhookEvents))
+	for _, name := range service.WebhookEvents {
+		names = append(names, string(name))
+	}
+
+	c.JSON(http.StatusOK, api.WebhookList{
+		Webhooks: dto.Webhooks(webhooks),
+		Events:   names,
+	})
+}
+
+// Создать вебхук
+func (h *WebHandlers) CreateWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	var request api.WebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, api.Error{Error: "Invalid request"})
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(ctx, webhookInput(&request))
+	if err != nil {
+		h.webhookFailed(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.Webhook(webhook))
+}
+
+// Изменить вебхук
+func (h *WebHandlers) UpdateWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	webhookID, ok := idParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	var request api.WebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, api.Error{Error: "Invalid request"})
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(ctx, webhookID, webhookInput(&request))
+	if err != nil {
+		h.webhookFailed(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.Webhook(webhook))
+}
+
+// Удалить вебхук
+func (h *WebHandlers) DeleteWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	webhookID, ok := idParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(ctx, webhookID); err != nil {
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, api.StatusMessage{Message: "Webhook deleted"})
+}
+
+// Журнал доставок вебхука
+func (h *WebHandlers) GetWebhookDeliveries(c *gin.Context) {
+	ctx := c.Request.Context()
+	webhookID, ok := idParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	if _, err := h.webhookService.GetWebhook(ctx, webhookID); err != nil {
+		c.JSON(http.StatusNotFound, api.Error{Error: "Webhook not found"})
+		return
+	}
+
+	page, limit := paginationParams(c)
+	deliveries, err := h.webhookService.GetDeliveriesPaginated(ctx, webhookID, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to get deliveries"})
+		return
+	}
+
+	total, err := h.webhookService.GetDeliveriesCount(ctx, webhookID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, api.Error{Error: "Failed to count deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, api.WebhookDeliveryList{
+		Deliveries: dto.WebhookDeliveries(deliveries),
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+	})
+}
+
+// Повторно отправить событие из журнала
+func (h *WebHandlers) RedeliverWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	webhookID, ok := idParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	deliveryID, ok := idParam(c, "delivery_id", "Invalid delivery ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(ctx, webhookID, deliveryID)
+	if err != nil {
+		h.webhookFailed(c, err, "Failed to redeliver")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.WebhookDelivery(delivery))
+}
+
+func (h *WebHandlers) webhookFailed(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, api.Error{Error: err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, api.Error{Error: "Not found"})
+	default:
+		logging.FromContext(c.Request.Context()).Error(message, "error", err)
+		c.JSON(http.StatusInternalServerError, api.Error{Error: message})
+	}
+}
+
+// idParam разбирает числовой ID из пути, при ошибке сам отвечает клиенту
+func idParam(c *gin.Context, name, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, api.Error{Error: message})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -4,30 +4,52 @@ import (
 	"ai_support_tg_writer_bot/internal/bot"
 	"ai_support_tg_writer_bot/internal/config"
 	"ai_support_tg_writer_bot/internal/database"
+	"ai_support_tg_writer_bot/internal/events"
+	"ai_support_tg_writer_bot/internal/health"
+	"ai_support_tg_writer_bot/internal/i18n"
+	"ai_support_tg_writer_bot/internal/logging"
+	"ai_support_tg_writer_bot/internal/metrics"
+	"ai_support_tg_writer_bot/internal/models"
 	"ai_support_tg_writer_bot/internal/repository"
 	"ai_support_tg_writer_bot/internal/service"
+	"ai_support_tg_writer_bot/internal/web"
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
+// Версия сборки, задается при компиляции: go build -ldflags "-X main.version=1.2.3"
+var version = "dev"
+
 func main() {
-	// Загружаем конфигурацию
-	cfg, err := config.Load()
+	// Загружаем конфигурацию: файл (-config или CONFIG_FILE), окружение и флаги
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		log.Fatalf("Failed to load config:\n%v", err)
 	}
+	configStore := config.NewStore(cfg)
 
-	// Проверяем обязательные параметры
-	if cfg.TelegramBotToken == "" {
-		log.Fatal("TELEGRAM_BOT_TOKEN is required")
+	// Структурированный логгер; через slog.SetDefault в него же попадает и стандартный log
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		log.Fatalf("Failed to create logger: %v", err)
 	}
+	slog.SetDefault(logger)
 
 	// Подключаемся к базе данных
 	db, err := database.Connect(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", err)
 	}
 
 	// Инициализируем репозитории
@@ -35,42 +57,174 @@ func main() {
 	chatRepo := repository.NewChatRepository(db)
 	chatMessageRepo := repository.NewChatMessageRepository(db)
 	fileRepo := repository.NewFileRepository(db)
+	mappingRepo := repository.NewMessageMappingRepository(db)
+	deliveryJobRepo := repository.NewDeliveryJobRepository(db)
+	sessionRepo := repository.NewAdminSessionRepository(db)
+	loginTokenRepo := repository.NewLoginTokenRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookDeliveryRepo := repository.NewWebhookDeliveryRepository(db)
+	idempotencyKeyRepo := repository.NewIdempotencyKeyRepository(db)
+	analyticsRepo := repository.NewAnalyticsRepository(db)
+	templateRepo := repository.NewTemplateRepository(db)
+
+	// Шина событий: сервисы сообщают об изменениях в чатах, а уведомления, темы и веб-панель на них подписаны
+	eventBus := events.NewBus()
 
 	// Инициализируем сервисы
 	userService := service.NewUserService(userRepo)
-	chatService := service.NewChatService(chatRepo, chatMessageRepo, fileRepo)
+	chatService := service.NewChatService(chatRepo, chatMessageRepo, fileRepo, mappingRepo, eventBus)
 	fileService := service.NewFileService(fileRepo)
+	deliveryService := service.NewDeliveryService(deliveryJobRepo, chatMessageRepo)
+	authService := service.NewAuthService(userRepo, sessionRepo, loginTokenRepo, cfg.TelegramBotToken, cfg.WebSessionSecret, cfg.WebSessionTTL)
+	webhookService := service.NewWebhookService(webhookRepo, webhookDeliveryRepo, chatRepo, eventBus, logger)
+	idempotencyService := service.NewIdempotencyService(idempotencyKeyRepo)
+	analyticsService := service.NewAnalyticsService(analyticsRepo, chatRepo)
+	templateService := service.NewTemplateService(templateRepo)
+
+	// Права админов в базе нужны веб-панели, источник истины - конфигурация
+	if err := userService.SyncAdmins(context.Background(), cfg.AdminIDs); err != nil {
+		fatal("Failed to sync admins", err)
+	}
+
+	// Переводы текстов бота: встроенные каталоги и, если задан, каталог LOCALES_DIR поверх них
+	translations, err := i18n.Load(cfg.LocalesDir, cfg.DefaultLanguage)
+	if err != nil {
+		fatal("Failed to load translations", err)
+	}
 
 	// Инициализируем Telegram бота
-	telegramBot, err := bot.NewChatBot(cfg, userService, chatService, fileService)
+	telegramBot, err := bot.NewChatBot(configStore, userService, chatService, fileService, deliveryService, authService, analyticsService, templateService, translations, eventBus, logger)
 	if err != nil {
-		log.Fatalf("Failed to create bot: %v", err)
+		fatal("Failed to create bot", err)
 	}
 
-	// Инициализируем веб-сервер (временно отключен)
-	// webServer := web.NewServer(cfg, userService, chatService, fileService)
+	registerMetrics(chatService, deliveryService, analyticsRepo, telegramBot)
+
+	// Проверки готовности: база, Redis (если задан) и связь с Telegram
+	checker := health.NewChecker(version)
+	sqlDB, err := db.DB()
+	if err != nil {
+		fatal("Failed to get database pool", err)
+	}
+	checker.Add("database", sqlDB.PingContext)
+	if cfg.Redis.Enabled {
+		checker.Add("redis", health.RedisCheck(net.JoinHostPort(cfg.Redis.Host, cfg.Redis.Port), cfg.Redis.Password))
+	}
+	checker.Add("telegram", telegramBot.CheckTelegram)
+	checker.Add("updates", telegramBot.CheckUpdates)
+
+	// Служебный сервер для Prometheus и оркестратора, работает независимо от веб-панели
+	opsMux := http.NewServeMux()
+	opsMux.Handle("/metrics", metrics.Handler())
+	opsMux.Handle("/healthz", checker.LivenessHandler())
+	opsMux.Handle("/readyz", checker.ReadinessHandler())
+	go func() {
+		logger.Info("Ops server listening", "port", cfg.OpsPort)
+		if err := http.ListenAndServe(":"+cfg.OpsPort, opsMux); err != nil {
+			logger.Error("Ops server error", "error", err)
+		}
+	}()
 
 	// Канал для graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	// Веб-сервер временно отключен
-	log.Println("Web admin panel disabled. Use /admin command in Telegram for admin functions.")
+	// Запускаем веб-сервер, если включена веб-панель или публичный API
+	if !cfg.EnableWebAdmin {
+		logger.Info("Web admin panel disabled. Set ENABLE_WEB_ADMIN=true to enable it.")
+	}
+	if cfg.EnableWebAdmin || len(cfg.APIKeys) > 0 {
+		webServer := web.NewServer(cfg, userService, chatService, fileService, authService, webhookService, idempotencyService, analyticsService, telegramBot, eventBus, logger)
+		go func() {
+			if err := webServer.Start(); err != nil {
+				logger.Error("Web server error", "error", err)
+			}
+		}()
+	}
+
+	// Отправляем события чатов на внешние вебхуки
+	go webhookService.Run()
 
 	// Запускаем Telegram бота в горутине
 	go func() {
-		log.Println("Starting Telegram bot...")
+		logger.Info("Starting Telegram bot...")
 		if err := telegramBot.Start(); err != nil {
-			log.Printf("Telegram bot error: %v", err)
+			logger.Error("Telegram bot error", "error", err)
 		}
 	}()
 
-	log.Println("🚀 Social Flow Support Bot started successfully!")
-	log.Printf("📱 Telegram bot: @%s", telegramBot.GetBotUsername())
-	log.Println("👨‍💼 Admin panel: Use /admin command in Telegram")
-	log.Println("Press Ctrl+C to stop...")
+	logger.Info("🚀 Social Flow Support Bot started successfully!", "version", version, "bot", "@"+telegramBot.GetBotUsername())
+	if cfg.EnableWebAdmin {
+		logger.Info("👨‍💼 Admin panel: http://localhost:" + cfg.ServerPort)
+	} else {
+		logger.Info("👨‍💼 Admin panel: Use /admin command in Telegram")
+	}
+	logger.Info("Press Ctrl+C to stop...")
+
+	// SIGHUP перечитывает конфигурацию без перезапуска
+	go reloadConfigOnSignal(configStore, userService, telegramBot, logger)
 
 	// Ждем сигнал завершения
 	<-quit
-	log.Println("Shutting down...")
+	logger.Info("Shutting down...")
+}
+
+// reloadConfigOnSignal по SIGHUP применяет новых админов, тексты и рабочее время.
+// Ошибочная конфигурация не применяется целиком, бот продолжает работать со старой.
+func reloadConfigOnSignal(store *config.Store, userService service.UserService, telegramBot *bot.ChatBot, logger *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		next, err := config.Load(os.Args[1:])
+		if err != nil {
+			logger.Error("Failed to reload config, keeping the current one", "error", err)
+			continue
+		}
+
+		restartRequired := store.Reload(next)
+		if err := userService.SyncAdmins(context.Background(), next.AdminIDs); err != nil {
+			logger.Error("Failed to sync admins after reload", "error", err)
+		}
+		// Новым админам - меню команд админа, бывшим - меню клиента
+		if err := telegramBot.SyncCommands(logging.WithLogger(context.Background(), logger)); err != nil {
+			logger.Error("Failed to sync bot commands after reload", "error", err)
+		}
+
+		logger.Info("Config reloaded", "admins", len(next.AdminIDs), "business_hours", next.BusinessHours.String())
+		if len(restartRequired) > 0 {
+			logger.Warn("Changed settings take effect after restart", "settings", restartRequired)
+		}
+	}
+}
+
+// fatal пишет ошибку запуска в лог и завершает процесс
+func fatal(msg string, err error) {
+	if err != nil {
+		slog.Error(msg, "error", err)
+	} else {
+		slog.Error(msg)
+	}
+	os.Exit(1)
+}
+
+// registerMetrics добавляет датчики, которые при каждом сборе метрик читают текущее состояние из базы и бота
+func registerMetrics(chatService service.ChatService, deliveryService service.DeliveryService, analyticsRepo repository.AnalyticsRepository, telegramBot *bot.ChatBot) {
+	// Датчики получают контекст сбора метрик с таймаутом, так что медленная база не подвесит /metrics
+	metrics.NewGaugeFunc("support_bot_active_chats", "Chats in active status.", func(ctx context.Context) (float64, error) {
+		count, err := chatService.GetChatsCount(ctx, models.ChatStatusActive)
+		return float64(count), err
+	})
+	// Чаты и сообщения - один запрос к базе, поэтому одна метрика с меткой
+	metrics.NewGaugeVecFunc("support_bot_unread", "Unread client messages in active chats: chats with them and the messages themselves.", "kind", func(ctx context.Context) (map[string]float64, error) {
+		backlog, err := analyticsRepo.UnreadBacklog(ctx)
+		return map[string]float64{"chats": float64(backlog.Chats), "messages": float64(backlog.Messages)}, err
+	})
+	metrics.NewGaugeVecFunc("support_bot_outbound_queue_depth", "Bot API requests waiting for chat and global rate limits, by priority.", "priority", func(context.Context) (map[string]float64, error) {
+		return telegramBot.OutboundQueueDepth(), nil
+	})
+	metrics.NewGaugeFunc("support_bot_delivery_retry_queue_depth", "Replies waiting for another delivery attempt.", func(ctx context.Context) (float64, error) {
+		count, err := deliveryService.GetQueuedCount(ctx)
+		return float64(count), err
+	})
 }
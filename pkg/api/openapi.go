@@ -0,0 +1,272 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Способы авторизации операций
+const (
+	SecuritySession = "session" // Cookie admin_session веб-панели
+	SecurityAPIKey  = "apiKey"  // Заголовок X-API-Key из API_KEYS
+)
+
+// Param - параметр запроса в пути, query или заголовке
+type Param struct {
+	Name        string
+	In          string // path, query или header
+	Description string
+	Type        string // string или integer
+	Required    bool
+}
+
+// Operation описывает метод HTTP API. По списку Operations строится OpenAPI-документ,
+// схемы тел берутся из типов Request и Response.
+type Operation struct {
+	ID       string
+	Method   string
+	Path     string // В формате OpenAPI: /admin/chats/{id}
+	Summary  string
+	Tag      string
+	Security string
+	Params   []Param
+	Request  interface{} // Значение типа тела запроса, nil - без тела
+	Response interface{} // Значение типа тела успешного ответа
+	Status   int         // Код успешного ответа
+	Stream   bool        // Ответ - поток text/event-stream из событий типа Response
+}
+
+var (
+	idParam         = Param{Name: "id", In: "path", Type: "integer", Required: true}
+	pageParams      = []Param{{Name: "page", In: "query", Type: "integer", Description: "Номер страницы, с 1"}, {Name: "limit", In: "query", Type: "integer", Description: "Размер страницы, до 100"}}
+	analyticsParams = []Param{
+		{Name: "period", In: "query", Type: "string", Description: "today, 7d (по умолчанию), 30d или 365d"},
+		{Name: "from", In: "query", Type: "string", Description: "Начало периода YYYY-MM-DD (UTC), вместо period"},
+		{Name: "to", In: "query", Type: "string", Description: "Конец периода YYYY-MM-DD включительно, по умолчанию сегодня"},
+	}
+	webhookParams = []Param{{Name: "id", In: "path", Type: "integer", Required: true, Description: "ID вебхука"}}
+)
+
+// Operations - все методы API под префиксом /api/v1
+var Operations = []Operation{
+	{ID: "health", Method: http.MethodGet, Path: "/health", Summary: "Проверка работоспособности", Tag: "system", Response: Health{}, Status: http.StatusOK},
+
+	{ID: "telegramLogin", Method: http.MethodPost, Path: "/auth/telegram", Summary: "Вход через Telegram Login Widget, выставляет cookie сессии", Tag: "auth", Request: TelegramLoginRequest{}, Response: LoginResponse{}, Status: http.StatusOK},
+	{ID: "me", Method: http.MethodGet, Path: "/auth/me", Summary: "Текущий админ", Tag: "auth", Security: SecuritySession, Response: MeResponse{}, Status: http.StatusOK},
+	{ID: "logout", Method: http.MethodPost, Path: "/auth/logout", Summary: "Завершить текущую сессию", Tag: "auth", Security: SecuritySession, Response: StatusMessage{}, Status: http.StatusOK},
+	{ID: "logoutAll", Method: http.MethodPost, Path: "/auth/logout-all", Summary: "Завершить все сессии админа", Tag: "auth", Security: SecuritySession, Response: StatusMessage{}, Status: http.StatusOK},
+
+	{ID: "dashboard", Method: http.MethodGet, Path: "/admin/dashboard", Summary: "Статистика и первая страница активных чатов", Tag: "chats", Security: SecuritySession, Response: Dashboard{}, Status: http.StatusOK},
+	{ID: "listChats", Method: http.MethodGet, Path: "/admin/chats", Summary: "Список чатов", Tag: "chats", Security: SecuritySession,
+		Params: append([]Param{{Name: "status", In: "query", Type: "string", Description: "active (по умолчанию) или archived"}}, pageParams...), Response: ChatList{}, Status: http.StatusOK},
+	{ID: "getChat", Method: http.MethodGet, Path: "/admin/chats/{id}", Summary: "Чат и страница его сообщений", Tag: "chats", Security: SecuritySession,
+		Params: append([]Param{idParam}, pageParams...), Response: ChatDetail{}, Status: http.StatusOK},
+	{ID: "replyToChat", Method: http.MethodPost, Path: "/admin/chats/{id}/reply", Summary: "Ответить клиенту", Tag: "chats", Security: SecuritySession,
+		Params: []Param{idParam}, Request: ReplyRequest{}, Response: ReplyResponse{}, Status: http.StatusOK},
+	{ID: "archiveChat", Method: http.MethodPost, Path: "/admin/chats/{id}/archive", Summary: "Архивировать чат", Tag: "chats", Security: SecuritySession,
+		Params: []Param{idParam}, Response: StatusMessage{}, Status: http.StatusOK},
+	{ID: "markChatRead", Method: http.MethodPost, Path: "/admin/chats/{id}/read", Summary: "Пометить чат прочитанным", Tag: "chats", Security: SecuritySession,
+		Params: []Param{idParam}, Response: StatusMessage{}, Status: http.StatusOK},
+	{ID: "stats", Method: http.MethodGet, Path: "/admin/stats", Summary: "Статистика чатов", Tag: "chats", Security: SecuritySession, Response: Stats{}, Status: http.StatusOK},
+	{ID: "analytics", Method: http.MethodGet, Path: "/admin/analytics", Summary: "Отчет о работе поддержки за период", Tag: "chats", Security: SecuritySession,
+		Params: analyticsParams, Response: Analytics{}, Status: http.StatusOK},
+	{ID: "events", Method: http.MethodGet, Path: "/admin/events", Summary: "Поток событий чатов (Server-Sent Events), имя события - поле type", Tag: "chats", Security: SecuritySession, Response: ChatEvent{}, Status: http.StatusOK, Stream: true},
+
+	{ID: "listWebhooks", Method: http.MethodGet, Path: "/admin/webhooks", Summary: "Вебхуки и доступные события", Tag: "webhooks", Security: SecuritySession, Response: WebhookList{}, Status: http.StatusOK},
+	{ID: "createWebhook", Method: http.MethodPost, Path: "/admin/webhooks", Summary: "Создать вебхук", Tag: "webhooks", Security: SecuritySession, Request: WebhookRequest{}, Response: Webhook{}, Status: http.StatusCreated},
+	{ID: "updateWebhook", Method: http.MethodPut, Path: "/admin/webhooks/{id}", Summary: "Изменить вебхук", Tag: "webhooks", Security: SecuritySession,
+		Params: webhookParams, Request: WebhookRequest{}, Response: Webhook{}, Status: http.StatusOK},
+	{ID: "deleteWebhook", Method: http.MethodDelete, Path: "/admin/webhooks/{id}", Summary: "Удалить вебхук", Tag: "webhooks", Security: SecuritySession,
+		Params: webhookParams, Response: StatusMessage{}, Status: http.StatusOK},
+	{ID: "listWebhookDeliveries", Method: http.MethodGet, Path: "/admin/webhooks/{id}/deliveries", Summary: "Журнал доставок вебхука", Tag: "webhooks", Security: SecuritySession,
+		Params: append(append([]Param{}, webhookParams...), pageParams...), Response: WebhookDeliveryList{}, Status: http.StatusOK},
+	{ID: "redeliverWebhook", Method: http.MethodPost, Path: "/admin/webhooks/{id}/deliveries/{delivery_id}/redeliver", Summary: "Повторно отправить событие из журнала", Tag: "webhooks", Security: SecuritySession,
+		Params: append(append([]Param{}, webhookParams...), Param{Name: "delivery_id", In: "path", Type: "integer", Required: true}), Response: WebhookDelivery{}, Status: http.StatusAccepted},
+
+	{ID: "sendMessage", Method: http.MethodPost, Path: "/messages", Summary: "Написать клиенту от имени поддержки", Tag: "public", Security: SecurityAPIKey,
+		Params:  []Param{{Name: "Idempotency-Key", In: "header", Type: "string", Description: "Повтор с тем же ключом вернет сохраненный ответ"}},
+		Request: SendMessageRequest{}, Response: SendMessageResponse{}, Status: http.StatusCreated},
+}
+
+// OpenAPI строит документ OpenAPI 3 для Operations. serverURL - внешний адрес сервера без /api/v1.
+func OpenAPI(serverURL string) map[string]interface{} {
+	b := &schemaBuilder{schemas: map[string]interface{}{}}
+	paths := map[string]map[string]interface{}{}
+
+	for _, op := range Operations {
+		if paths[op.Path] == nil {
+			paths[op.Path] = map[string]interface{}{}
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = b.operation(op)
+	}
+
+	// Для вебхуков описываем тело, которое бот отправляет получателю
+	b.schemaFor(reflect.TypeOf(WebhookEvent{}))
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Support Bot API",
+			"version": "1.0.0",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": strings.TrimRight(serverURL, "/") + "/api/v1"}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]interface{}{
+				SecuritySession: map[string]interface{}{"type": "apiKey", "in": "cookie", "name": "admin_session"},
+				SecurityAPIKey:  map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+type schemaBuilder struct {
+	schemas map[string]interface{}
+}
+
+func (b *schemaBuilder) operation(op Operation) map[string]interface{} {
+	errorResponse := map[string]interface{}{
+		"description": "Ошибка",
+		"content":     jsonContent(b.schemaFor(reflect.TypeOf(Error{}))),
+	}
+
+	success := map[string]interface{}{"description": "OK"}
+	if op.Stream {
+		success["content"] = map[string]interface{}{
+			"text/event-stream": map[string]interface{}{"schema": b.schemaFor(reflect.TypeOf(op.Response))},
+		}
+	} else if op.Response != nil {
+		success["content"] = jsonContent(b.schemaFor(reflect.TypeOf(op.Response)))
+	}
+
+	result := map[string]interface{}{
+		"operationId": op.ID,
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+		"responses": map[string]interface{}{
+			strconv.Itoa(op.Status): success,
+			"default":               errorResponse,
+		},
+	}
+
+	if op.Security != "" {
+		result["security"] = []interface{}{map[string]interface{}{op.Security: []string{}}}
+	}
+
+	if len(op.Params) > 0 {
+		params := make([]interface{}, 0, len(op.Params))
+		for _, p := range op.Params {
+			param := map[string]interface{}{
+				"name":     p.Name,
+				"in":       p.In,
+				"required": p.Required,
+				"schema":   map[string]interface{}{"type": p.Type},
+			}
+			if p.Description != "" {
+				param["description"] = p.Description
+			}
+			params = append(params, param)
+		}
+		result["parameters"] = params
+	}
+
+	if op.Request != nil {
+		result["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  jsonContent(b.schemaFor(reflect.TypeOf(op.Request))),
+		}
+	}
+
+	return result
+}
+
+// schemaFor возвращает схему типа. Именованные структуры и словари выносятся в components/schemas.
+func (b *schemaBuilder) schemaFor(t reflect.Type) map[string]interface{} {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := b.schemaFor(t.Elem())
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Struct:
+		if _, ok := b.schemas[t.Name()]; !ok {
+			// Заглушка на время обхода полей защищает от бесконечной рекурсии
+			b.schemas[t.Name()] = map[string]interface{}{}
+			b.schemas[t.Name()] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	case reflect.Map:
+		schema := map[string]interface{}{"type": "object", "additionalProperties": true}
+		if t.Name() != "" {
+			b.schemas[t.Name()] = schema
+			return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		}
+		return schema
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": b.schemaFor(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int32, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := b.schemaFor(field.Type)
+		if enum := field.Tag.Get("enum"); enum != "" {
+			values := []interface{}{}
+			for _, value := range strings.Split(enum, ",") {
+				values = append(values, value)
+			}
+			schema["enum"] = values
+		}
+		properties[name] = schema
+
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
@@ -0,0 +1,240 @@
+// Package api описывает JSON, которым обмениваются HTTP API бота поддержки и его клиенты:
+// админ-панель, публичный API для внешних систем и исходящие вебхуки.
+package api
+
+import "time"
+
+// Error - тело ответа с ошибкой
+type Error struct {
+	Error string `json:"error"`
+}
+
+// StatusMessage - тело ответа на действие без собственного результата
+type StatusMessage struct {
+	Message string `json:"message"`
+}
+
+type Health struct {
+	Status string `json:"status"`
+}
+
+type User struct {
+	ID         uint   `json:"id"`
+	TelegramID int64  `json:"telegram_id"`
+	Username   string `json:"username"` // С ведущим "@"
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	IsAdmin    bool   `json:"is_admin"`
+	Language   string `json:"language"` // Язык бота, пусто - еще не выбран
+}
+
+type Chat struct {
+	ID            uint       `json:"id"`
+	Status        string     `json:"status" enum:"active,archived"`
+	User          User       `json:"user"`
+	UnreadCount   int        `json:"unread_count"`
+	TopicID       int        `json:"topic_id"` // Тема группы поддержки, 0 - без темы
+	LastMessageAt *time.Time `json:"last_message_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type File struct {
+	ID       uint   `json:"id"`
+	FileID   string `json:"file_id"` // file_id Telegram или ссылка, переданная через API
+	FileName string `json:"file_name"`
+	FileType string `json:"file_type"`
+	FileSize int64  `json:"file_size"`
+}
+
+type Message struct {
+	ID             uint       `json:"id"`
+	ChatID         uint       `json:"chat_id"`
+	UserID         uint       `json:"user_id"`
+	User           *User      `json:"user,omitempty"` // Автор сообщения, если загружен
+	Content        string     `json:"content"`
+	IsFromUser     bool       `json:"is_from_user"`                                                // true - от клиента, false - от поддержки
+	IsRead         bool       `json:"is_read"`                                                     // Прочитано ли админом
+	DeliveryStatus string     `json:"delivery_status" enum:",pending,sent,failed,blocked,partial"` // Только для ответов поддержки
+	DeliveryError  string     `json:"delivery_error"`
+	EditedAt       *time.Time `json:"edited_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Files          []File     `json:"files"`
+}
+
+type Stats struct {
+	ActiveChats   int64 `json:"active_chats"`
+	ArchivedChats int64 `json:"archived_chats"`
+	UnreadChats   int   `json:"unread_chats"`
+	TotalChats    int64 `json:"total_chats"`
+}
+
+type DailyActivity struct {
+	Day             string `json:"day"` // YYYY-MM-DD, UTC
+	NewChats        int64  `json:"new_chats"`
+	ClientMessages  int64  `json:"client_messages"`
+	SupportMessages int64  `json:"support_messages"`
+}
+
+// ResponseTime - время от первого сообщения клиента до первого ответа поддержки
+type ResponseTime struct {
+	Answered       int64   `json:"answered"`
+	Unanswered     int64   `json:"unanswered"`
+	MedianSeconds  float64 `json:"median_seconds"`
+	AverageSeconds float64 `json:"average_seconds"`
+}
+
+type OperatorStats struct {
+	User     User  `json:"user"`
+	Chats    int64 `json:"chats"`
+	Messages int64 `json:"messages"`
+}
+
+type HourlyCount struct {
+	Hour     int   `json:"hour"` // 0-23, UTC
+	Messages int64 `json:"messages"`
+}
+
+type Backlog struct {
+	Chats          int64      `json:"chats"`
+	Messages       int64      `json:"messages"`
+	OldestUnreadAt *time.Time `json:"oldest_unread_at"`
+}
+
+// Analytics - отчет за период [from, to). Счетчики чатов и backlog - на текущий момент.
+type Analytics struct {
+	From          time.Time       `json:"from"`
+	To            time.Time       `json:"to"`
+	ActiveChats   int64           `json:"active_chats"`
+	ArchivedChats int64           `json:"archived_chats"`
+	Daily         []DailyActivity `json:"daily"`
+	FirstResponse ResponseTime    `json:"first_response"`
+	Operators     []OperatorStats `json:"operators"`
+	BusiestHours  []HourlyCount   `json:"busiest_hours"` // По убыванию числа сообщений
+	Backlog       Backlog         `json:"backlog"`
+}
+
+type Dashboard struct {
+	Stats       Stats  `json:"stats"`
+	ActiveChats []Chat `json:"active_chats"`
+}
+
+type ChatList struct {
+	Chats []Chat `json:"chats"`
+	Page  int    `json:"page"`
+	Limit int    `json:"limit"`
+	Total int64  `json:"total"`
+}
+
+// ChatDetail - чат и одна страница его сообщений
+type ChatDetail struct {
+	Chat     Chat      `json:"chat"`
+	Messages []Message `json:"messages"`
+	Page     int       `json:"page"`
+	Limit    int       `json:"limit"`
+	Total    int64     `json:"total"`
+}
+
+type ReplyRequest struct {
+	Message string `json:"message"`
+}
+
+type ReplyResponse struct {
+	Message        Message `json:"message"`
+	DeliveryStatus string  `json:"delivery_status"`
+}
+
+// TelegramLoginRequest - данные Telegram Login Widget как есть
+type TelegramLoginRequest map[string]interface{}
+
+type LoginResponse struct {
+	User User `json:"user"`
+}
+
+type MeResponse struct {
+	User      User      `json:"user"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ChatEvent - событие из потока /api/v1/admin/events
+type ChatEvent struct {
+	Type      string    `json:"type"`
+	ChatID    uint      `json:"chat_id"`
+	MessageID uint      `json:"message_id,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+type Webhook struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"` // Пусто - все события
+	Secret      string    `json:"secret"`
+	Description string    `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events,omitempty"`
+	Secret      string   `json:"secret,omitempty"` // Пусто - сгенерировать при создании, оставить прежний при изменении
+	Description string   `json:"description,omitempty"`
+	IsActive    *bool    `json:"is_active,omitempty"` // По умолчанию true
+}
+
+type WebhookList struct {
+	Webhooks []Webhook `json:"webhooks"`
+	Events   []string  `json:"events"` // События, на которые можно подписаться
+}
+
+type WebhookDelivery struct {
+	ID            uint       `json:"id"`
+	WebhookID     uint       `json:"webhook_id"`
+	Event         string     `json:"event"`
+	Payload       string     `json:"payload"` // Отправленное тело, WebhookEvent в JSON
+	Status        string     `json:"status" enum:"pending,success,failed"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	ResponseCode  int        `json:"response_code"`
+	ResponseBody  string     `json:"response_body"`
+	LastError     string     `json:"last_error"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type WebhookDeliveryList struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Page       int               `json:"page"`
+	Limit      int               `json:"limit"`
+	Total      int64             `json:"total"`
+}
+
+// WebhookEvent - тело запроса, которое бот отправляет на вебхук
+type WebhookEvent struct {
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Chat       Chat      `json:"chat"`
+	Message    *Message  `json:"message,omitempty"` // Для message.received и reply.sent
+}
+
+type Attachment struct {
+	Type     string `json:"type" enum:"photo,video,document"`
+	URL      string `json:"url"` // Telegram скачает файл по ссылке
+	FileName string `json:"file_name,omitempty"`
+}
+
+// SendMessageRequest - сообщение клиенту через публичный API. Нужно ровно одно из TelegramID и UserID.
+type SendMessageRequest struct {
+	TelegramID int64       `json:"telegram_id,omitempty"`
+	UserID     uint        `json:"user_id,omitempty"`
+	Text       string      `json:"text"`
+	Attachment *Attachment `json:"attachment,omitempty"`
+}
+
+type SendMessageResponse struct {
+	MessageID      uint   `json:"message_id"`
+	ChatID         uint   `json:"chat_id"`
+	UserID         uint   `json:"user_id"`
+	DeliveryStatus string `json:"delivery_status"`
+}
@@ -0,0 +1,246 @@
+// Package client - Go-клиент HTTP API бота поддержки.
+//
+// Публичный API (SendMessage) авторизуется ключом из API_KEYS сервера:
+//
+//	c := client.New("https://support.example.com", client.WithAPIKey("ключ"))
+//	resp, err := c.SendMessage(ctx, api.SendMessageRequest{TelegramID: 123, Text: "Привет"}, "order-42")
+//
+// Методы админ-панели требуют cookie сессии админа (WithSessionCookie).
+package client
+
+import (
+	"ai_support_tg_writer_bot/pkg/api"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Error - ответ сервера с кодом не 2xx
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("support api: %d %s", e.StatusCode, e.Message)
+}
+
+type Client struct {
+	baseURL       string
+	httpClient    *http.Client
+	apiKey        string
+	sessionCookie string
+}
+
+type Option func(*Client)
+
+// WithAPIKey задает ключ публичного API (заголовок X-API-Key)
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithSessionCookie задает значение cookie admin_session для методов админ-панели
+func WithSessionCookie(value string) Option {
+	return func(c *Client) { c.sessionCookie = value }
+}
+
+// WithHTTPClient заменяет HTTP-клиент по умолчанию (таймаут 30 секунд)
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// New создает клиент. baseURL - адрес сервера без /api/v1.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/") + "/api/v1",
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) Health(ctx context.Context) (*api.Health, error) {
+	var result api.Health
+	return &result, c.do(ctx, http.MethodGet, "/health", nil, nil, nil, &result)
+}
+
+// SendMessage пишет клиенту от имени поддержки. Непустой idempotencyKey защищает от повторной отправки.
+func (c *Client) SendMessage(ctx context.Context, request api.SendMessageRequest, idempotencyKey string) (*api.SendMessageResponse, error) {
+	var headers http.Header
+	if idempotencyKey != "" {
+		headers = http.Header{"Idempotency-Key": []string{idempotencyKey}}
+	}
+
+	var result api.SendMessageResponse
+	return &result, c.do(ctx, http.MethodPost, "/messages", nil, headers, request, &result)
+}
+
+func (c *Client) Me(ctx context.Context) (*api.MeResponse, error) {
+	var result api.MeResponse
+	return &result, c.do(ctx, http.MethodGet, "/auth/me", nil, nil, nil, &result)
+}
+
+func (c *Client) Stats(ctx context.Context) (*api.Stats, error) {
+	var result api.Stats
+	return &result, c.do(ctx, http.MethodGet, "/admin/stats", nil, nil, nil, &result)
+}
+
+// Analytics возвращает отчет за период: today, 7d, 30d или 365d (пустая строка - 7d)
+func (c *Client) Analytics(ctx context.Context, period string) (*api.Analytics, error) {
+	query := url.Values{}
+	if period != "" {
+		query.Set("period", period)
+	}
+
+	var result api.Analytics
+	return &result, c.do(ctx, http.MethodGet, "/admin/analytics", query, nil, nil, &result)
+}
+
+// ListChats возвращает страницу чатов со статусом active или archived
+func (c *Client) ListChats(ctx context.Context, status string, page, limit int) (*api.ChatList, error) {
+	query := pageQuery(page, limit)
+	if status != "" {
+		query.Set("status", status)
+	}
+
+	var result api.ChatList
+	return &result, c.do(ctx, http.MethodGet, "/admin/chats", query, nil, nil, &result)
+}
+
+// GetChat возвращает чат и страницу его сообщений
+func (c *Client) GetChat(ctx context.Context, chatID uint, page, limit int) (*api.ChatDetail, error) {
+	var result api.ChatDetail
+	return &result, c.do(ctx, http.MethodGet, chatPath(chatID, ""), pageQuery(page, limit), nil, nil, &result)
+}
+
+func (c *Client) ReplyToChat(ctx context.Context, chatID uint, text string) (*api.ReplyResponse, error) {
+	var result api.ReplyResponse
+	return &result, c.do(ctx, http.MethodPost, chatPath(chatID, "/reply"), nil, nil, api.ReplyRequest{Message: text}, &result)
+}
+
+func (c *Client) ArchiveChat(ctx context.Context, chatID uint) error {
+	return c.do(ctx, http.MethodPost, chatPath(chatID, "/archive"), nil, nil, nil, nil)
+}
+
+func (c *Client) MarkChatAsRead(ctx context.Context, chatID uint) error {
+	return c.do(ctx, http.MethodPost, chatPath(chatID, "/read"), nil, nil, nil, nil)
+}
+
+func (c *Client) ListWebhooks(ctx context.Context) (*api.WebhookList, error) {
+	var result api.WebhookList
+	return &result, c.do(ctx, http.MethodGet, "/admin/webhooks", nil, nil, nil, &result)
+}
+
+func (c *Client) CreateWebhook(ctx context.Context, request api.WebhookRequest) (*api.Webhook, error) {
+	var result api.Webhook
+	return &result, c.do(ctx, http.MethodPost, "/admin/webhooks", nil, nil, request, &result)
+}
+
+func (c *Client) UpdateWebhook(ctx context.Context, webhookID uint, request api.WebhookRequest) (*api.Webhook, error) {
+	var result api.Webhook
+	return &result, c.do(ctx, http.MethodPut, webhookPath(webhookID, ""), nil, nil, request, &result)
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID uint) error {
+	return c.do(ctx, http.MethodDelete, webhookPath(webhookID, ""), nil, nil, nil, nil)
+}
+
+func (c *Client) ListWebhookDeliveries(ctx context.Context, webhookID uint, page, limit int) (*api.WebhookDeliveryList, error) {
+	var result api.WebhookDeliveryList
+	return &result, c.do(ctx, http.MethodGet, webhookPath(webhookID, "/deliveries"), pageQuery(page, limit), nil, nil, &result)
+}
+
+func (c *Client) RedeliverWebhook(ctx context.Context, webhookID, deliveryID uint) (*api.WebhookDelivery, error) {
+	var result api.WebhookDelivery
+	path := webhookPath(webhookID, "/deliveries/"+strconv.FormatUint(uint64(deliveryID), 10)+"/redeliver")
+	return &result, c.do(ctx, http.MethodPost, path, nil, nil, nil, &result)
+}
+
+// do выполняет запрос и разбирает ответ в result (если он не nil)
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, headers http.Header, body, result interface{}) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.sessionCookie != "" {
+		req.AddCookie(&http.Cookie{Name: "admin_session", Value: c.sessionCookie})
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr api.Error
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+			apiErr.Error = http.StatusText(resp.StatusCode)
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: apiErr.Error}
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func pageQuery(page, limit int) url.Values {
+	query := url.Values{}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return query
+}
+
+func chatPath(chatID uint, suffix string) string {
+	return "/admin/chats/" + strconv.FormatUint(uint64(chatID), 10) + suffix
+}
+
+func webhookPath(webhookID uint, suffix string) string {
+	return "/admin/webhooks/" + strconv.FormatUint(uint64(webhookID), 10) + suffix
+}